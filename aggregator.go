@@ -16,3 +16,13 @@ type Aggregator interface {
 	// Reset resets the aggregators caches and aggregates.
 	Reset()
 }
+
+// PersistentAggregator is an optional interface for aggregators keeping their
+// state across periods instead of clearing it on Reset. Such aggregators cannot
+// be used with windows replaying the buffered metrics on each push.
+type PersistentAggregator interface {
+	Aggregator
+
+	// Persistent returns true if the state is kept across periods.
+	Persistent() bool
+}
@@ -963,6 +963,12 @@ func (c *Config) addAggregator(name, source string, table *ast.Table) error {
 		return err
 	}
 
+	// Sliding and session windows replay the buffered metrics on each push
+	// so the aggregator must clear its state on reset
+	if p, ok := aggregator.(telegraf.PersistentAggregator); ok && p.Persistent() && conf.Window != "tumbling" {
+		return fmt.Errorf("%s window cannot be used with aggregator %s keeping its state across periods", conf.Window, name)
+	}
+
 	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
 	return nil
 }
@@ -1444,6 +1450,9 @@ func (c *Config) buildAggregator(name, source string, tbl *ast.Table) (*models.A
 		Delay:  time.Millisecond * 100,
 		Period: time.Second * 30,
 		Grace:  time.Second * 0,
+
+		MaxSessionDuration: time.Hour,
+		MaxSessionMetrics:  10000,
 	}
 
 	if period, found := c.getFieldDuration(tbl, "period"); found {
@@ -1455,6 +1464,19 @@ func (c *Config) buildAggregator(name, source string, tbl *ast.Table) (*models.A
 	if grace, found := c.getFieldDuration(tbl, "grace"); found {
 		conf.Grace = grace
 	}
+	conf.Window = c.getFieldString(tbl, "window")
+	if step, found := c.getFieldDuration(tbl, "step"); found {
+		conf.Step = step
+	}
+	if gap, found := c.getFieldDuration(tbl, "gap"); found {
+		conf.Gap = gap
+	}
+	if maxDuration, found := c.getFieldDuration(tbl, "max_session_duration"); found {
+		conf.MaxSessionDuration = maxDuration
+	}
+	if maxMetrics := c.getFieldInt(tbl, "max_session_metrics"); maxMetrics != 0 {
+		conf.MaxSessionMetrics = maxMetrics
+	}
 
 	conf.DropOriginal = c.getFieldBool(tbl, "drop_original")
 	conf.MeasurementPrefix = c.getFieldString(tbl, "name_prefix")
@@ -1476,6 +1498,27 @@ func (c *Config) buildAggregator(name, source string, tbl *ast.Table) (*models.A
 		return nil, c.firstErr()
 	}
 
+	switch conf.Window {
+	case "", "tumbling":
+		conf.Window = "tumbling"
+	case "sliding":
+		if conf.Step == 0 {
+			conf.Step = conf.Period
+		}
+		if conf.Step < 0 || conf.Step > conf.Period {
+			return nil, fmt.Errorf("step of sliding window for aggregator %s must be positive and not exceed the period", name)
+		}
+	case "session":
+		if conf.Gap <= 0 {
+			return nil, fmt.Errorf("gap of session window for aggregator %s must be positive", name)
+		}
+		if conf.MaxSessionDuration <= 0 || conf.MaxSessionMetrics <= 0 {
+			return nil, fmt.Errorf("session limits for aggregator %s must be positive", name)
+		}
+	default:
+		return nil, fmt.Errorf("invalid window %q for aggregator %s", conf.Window, name)
+	}
+
 	var err error
 	conf.Filter, err = c.buildFilter("aggregators."+name, tbl)
 	if err != nil {
@@ -1694,14 +1737,17 @@ func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 		"collection_jitter", "collection_offset",
 		"data_format", "delay", "drop", "drop_original",
 		"fielddrop", "fieldexclude", "fieldinclude", "fieldpass", "flush_interval", "flush_jitter",
-		"grace",
+		"gap", "grace",
 		"interval",
 		"log_level", "lvm", // What is this used for?
+		"max_session_duration", "max_session_metrics",
 		"metric_batch_size", "metric_buffer_limit", "metricpass",
 		"name_override", "name_prefix", "name_suffix", "namedrop", "namedrop_separator", "namepass", "namepass_separator",
 		"order",
 		"pass", "period", "precision",
-		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "startup_error_behavior":
+		"step",
+		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "startup_error_behavior",
+		"window":
 
 	// Secret-store options to ignore
 	case "id":
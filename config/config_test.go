@@ -26,6 +26,7 @@ import (
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/models"
 	"github.com/influxdata/telegraf/persister"
+	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -1101,6 +1102,73 @@ func TestConfigPluginIDsSame(t *testing.T) {
 	}
 }
 
+func TestConfig_AggregatorWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      string
+		expected string
+	}{
+		{
+			name: "tumbling",
+			cfg: `
+[[aggregators.persistent]]
+  persistent = true
+`,
+		},
+		{
+			name: "sliding",
+			cfg: `
+[[aggregators.persistent]]
+  window = "sliding"
+  step = "10s"
+`,
+		},
+		{
+			name: "sliding persistent",
+			cfg: `
+[[aggregators.persistent]]
+  persistent = true
+  window = "sliding"
+  step = "10s"
+`,
+			expected: "sliding window cannot be used with aggregator persistent keeping its state across periods",
+		},
+		{
+			name: "session persistent",
+			cfg: `
+[[aggregators.persistent]]
+  persistent = true
+  window = "session"
+  gap = "1m"
+`,
+			expected: "session window cannot be used with aggregator persistent keeping its state across periods",
+		},
+		{
+			name: "session limits",
+			cfg: `
+[[aggregators.persistent]]
+  window = "session"
+  gap = "1m"
+  max_session_metrics = -1
+`,
+			expected: "session limits for aggregator persistent must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := config.NewConfig()
+			err := c.LoadConfigData([]byte(tt.cfg), config.EmptySourcePath)
+			if tt.expected != "" {
+				require.ErrorContains(t, err, tt.expected)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, c.Aggregators, 1)
+		})
+	}
+}
+
 func TestPersisterInputStoreLoad(t *testing.T) {
 	// Reserve a temporary state file
 	file, err := os.CreateTemp("", "telegraf_state-*.json")
@@ -1387,6 +1455,21 @@ func (m *MockupProcessorPluginParserFunc) SetParserFunc(pf telegraf.ParserFunc)
 	m.Parser = pf
 }
 
+// Mockup AGGREGATOR plugin for testing to avoid cyclic dependencies
+type MockupAggregatorPlugin struct {
+	KeepState bool `toml:"persistent"`
+}
+
+func (*MockupAggregatorPlugin) SampleConfig() string {
+	return "Mockup test aggregator plugin"
+}
+func (*MockupAggregatorPlugin) Add(telegraf.Metric)       {}
+func (*MockupAggregatorPlugin) Push(telegraf.Accumulator) {}
+func (*MockupAggregatorPlugin) Reset()                    {}
+func (m *MockupAggregatorPlugin) Persistent() bool {
+	return m.KeepState
+}
+
 // Mockup OUTPUT plugin for testing to avoid cyclic dependencies
 type MockupOutputPlugin struct {
 	URL             string            `toml:"url"`
@@ -1538,6 +1621,11 @@ func init() {
 		return &MockupProcessorPlugin{}
 	})
 
+	// Register the mockup aggregator plugin for the required names
+	aggregators.Add("persistent", func() telegraf.Aggregator {
+		return &MockupAggregatorPlugin{}
+	})
+
 	// Register the mockup output plugin for the required names
 	outputs.Add("azure_monitor", func() telegraf.Output {
 		return &MockupOutputPlugin{NamespacePrefix: "Telegraf/"}
@@ -0,0 +1,37 @@
+package docker
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/docker/docker/client"
+)
+
+var defaultHeaders = map[string]string{"User-Agent": "engine-api-cli-1.0"}
+
+// NewEnvClient creates a client configured by the DOCKER_* environment variables
+func NewEnvClient() (*client.Client, error) {
+	return client.NewClientWithOpts(client.FromEnv)
+}
+
+// NewClient creates a client connecting to the given host, e.g.
+// "unix:///var/run/docker.sock" or "tcp://[ip]:[port]", negotiating the API
+// version with the daemon
+func NewClient(host string, tlsConfig *tls.Config) (*client.Client, error) {
+	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
+	}
+	httpClient := &http.Client{Transport: transport}
+
+	return client.NewClientWithOpts(
+		client.WithHTTPHeaders(defaultHeaders),
+		client.WithHTTPClient(httpClient),
+		client.WithAPIVersionNegotiation(),
+		client.WithHost(host),
+	)
+}
+
+// IsErrNotFound returns true if the error is caused by a missing object
+func IsErrNotFound(err error) bool {
+	return client.IsErrNotFound(err)
+}
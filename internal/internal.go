@@ -180,6 +180,25 @@ func AlignTime(tm time.Time, interval time.Duration) time.Time {
 	return truncated.Add(interval)
 }
 
+// ShortDuration formats the duration using the largest unit of days, hours,
+// minutes or seconds dividing the duration, e.g. "5m" or "3d" instead of
+// "5m0s" or "72h0m0s". Other durations use the default formatting.
+func ShortDuration(d time.Duration) string {
+	switch {
+	case d == 0:
+		return "0s"
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	return d.String()
+}
+
 // ExitStatus takes the error from exec.Command
 // and returns the exit status and true
 // if error is not exit status, will return 0 and false
@@ -340,6 +340,28 @@ func TestAlignTime(t *testing.T) {
 	}
 }
 
+func TestShortDuration(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{duration: 0, expected: "0s"},
+		{duration: 1500 * time.Millisecond, expected: "1.5s"},
+		{duration: 30 * time.Second, expected: "30s"},
+		{duration: 90 * time.Second, expected: "90s"},
+		{duration: 5 * time.Minute, expected: "5m"},
+		{duration: 6 * time.Hour, expected: "6h"},
+		{duration: 36 * time.Hour, expected: "36h"},
+		{duration: 72 * time.Hour, expected: "3d"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			require.Equal(t, tt.expected, ShortDuration(tt.duration))
+		})
+	}
+}
+
 func TestParseTimestamp(t *testing.T) {
 	rfc3339 := func(value string) time.Time {
 		tm, err := time.Parse(time.RFC3339Nano, value)
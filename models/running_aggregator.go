@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"sync"
 	"time"
 
@@ -18,6 +19,13 @@ type RunningAggregator struct {
 	periodEnd   time.Time
 	log         telegraf.Logger
 
+	// buffer holds the metrics of the current sliding window
+	buffer []telegraf.Metric
+	// sessions holds the metrics of the open sessions keyed by series
+	sessions map[uint64]*aggregatorSession
+	// closed holds the sessions closed on adding metrics until the next push
+	closed []*aggregatorSession
+
 	MetricsPushed   selfstat.Stat
 	MetricsFiltered selfstat.Stat
 	MetricsDropped  selfstat.Stat
@@ -63,10 +71,19 @@ func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConf
 			"push_time_ns",
 			tags,
 		),
-		log: logger,
+		log:      logger,
+		sessions: make(map[uint64]*aggregatorSession),
 	}
 }
 
+// aggregatorSession holds the metrics of a series until the session is closed
+// after a gap of inactivity or when exceeding the session limits
+type aggregatorSession struct {
+	metrics []telegraf.Metric
+	first   time.Time
+	last    time.Time
+}
+
 // AggregatorConfig is the common config for all aggregators.
 type AggregatorConfig struct {
 	Name         string
@@ -77,8 +94,14 @@ type AggregatorConfig struct {
 	Period       time.Duration
 	Delay        time.Duration
 	Grace        time.Duration
+	Window       string
+	Step         time.Duration
+	Gap          time.Duration
 	LogLevel     string
 
+	MaxSessionDuration time.Duration
+	MaxSessionMetrics  int
+
 	NameOverride      string
 	MeasurementPrefix string
 	MeasurementSuffix string
@@ -167,6 +190,20 @@ func (r *RunningAggregator) Add(m telegraf.Metric) bool {
 		return r.Config.DropOriginal
 	}
 
+	// Sessions are not aligned to the aggregation period so collect the
+	// metrics of each series until the session is closed
+	if r.Config.Window == "session" {
+		r.addToSession(m)
+		return r.Config.DropOriginal
+	}
+
+	// Metrics might be part of multiple overlapping sliding windows so keep
+	// them until they left the last window
+	if r.Config.Window == "sliding" {
+		r.buffer = append(r.buffer, m)
+		return r.Config.DropOriginal
+	}
+
 	r.Aggregator.Add(m)
 	return r.Config.DropOriginal
 }
@@ -175,10 +212,98 @@ func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
 	r.Lock()
 	defer r.Unlock()
 
+	switch r.Config.Window {
+	case "sliding":
+		r.pushSliding(acc)
+	case "session":
+		r.pushSessions(acc)
+	default:
+		since := r.periodEnd
+		until := r.periodEnd.Add(r.Config.Period)
+		r.UpdateWindow(since, until)
+
+		r.push(acc)
+	}
+}
+
+// pushSliding feeds the metrics of the current window to the aggregator,
+// pushes the result and advances the window by one step
+func (r *RunningAggregator) pushSliding(acc telegraf.Accumulator) {
+	earliest := r.periodStart.Add(-r.Config.Grace)
+	for _, m := range r.buffer {
+		if !m.Time().Before(earliest) && m.Time().Before(r.periodEnd) {
+			r.Aggregator.Add(m)
+		}
+	}
+	r.push(acc)
+
+	until := r.periodEnd.Add(r.Config.Step)
+	r.UpdateWindow(until.Add(-r.Config.Period), until)
+
+	earliest = r.periodStart.Add(-r.Config.Grace)
+	r.buffer = slices.DeleteFunc(r.buffer, func(m telegraf.Metric) bool {
+		return m.Time().Before(earliest)
+	})
+}
+
+// addToSession adds the metric to the session of its series. The current
+// session is closed and a new one is started if the metric is more than the
+// gap after the last metric or if the session would exceed its limits.
+func (r *RunningAggregator) addToSession(m telegraf.Metric) {
+	id := m.HashID()
+	s, found := r.sessions[id]
+	if found && (m.Time().Sub(s.last) >= r.Config.Gap ||
+		m.Time().Sub(s.first) >= r.Config.MaxSessionDuration ||
+		len(s.metrics) >= r.Config.MaxSessionMetrics) {
+		r.closed = append(r.closed, s)
+		found = false
+	}
+	if !found {
+		s = &aggregatorSession{first: m.Time(), last: m.Time()}
+		r.sessions[id] = s
+	}
+
+	s.metrics = append(s.metrics, m)
+	if m.Time().Before(s.first) {
+		s.first = m.Time()
+	}
+	if m.Time().After(s.last) {
+		s.last = m.Time()
+	}
+}
+
+// pushSessions pushes the result of each closed session and of each session
+// without metrics for at least the configured gap before the end of the
+// current period. The grace period is added to the gap to allow late metrics
+// to join the session.
+func (r *RunningAggregator) pushSessions(acc telegraf.Accumulator) {
+	for _, s := range r.closed {
+		r.pushSession(acc, s)
+	}
+	r.closed = nil
+
+	for id, s := range r.sessions {
+		if r.periodEnd.Sub(s.last) < r.Config.Gap+r.Config.Grace {
+			continue
+		}
+		r.pushSession(acc, s)
+		delete(r.sessions, id)
+	}
+
+	// The period determines how often the sessions are checked
 	since := r.periodEnd
 	until := r.periodEnd.Add(r.Config.Period)
 	r.UpdateWindow(since, until)
+}
+
+func (r *RunningAggregator) pushSession(acc telegraf.Accumulator, s *aggregatorSession) {
+	for _, m := range s.metrics {
+		r.Aggregator.Add(m)
+	}
+	r.push(acc)
+}
 
+func (r *RunningAggregator) push(acc telegraf.Accumulator) {
 	start := time.Now()
 	r.Aggregator.Push(acc)
 	elapsed := time.Since(start)
@@ -239,6 +239,143 @@ func TestRunningAggregatorAddDoesNotModifyMetric(t *testing.T) {
 	testutil.RequireMetricEqual(t, expected, m)
 }
 
+func TestRunningAggregatorSlidingWindow(t *testing.T) {
+	ra := NewRunningAggregator(&mockAggregator{}, &AggregatorConfig{
+		Name:   "TestRunningAggregator",
+		Period: 3 * time.Second,
+		Window: "sliding",
+		Step:   time.Second,
+	})
+	require.NoError(t, ra.Config.Filter.Compile())
+	acc := testutil.Accumulator{}
+
+	start := time.Unix(1700000000, 0)
+	ra.UpdateWindow(start, start.Add(ra.Config.Period))
+
+	// Add one metric per second with increasing values
+	add := func(i int) {
+		m := testutil.MustMetric("RITest",
+			map[string]string{},
+			map[string]interface{}{"value": int64(1) << i},
+			start.Add(time.Duration(i)*time.Second),
+		)
+		require.False(t, ra.Add(m))
+	}
+	for i := range 3 {
+		add(i)
+	}
+
+	// The windows are [0s, 3s), [1s, 4s) and [2s, 5s)
+	for i, expected := range []int64{1 + 2 + 4, 2 + 4 + 8, 4 + 8 + 16} {
+		if i > 0 {
+			add(i + 2)
+		}
+		acc.ClearMetrics()
+		ra.Push(&acc)
+		require.Len(t, acc.Metrics, 1)
+		require.Equal(t, expected, acc.Metrics[0].Fields["sum"])
+	}
+	require.Equal(t, start.Add(3*time.Second), ra.periodStart)
+	require.Equal(t, start.Add(6*time.Second), ra.EndPeriod())
+
+	// Metrics of previous windows must be removed
+	require.Len(t, ra.buffer, 2)
+}
+
+func TestRunningAggregatorSessionWindow(t *testing.T) {
+	ra := NewRunningAggregator(&mockAggregator{}, &AggregatorConfig{
+		Name:               "TestRunningAggregator",
+		Period:             time.Minute,
+		Grace:              time.Minute,
+		Window:             "session",
+		Gap:                2 * time.Minute,
+		MaxSessionDuration: time.Hour,
+		MaxSessionMetrics:  100,
+	})
+	require.NoError(t, ra.Config.Filter.Compile())
+	acc := testutil.Accumulator{}
+
+	start := time.Unix(1700000000, 0)
+	ra.UpdateWindow(start, start.Add(ra.Config.Period))
+
+	add := func(host string, value int64, offset time.Duration) {
+		m := testutil.MustMetric("RITest",
+			map[string]string{"host": host},
+			map[string]interface{}{"value": value},
+			start.Add(offset),
+		)
+		require.False(t, ra.Add(m))
+	}
+
+	// Late metrics within the grace period are added to the session
+	add("a", 1, -30*time.Second)
+	add("a", 1, 10*time.Second)
+	add("b", 10, 50*time.Second)
+
+	// Metrics before the grace period are dropped
+	add("a", 100, -2*time.Minute)
+
+	// The sessions are closed after the gap and grace period since their last
+	// metric elapsed in metric time
+	var sums []int64
+	for i := range 5 {
+		if i == 1 {
+			add("b", 10, 90*time.Second)
+		}
+		acc.ClearMetrics()
+		ra.Push(&acc)
+		for _, m := range acc.Metrics {
+			sums = append(sums, m.Fields["sum"].(int64))
+		}
+		require.Equal(t, start.Add(time.Duration(i+2)*time.Minute), ra.EndPeriod())
+		if i < 3 {
+			require.Emptyf(t, acc.Metrics, "push %d", i)
+		}
+	}
+	require.Equal(t, []int64{2, 20}, sums)
+	require.Empty(t, ra.sessions)
+}
+
+func TestRunningAggregatorSessionWindowLimits(t *testing.T) {
+	ra := NewRunningAggregator(&mockAggregator{}, &AggregatorConfig{
+		Name:               "TestRunningAggregator",
+		Period:             time.Minute,
+		Window:             "session",
+		Gap:                2 * time.Minute,
+		MaxSessionDuration: 30 * time.Second,
+		MaxSessionMetrics:  2,
+	})
+	require.NoError(t, ra.Config.Filter.Compile())
+	acc := testutil.Accumulator{}
+
+	start := time.Unix(1700000000, 0)
+	ra.UpdateWindow(start, start.Add(ra.Config.Period))
+
+	add := func(host string, value int64, offset time.Duration) {
+		m := testutil.MustMetric("RITest",
+			map[string]string{"host": host},
+			map[string]interface{}{"value": value},
+			start.Add(offset),
+		)
+		require.False(t, ra.Add(m))
+	}
+
+	// Exceed the number of metrics of the first and the duration of the
+	// second session
+	add("a", 1, 0)
+	add("a", 2, 5*time.Second)
+	add("a", 4, 10*time.Second)
+	add("b", 8, 0)
+	add("b", 16, 40*time.Second)
+
+	// Only the sessions closed due to the limits are pushed
+	ra.Push(&acc)
+	require.Len(t, acc.Metrics, 2)
+	require.Equal(t, int64(3), acc.Metrics[0].Fields["sum"])
+	require.Equal(t, int64(8), acc.Metrics[1].Fields["sum"])
+	require.Len(t, ra.sessions, 2)
+}
+
 type mockAggregator struct {
 	sum int64
 }
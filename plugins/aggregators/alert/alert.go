@@ -0,0 +1,238 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package alert
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/ext"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Alert struct {
+	Rules       []*rule         `toml:"rule"`
+	OKState     string          `toml:"ok_state"`
+	ExpireAfter config.Duration `toml:"expire_after"`
+	Log         telegraf.Logger `toml:"-"`
+
+	series      map[uint64]*series
+	transitions []telegraf.Metric
+}
+
+type rule struct {
+	State     string          `toml:"state"`
+	Condition string          `toml:"condition"`
+	Recover   string          `toml:"recover"`
+	For       config.Duration `toml:"for"`
+
+	condition cel.Program
+	recover   cel.Program
+}
+
+// series holds the current state of a series
+type series struct {
+	state    string
+	since    time.Time
+	rules    []ruleState
+	lastSeen time.Time
+}
+
+// ruleState holds the evaluation state of a rule for a series
+type ruleState struct {
+	active  bool
+	pending time.Time
+}
+
+func (*Alert) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Alert) Init() error {
+	if len(a.Rules) == 0 {
+		return errors.New("no rules specified")
+	}
+	if a.OKState == "" {
+		a.OKState = "ok"
+	}
+
+	env, err := cel.NewEnv(
+		cel.Declarations(
+			decls.NewVar("name", decls.String),
+			decls.NewVar("tags", decls.NewMapType(decls.String, decls.String)),
+			decls.NewVar("fields", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("time", decls.Timestamp),
+		),
+		ext.Math(),
+		ext.Strings(),
+	)
+	if err != nil {
+		return fmt.Errorf("creating environment failed: %w", err)
+	}
+
+	for i, r := range a.Rules {
+		if r.State == "" {
+			return fmt.Errorf("rule %d: no state specified", i+1)
+		}
+		if r.State == a.OKState {
+			return fmt.Errorf("rule %d: state must differ from %q", i+1, a.OKState)
+		}
+		if r.Condition == "" {
+			return fmt.Errorf("rule %d: no condition specified", i+1)
+		}
+		if r.condition, err = compile(env, r.Condition); err != nil {
+			return fmt.Errorf("rule %d: compiling condition failed: %w", i+1, err)
+		}
+		if r.Recover != "" {
+			if r.recover, err = compile(env, r.Recover); err != nil {
+				return fmt.Errorf("rule %d: compiling recover condition failed: %w", i+1, err)
+			}
+		}
+	}
+
+	a.series = make(map[uint64]*series)
+
+	return nil
+}
+
+func (a *Alert) Add(in telegraf.Metric) {
+	id := in.HashID()
+	s, found := a.series[id]
+	if !found {
+		s = &series{
+			state: a.OKState,
+			since: in.Time(),
+			rules: make([]ruleState, len(a.Rules)),
+		}
+		a.series[id] = s
+	}
+	s.lastSeen = time.Now()
+
+	vars := map[string]interface{}{
+		"name":   in.Name(),
+		"tags":   in.Tags(),
+		"fields": in.Fields(),
+		"time":   in.Time(),
+	}
+
+	// Update the rules and determine the new state which is the one of the
+	// first active rule
+	state := a.OKState
+	for i, r := range a.Rules {
+		rs := &s.rules[i]
+		if !rs.active {
+			match, err := evaluate(r.condition, vars)
+			if err != nil {
+				a.Log.Debugf("Evaluating condition of rule %d for %v failed: %v", i+1, in, err)
+			} else if !match {
+				rs.pending = time.Time{}
+			} else {
+				if rs.pending.IsZero() {
+					rs.pending = in.Time()
+				}
+				rs.active = in.Time().Sub(rs.pending) >= time.Duration(r.For)
+			}
+		} else {
+			// Use the recover condition for hysteresis if specified and
+			// otherwise leave the state as soon as the condition is false
+			var recovered bool
+			var err error
+			if r.recover != nil {
+				recovered, err = evaluate(r.recover, vars)
+			} else {
+				var match bool
+				match, err = evaluate(r.condition, vars)
+				recovered = !match
+			}
+			if err != nil {
+				a.Log.Debugf("Evaluating recover condition of rule %d for %v failed: %v", i+1, in, err)
+			} else if recovered {
+				rs.active = false
+				rs.pending = time.Time{}
+			}
+		}
+
+		if rs.active && state == a.OKState {
+			state = r.State
+		}
+	}
+
+	if state == s.state {
+		return
+	}
+
+	tags := in.Tags()
+	tags["state"] = state
+	tags["previous_state"] = s.state
+	fields := map[string]interface{}{
+		"duration": in.Time().Sub(s.since).Seconds(),
+	}
+	a.transitions = append(a.transitions, metric.New(in.Name(), tags, fields, in.Time()))
+
+	s.state = state
+	s.since = in.Time()
+}
+
+func (a *Alert) Push(acc telegraf.Accumulator) {
+	for _, m := range a.transitions {
+		acc.AddMetric(m)
+	}
+
+	// Remove the state of series not seen for the configured expiry duration
+	if a.ExpireAfter > 0 {
+		for id, s := range a.series {
+			if time.Since(s.lastSeen) >= time.Duration(a.ExpireAfter) {
+				delete(a.series, id)
+			}
+		}
+	}
+}
+
+func (a *Alert) Reset() {
+	a.transitions = nil
+}
+
+// Persistent returns true as the state of the series is kept across periods
+func (*Alert) Persistent() bool {
+	return true
+}
+
+func compile(env *cel.Env, expression string) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, errors.New("expression needs to return a boolean")
+	}
+	return env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+}
+
+func evaluate(program cel.Program, vars map[string]interface{}) (bool, error) {
+	result, _, err := program.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	if r, ok := result.Value().(bool); ok {
+		return r, nil
+	}
+	return false, fmt.Errorf("invalid result type %T", result.Value())
+}
+
+func init() {
+	aggregators.Add("alert", func() telegraf.Aggregator {
+		return &Alert{
+			ExpireAfter: config.Duration(time.Hour),
+		}
+	})
+}
@@ -0,0 +1,193 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		rules    []*rule
+		expected string
+	}{
+		{
+			name:     "no rules",
+			expected: "no rules specified",
+		},
+		{
+			name:     "no state",
+			rules:    []*rule{{Condition: "true"}},
+			expected: "rule 1: no state specified",
+		},
+		{
+			name:     "ok state",
+			rules:    []*rule{{State: "ok", Condition: "true"}},
+			expected: `rule 1: state must differ from "ok"`,
+		},
+		{
+			name:     "no condition",
+			rules:    []*rule{{State: "critical"}},
+			expected: "rule 1: no condition specified",
+		},
+		{
+			name:     "non-boolean condition",
+			rules:    []*rule{{State: "critical", Condition: "fields.value + 1"}},
+			expected: "rule 1: compiling condition failed: expression needs to return a boolean",
+		},
+		{
+			name:     "invalid recover",
+			rules:    []*rule{{State: "critical", Condition: "fields.value > 1", Recover: "fields.value <"}},
+			expected: "rule 1: compiling recover condition failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Alert{Rules: tt.rules, Log: &testutil.Logger{}}
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	plugin := &Alert{
+		Rules: []*rule{
+			{
+				State:     "critical",
+				Condition: "fields.value > 90",
+			},
+			{
+				State:     "warning",
+				Condition: "fields.value > 70",
+				Recover:   "fields.value < 60",
+				For:       config.Duration(20 * time.Second),
+			},
+		},
+		Log: &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	start := time.Unix(1700000000, 0)
+	values := []float64{
+		50, // ok
+		75, // warning condition pending
+		80, // warning condition pending for 10s
+		85, // warning after 20s
+		95, // critical
+		65, // back to warning due to hysteresis
+		55, // ok
+	}
+	for i, v := range values {
+		ts := start.Add(time.Duration(i) * 10 * time.Second)
+		plugin.Add(metric.New("sensor", map[string]string{"id": "1"}, map[string]interface{}{"value": v}, ts))
+	}
+	// Metrics without the field must not change the state
+	plugin.Add(metric.New("sensor", map[string]string{"id": "1"}, map[string]interface{}{"other": 100.0}, start.Add(time.Hour)))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("sensor",
+			map[string]string{"id": "1", "state": "warning", "previous_state": "ok"},
+			map[string]interface{}{"duration": float64(30)},
+			start.Add(30*time.Second),
+		),
+		metric.New("sensor",
+			map[string]string{"id": "1", "state": "critical", "previous_state": "warning"},
+			map[string]interface{}{"duration": float64(10)},
+			start.Add(40*time.Second),
+		),
+		metric.New("sensor",
+			map[string]string{"id": "1", "state": "warning", "previous_state": "critical"},
+			map[string]interface{}{"duration": float64(10)},
+			start.Add(50*time.Second),
+		),
+		metric.New("sensor",
+			map[string]string{"id": "1", "state": "ok", "previous_state": "warning"},
+			map[string]interface{}{"duration": float64(10)},
+			start.Add(60*time.Second),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+
+	// Transitions must only be emitted once
+	plugin.Reset()
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestPendingInterrupted(t *testing.T) {
+	plugin := &Alert{
+		Rules: []*rule{
+			{
+				State:     "warning",
+				Condition: "fields.value > 70",
+				For:       config.Duration(20 * time.Second),
+			},
+		},
+		Log: &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	// The condition is interrupted before the 'for' duration elapsed and
+	// different series are handled independently
+	start := time.Unix(1700000000, 0)
+	for i, v := range []float64{75, 80, 50, 75, 80} {
+		ts := start.Add(time.Duration(i) * 10 * time.Second)
+		plugin.Add(metric.New("sensor", map[string]string{"id": "1"}, map[string]interface{}{"value": v}, ts))
+		plugin.Add(metric.New("sensor", map[string]string{"id": "2"}, map[string]interface{}{"value": 75.0}, ts))
+	}
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("sensor",
+			map[string]string{"id": "2", "state": "warning", "previous_state": "ok"},
+			map[string]interface{}{"duration": float64(20)},
+			start.Add(20*time.Second),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestExpireSeries(t *testing.T) {
+	plugin := &Alert{
+		Rules: []*rule{
+			{
+				State:     "critical",
+				Condition: "fields.value > 90",
+			},
+		},
+		ExpireAfter: config.Duration(time.Hour),
+		Log:         &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	plugin.Add(metric.New("sensor", map[string]string{"id": "idle"}, map[string]interface{}{"value": 95.0}, time.Unix(0, 0)))
+	plugin.Add(metric.New("sensor", map[string]string{"id": "active"}, map[string]interface{}{"value": 95.0}, time.Unix(0, 0)))
+	require.Len(t, plugin.series, 2)
+
+	// Pretend the series were last seen two hours ago and only update the
+	// active one
+	for _, s := range plugin.series {
+		s.lastSeen = time.Now().Add(-2 * time.Hour)
+	}
+	active := metric.New("sensor", map[string]string{"id": "active"}, map[string]interface{}{"value": 95.0}, time.Unix(10, 0))
+	plugin.Add(active)
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	require.Len(t, plugin.series, 1)
+	require.Contains(t, plugin.series, active.HashID())
+}
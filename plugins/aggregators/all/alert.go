@@ -0,0 +1,5 @@
+//go:build !custom || aggregators || aggregators.alert
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/aggregators/alert" // register plugin
@@ -0,0 +1,5 @@
+//go:build !custom || aggregators || aggregators.distinct
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/aggregators/distinct" // register plugin
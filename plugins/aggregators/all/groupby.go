@@ -0,0 +1,5 @@
+//go:build !custom || aggregators || aggregators.groupby
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/aggregators/groupby" // register plugin
@@ -0,0 +1,5 @@
+//go:build !custom || aggregators || aggregators.heavy_hitters
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/aggregators/heavy_hitters" // register plugin
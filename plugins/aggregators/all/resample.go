@@ -0,0 +1,5 @@
+//go:build !custom || aggregators || aggregators.resample
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/aggregators/resample" // register plugin
@@ -0,0 +1,5 @@
+//go:build !custom || aggregators || aggregators.slo
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/aggregators/slo" // register plugin
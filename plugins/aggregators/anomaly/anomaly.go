@@ -0,0 +1,232 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package anomaly
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Anomaly struct {
+	Fields       []string        `toml:"fields"`
+	Model        string          `toml:"model"`
+	Threshold    float64         `toml:"threshold"`
+	MinSamples   int64           `toml:"min_samples"`
+	Alpha        float64         `toml:"alpha"`
+	Beta         float64         `toml:"beta"`
+	Gamma        float64         `toml:"gamma"`
+	SeasonLength int             `toml:"season_length"`
+	HistorySize  int             `toml:"history_size"`
+	ExpireAfter  config.Duration `toml:"expire_after"`
+	Log          telegraf.Logger `toml:"-"`
+
+	fieldFilter filter.Filter
+	cache       map[uint64]*aggregate
+	models      map[string]map[string]*modelState
+}
+
+// aggregate accumulates the field values of a series within a period
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	sums   map[string]float64
+	counts map[string]int64
+}
+
+func (*Anomaly) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Anomaly) Init() error {
+	switch a.Model {
+	case "":
+		a.Model = "ewma"
+	case "ewma", "mad":
+	case "holt_winters":
+		if a.SeasonLength < 2 {
+			return errors.New("'season_length' must be at least two")
+		}
+		if a.Beta <= 0 || a.Beta > 1 {
+			return errors.New("'beta' must be in the range (0, 1]")
+		}
+		if a.Gamma <= 0 || a.Gamma > 1 {
+			return errors.New("'gamma' must be in the range (0, 1]")
+		}
+	default:
+		return fmt.Errorf("unknown model %q", a.Model)
+	}
+
+	if a.Alpha <= 0 || a.Alpha > 1 {
+		return errors.New("'alpha' must be in the range (0, 1]")
+	}
+	if a.Threshold <= 0 {
+		return errors.New("'threshold' must be positive")
+	}
+	if a.Model == "mad" && a.HistorySize < 3 {
+		return errors.New("'history_size' must be at least three")
+	}
+
+	if len(a.Fields) == 0 {
+		a.Fields = []string{"*"}
+	}
+	f, err := filter.Compile(a.Fields)
+	if err != nil {
+		return fmt.Errorf("creating field filter failed: %w", err)
+	}
+	a.fieldFilter = f
+
+	a.models = make(map[string]map[string]*modelState)
+	a.Reset()
+
+	return nil
+}
+
+func (a *Anomaly) Add(in telegraf.Metric) {
+	id := in.HashID()
+	agg, found := a.cache[id]
+	if !found {
+		agg = &aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			sums:   make(map[string]float64),
+			counts: make(map[string]int64),
+		}
+		a.cache[id] = agg
+	}
+
+	for _, field := range in.FieldList() {
+		if !a.fieldFilter.Match(field.Key) {
+			continue
+		}
+		var v float64
+		switch fv := field.Value.(type) {
+		case int64:
+			v = float64(fv)
+		case uint64:
+			v = float64(fv)
+		case float64:
+			v = fv
+		default:
+			continue
+		}
+		agg.sums[field.Key] += v
+		agg.counts[field.Key]++
+	}
+}
+
+func (a *Anomaly) Push(acc telegraf.Accumulator) {
+	now := time.Now().Unix()
+	for id, agg := range a.cache {
+		key := strconv.FormatUint(id, 10)
+		models, found := a.models[key]
+		if !found {
+			models = make(map[string]*modelState)
+			a.models[key] = models
+		}
+
+		fields := make(map[string]interface{}, 2*len(agg.sums))
+		for field, sum := range agg.sums {
+			state, found := models[field]
+			if !found {
+				state = &modelState{}
+				models[field] = state
+			}
+			state.LastSeen = now
+
+			// Use the mean of the period as value and only report scores
+			// after the model learned enough samples
+			warm := state.Samples >= a.MinSamples
+			var score float64
+			x := sum / float64(agg.counts[field])
+			switch a.Model {
+			case "ewma":
+				score = state.ewma(x, a.Alpha)
+			case "holt_winters":
+				warm = state.Samples >= int64(a.SeasonLength)+a.MinSamples
+				score = state.holtWinters(x, a.Alpha, a.Beta, a.Gamma, a.SeasonLength)
+			case "mad":
+				score = state.mad(x, a.HistorySize)
+			}
+			if !warm {
+				score = 0
+			}
+
+			fields[field+"_anomaly_score"] = score
+			fields[field+"_anomaly"] = score > a.Threshold
+		}
+		if len(fields) > 0 {
+			acc.AddFields(agg.name, fields, agg.tags)
+		}
+	}
+
+	a.expire(now)
+}
+
+// Remove the models of fields not seen for the configured expiry duration
+func (a *Anomaly) expire(now int64) {
+	if a.ExpireAfter <= 0 {
+		return
+	}
+
+	limit := now - int64(time.Duration(a.ExpireAfter)/time.Second)
+	for key, models := range a.models {
+		for field, state := range models {
+			if state.LastSeen <= limit {
+				delete(models, field)
+			}
+		}
+		if len(models) == 0 {
+			delete(a.models, key)
+		}
+	}
+}
+
+func (a *Anomaly) Reset() {
+	a.cache = make(map[uint64]*aggregate)
+}
+
+// Persistent returns true as the models are kept across periods
+func (*Anomaly) Persistent() bool {
+	return true
+}
+
+func (a *Anomaly) GetState() interface{} {
+	return a.models
+}
+
+func (a *Anomaly) SetState(state interface{}) error {
+	models, ok := state.(map[string]map[string]*modelState)
+	if !ok {
+		return fmt.Errorf("state has wrong type %T", state)
+	}
+	for k, v := range models {
+		a.models[k] = v
+	}
+	return nil
+}
+
+func init() {
+	aggregators.Add("anomaly", func() telegraf.Aggregator {
+		return &Anomaly{
+			Model:        "ewma",
+			Threshold:    3.0,
+			MinSamples:   10,
+			Alpha:        0.1,
+			Beta:         0.01,
+			Gamma:        0.1,
+			SeasonLength: 24,
+			HistorySize:  100,
+			ExpireAfter:  config.Duration(24 * time.Hour),
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package anomaly
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/persister"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Anomaly
+		expected string
+	}{
+		{
+			name:     "unknown model",
+			plugin:   &Anomaly{Model: "arima", Alpha: 0.1, Threshold: 3},
+			expected: `unknown model "arima"`,
+		},
+		{
+			name:     "invalid alpha",
+			plugin:   &Anomaly{Model: "ewma", Alpha: 1.5, Threshold: 3},
+			expected: "'alpha' must be in the range (0, 1]",
+		},
+		{
+			name:     "invalid threshold",
+			plugin:   &Anomaly{Model: "ewma", Alpha: 0.1},
+			expected: "'threshold' must be positive",
+		},
+		{
+			name:     "season too short",
+			plugin:   &Anomaly{Model: "holt_winters", Alpha: 0.1, Beta: 0.1, Gamma: 0.1, Threshold: 3, SeasonLength: 1},
+			expected: "'season_length' must be at least two",
+		},
+		{
+			name:     "history too short",
+			plugin:   &Anomaly{Model: "mad", Alpha: 0.1, Threshold: 3, HistorySize: 2},
+			expected: "'history_size' must be at least three",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestModels(t *testing.T) {
+	// Daily pattern with some noise repeated over a couple of days
+	values := make([]float64, 0, 24*7)
+	for i := range 24 * 7 {
+		noise := 0.5 * math.Sin(float64(i)*1.7)
+		values = append(values, 50+20*math.Sin(2*math.Pi*float64(i)/24)+noise)
+	}
+
+	tests := []struct {
+		name   string
+		plugin *Anomaly
+	}{
+		{
+			name:   "ewma",
+			plugin: &Anomaly{Model: "ewma", Alpha: 0.1, Threshold: 3, MinSamples: 24},
+		},
+		{
+			name: "holt_winters",
+			plugin: &Anomaly{
+				Model:        "holt_winters",
+				Alpha:        0.2,
+				Beta:         0.01,
+				Gamma:        0.3,
+				SeasonLength: 24,
+				Threshold:    3,
+				MinSamples:   48,
+			},
+		},
+		{
+			name:   "mad",
+			plugin: &Anomaly{Model: "mad", Alpha: 0.1, Threshold: 3.5, MinSamples: 24, HistorySize: 48},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.plugin.Init())
+
+			var acc testutil.Accumulator
+			push := func(v float64) telegraf.Metric {
+				// Two values per period, the mean is used
+				for _, x := range []float64{v - 1, v + 1} {
+					tt.plugin.Add(metric.New("sensor", map[string]string{"id": "1"}, map[string]interface{}{"value": x, "state": "ok"}, time.Unix(0, 0)))
+				}
+				acc.ClearMetrics()
+				tt.plugin.Push(&acc)
+				tt.plugin.Reset()
+				require.Len(t, acc.GetTelegrafMetrics(), 1)
+				return acc.GetTelegrafMetrics()[0]
+			}
+
+			for i, v := range values {
+				m := push(v)
+				if i < 24 {
+					score, found := m.GetField("value_anomaly_score")
+					require.True(t, found)
+					require.Zerof(t, score, "score during training at %d", i)
+				}
+				if tt.name == "holt_winters" && i >= 24*4 {
+					flag, found := m.GetField("value_anomaly")
+					require.True(t, found)
+					require.Equalf(t, false, flag, "false positive at %d", i)
+				}
+			}
+
+			// A spike must be detected
+			m := push(500)
+			flag, found := m.GetField("value_anomaly")
+			require.True(t, found)
+			require.Equal(t, true, flag)
+			score, found := m.GetField("value_anomaly_score")
+			require.True(t, found)
+			require.Greater(t, score, 3.5)
+		})
+	}
+}
+
+func TestStatePersistence(t *testing.T) {
+	plugin := &Anomaly{Model: "ewma", Alpha: 0.1, Threshold: 3, MinSamples: 5}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	for i := range 10 {
+		plugin.Add(metric.New("sensor", map[string]string{"id": "1"}, map[string]interface{}{"value": float64(10 + i%2)}, time.Unix(0, 0)))
+		plugin.Push(&acc)
+		plugin.Reset()
+	}
+
+	// Store the state and load it into a new instance
+	statefile := filepath.Join(t.TempDir(), "state.json")
+	p := &persister.Persister{Filename: statefile}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("anomaly", plugin))
+	require.NoError(t, p.Store())
+
+	restored := &Anomaly{Model: "ewma", Alpha: 0.1, Threshold: 3, MinSamples: 5}
+	require.NoError(t, restored.Init())
+	p = &persister.Persister{Filename: statefile}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Register("anomaly", restored))
+	require.NoError(t, p.Load())
+	require.Equal(t, plugin.models, restored.models)
+
+	// The restored model must not require training
+	acc.ClearMetrics()
+	restored.Add(metric.New("sensor", map[string]string{"id": "1"}, map[string]interface{}{"value": 100.0}, time.Unix(0, 0)))
+	restored.Push(&acc)
+	require.Len(t, acc.GetTelegrafMetrics(), 1)
+	flag, found := acc.GetTelegrafMetrics()[0].GetField("value_anomaly")
+	require.True(t, found)
+	require.Equal(t, true, flag)
+}
+
+func TestExpireModels(t *testing.T) {
+	plugin := &Anomaly{
+		Model:       "ewma",
+		Alpha:       0.1,
+		Threshold:   3,
+		ExpireAfter: config.Duration(time.Hour),
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	plugin.Add(metric.New("sensor", map[string]string{"id": "idle"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)))
+	plugin.Add(metric.New("sensor", map[string]string{"id": "active"}, map[string]interface{}{"value": 1.0, "other": 2.0}, time.Unix(0, 0)))
+	plugin.Push(&acc)
+	plugin.Reset()
+	require.Len(t, plugin.models, 2)
+
+	// Pretend the models were last updated two hours ago
+	for _, models := range plugin.models {
+		for _, state := range models {
+			state.LastSeen -= int64((2 * time.Hour).Seconds())
+		}
+	}
+
+	// Only the model of the field seen in the period is kept
+	plugin.Add(metric.New("sensor", map[string]string{"id": "active"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)))
+	plugin.Push(&acc)
+	require.Len(t, plugin.models, 1)
+	for _, models := range plugin.models {
+		require.Len(t, models, 1)
+		require.Contains(t, models, "value")
+	}
+}
@@ -0,0 +1,114 @@
+package anomaly
+
+import (
+	"math"
+	"sort"
+)
+
+// Smallest deviation used for computing the score to avoid divisions by zero
+// for constant series
+const minDeviation = 1e-9
+
+// modelState holds the state of the model for a single field of a series.
+// The state is persisted across restarts so all members must be serializable.
+type modelState struct {
+	Samples  int64     `json:"samples"`
+	Level    float64   `json:"level"`
+	Variance float64   `json:"variance"`
+	Trend    float64   `json:"trend,omitempty"`
+	Seasonal []float64 `json:"seasonal,omitempty"`
+	Window   []float64 `json:"window,omitempty"`
+	// Time of the last update in seconds since the epoch
+	LastSeen int64 `json:"last_seen"`
+}
+
+// Compute the anomaly score of the value for the exponentially weighted
+// moving average model and update the model afterwards. The score is the
+// deviation from the average in units of the standard deviation.
+func (s *modelState) ewma(x, alpha float64) float64 {
+	if s.Samples == 0 {
+		s.Samples++
+		s.Level = x
+		return 0
+	}
+
+	diff := x - s.Level
+	score := math.Abs(diff) / max(math.Sqrt(s.Variance), minDeviation)
+
+	incr := alpha * diff
+	s.Level += incr
+	s.Variance = (1 - alpha) * (s.Variance + diff*incr)
+	s.Samples++
+
+	return score
+}
+
+// Compute the anomaly score of the value for the additive Holt-Winters model
+// and update the model afterwards. The score is the deviation from the
+// forecast in units of the standard deviation of the forecast error. The
+// first season is used to initialize the seasonal components.
+func (s *modelState) holtWinters(x, alpha, beta, gamma float64, length int) float64 {
+	if len(s.Seasonal) != length {
+		s.Samples = 0
+		s.Seasonal = make([]float64, length)
+	}
+
+	if s.Samples < int64(length) {
+		if s.Samples == 0 {
+			s.Level = x
+		}
+		s.Seasonal[s.Samples] = x - s.Level
+		s.Samples++
+		return 0
+	}
+
+	i := s.Samples % int64(length)
+	forecast := s.Level + s.Trend + s.Seasonal[i]
+	residual := x - forecast
+	score := math.Abs(residual) / max(math.Sqrt(s.Variance), minDeviation)
+
+	level := alpha*(x-s.Seasonal[i]) + (1-alpha)*(s.Level+s.Trend)
+	s.Trend = beta*(level-s.Level) + (1-beta)*s.Trend
+	s.Level = level
+	s.Seasonal[i] = gamma*(x-s.Level) + (1-gamma)*s.Seasonal[i]
+	s.Variance = (1-alpha)*s.Variance + alpha*residual*residual
+	s.Samples++
+
+	return score
+}
+
+// Compute the anomaly score of the value using the median absolute deviation
+// of the values in the window and add the value to the window afterwards. The
+// score is the modified z-score as defined by Iglewicz and Hoaglin.
+func (s *modelState) mad(x float64, window int) float64 {
+	var score float64
+	if len(s.Window) > 0 {
+		med := median(s.Window)
+		deviations := make([]float64, 0, len(s.Window))
+		for _, v := range s.Window {
+			deviations = append(deviations, math.Abs(v-med))
+		}
+		mad := median(deviations)
+		score = 0.6745 * math.Abs(x-med) / max(mad, minDeviation)
+	}
+
+	s.Window = append(s.Window, x)
+	if len(s.Window) > window {
+		s.Window = s.Window[len(s.Window)-window:]
+	}
+	s.Samples++
+
+	return score
+}
+
+func median(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,149 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package distinct
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Distinct struct {
+	Tags      []string `toml:"tags"`
+	Fields    []string `toml:"fields"`
+	GroupBy   []string `toml:"group_by"`
+	Precision uint8    `toml:"precision"`
+
+	groupFilter filter.Filter
+	cache       map[uint64]*aggregate
+}
+
+// aggregate holds the estimators for a group
+type aggregate struct {
+	name       string
+	tags       map[string]string
+	estimators map[string]*hyperLogLog
+}
+
+func (*Distinct) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Distinct) Init() error {
+	if len(d.Tags) == 0 && len(d.Fields) == 0 {
+		return errors.New("no tags or fields specified")
+	}
+	if d.Precision < 4 || d.Precision > 18 {
+		return fmt.Errorf("precision %d out of range [4, 18]", d.Precision)
+	}
+
+	if len(d.GroupBy) > 0 {
+		f, err := filter.Compile(d.GroupBy)
+		if err != nil {
+			return fmt.Errorf("creating group_by filter failed: %w", err)
+		}
+		d.groupFilter = f
+	}
+
+	d.Reset()
+
+	return nil
+}
+
+func (d *Distinct) Add(in telegraf.Metric) {
+	// Collect the values to count
+	values := make(map[string]string, len(d.Tags)+len(d.Fields))
+	for _, key := range d.Tags {
+		if v, found := in.GetTag(key); found {
+			values[key] = v
+		}
+	}
+	for _, key := range d.Fields {
+		if raw, found := in.GetField(key); found {
+			if v, ok := raw.(string); ok {
+				values[key] = v
+			}
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+
+	// Group the metric by name and the grouping tags, the counted tags are
+	// never used for grouping
+	tags := make(map[string]string)
+	h := fnv.New64a()
+	h.Write([]byte(in.Name()))
+	h.Write([]byte("\n"))
+	for _, tag := range in.TagList() {
+		if d.isCounted(tag.Key) {
+			continue
+		}
+		if d.groupFilter != nil && !d.groupFilter.Match(tag.Key) {
+			continue
+		}
+		tags[tag.Key] = tag.Value
+		h.Write([]byte(tag.Key))
+		h.Write([]byte("\n"))
+		h.Write([]byte(tag.Value))
+		h.Write([]byte("\n"))
+	}
+	id := h.Sum64()
+
+	a, found := d.cache[id]
+	if !found {
+		a = &aggregate{
+			name:       in.Name(),
+			tags:       tags,
+			estimators: make(map[string]*hyperLogLog),
+		}
+		d.cache[id] = a
+	}
+
+	for key, v := range values {
+		e, found := a.estimators[key]
+		if !found {
+			e = newHyperLogLog(d.Precision)
+			a.estimators[key] = e
+		}
+		e.add(v)
+	}
+}
+
+func (d *Distinct) Push(acc telegraf.Accumulator) {
+	for _, a := range d.cache {
+		fields := make(map[string]interface{}, len(a.estimators))
+		for key, e := range a.estimators {
+			fields[key+"_distinct"] = e.count()
+		}
+		acc.AddFields(a.name, fields, a.tags)
+	}
+}
+
+func (d *Distinct) Reset() {
+	d.cache = make(map[uint64]*aggregate)
+}
+
+func (d *Distinct) isCounted(tag string) bool {
+	for _, key := range d.Tags {
+		if key == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	aggregators.Add("distinct", func() telegraf.Aggregator {
+		return &Distinct{
+			Precision: 14,
+		}
+	})
+}
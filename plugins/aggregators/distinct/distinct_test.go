@@ -0,0 +1,144 @@
+package distinct
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	plugin := &Distinct{Precision: 14}
+	require.ErrorContains(t, plugin.Init(), "no tags or fields specified")
+
+	plugin = &Distinct{Tags: []string{"client_ip"}, Precision: 19}
+	require.ErrorContains(t, plugin.Init(), "precision 19 out of range")
+}
+
+func TestCases(t *testing.T) {
+	plugin := &Distinct{
+		Tags:      []string{"client_ip"},
+		Fields:    []string{"user"},
+		GroupBy:   []string{"host"},
+		Precision: 14,
+	}
+	require.NoError(t, plugin.Init())
+
+	input := []telegraf.Metric{
+		metric.New("nginx",
+			map[string]string{"host": "a", "verb": "GET", "client_ip": "10.0.0.1"},
+			map[string]interface{}{"user": "alice", "bytes": 100},
+			time.Unix(0, 0),
+		),
+		metric.New("nginx",
+			map[string]string{"host": "a", "verb": "POST", "client_ip": "10.0.0.1"},
+			map[string]interface{}{"user": "bob", "bytes": 100},
+			time.Unix(0, 0),
+		),
+		metric.New("nginx",
+			map[string]string{"host": "a", "verb": "GET", "client_ip": "10.0.0.2"},
+			map[string]interface{}{"user": "alice", "bytes": 100},
+			time.Unix(0, 0),
+		),
+		metric.New("nginx",
+			map[string]string{"host": "b", "verb": "GET", "client_ip": "10.0.0.2"},
+			map[string]interface{}{"user": 42, "bytes": 100},
+			time.Unix(0, 0),
+		),
+		metric.New("nginx",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"bytes": 100},
+			time.Unix(0, 0),
+		),
+	}
+	for _, m := range input {
+		plugin.Add(m)
+	}
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("nginx",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"client_ip_distinct": uint64(2), "user_distinct": uint64(2)},
+			time.Unix(0, 0),
+		),
+		metric.New("nginx",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"client_ip_distinct": uint64(1)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+
+	plugin.Reset()
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestEstimation(t *testing.T) {
+	for _, precision := range []uint8{4, 10, 14} {
+		for _, n := range []int{100, 10000, 200000} {
+			t.Run(strconv.Itoa(int(precision))+"_"+strconv.Itoa(n), func(t *testing.T) {
+				h := newHyperLogLog(precision)
+				for i := range n {
+					// Add every value twice
+					h.add("value" + strconv.Itoa(i))
+					h.add("value" + strconv.Itoa(i))
+				}
+
+				// Allow for four times the standard error
+				stderr := 1.04 / float64(int(1)<<(precision/2))
+				if precision%2 == 1 {
+					stderr /= 1.41421356
+				}
+				require.InEpsilon(t, n, h.count(), 4*stderr)
+			})
+		}
+	}
+}
+
+func TestEstimationUnbiased(t *testing.T) {
+	// The raw HyperLogLog estimate is biased for cardinalities up to about
+	// five times the number of registers, so check the mean error over
+	// multiple estimators in this range
+	const precision = 10
+	for _, n := range []int{1 << precision, 2 << precision, 3 << precision, 5 << precision} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			var sum float64
+			for run := range 20 {
+				h := newHyperLogLog(precision)
+				for i := range n {
+					h.add(strconv.Itoa(run) + "-" + strconv.Itoa(i))
+				}
+				sum += float64(h.count())
+			}
+			require.InEpsilon(t, float64(n), sum/20, 0.02)
+		})
+	}
+}
+
+func TestSparseExact(t *testing.T) {
+	h := newHyperLogLog(14)
+	for i := range 1000 {
+		h.add(strconv.Itoa(i % 500))
+	}
+	require.NotNil(t, h.sparse)
+	require.Nil(t, h.registers)
+	require.Equal(t, uint64(500), h.count())
+
+	// Exceed the sparse limit
+	for i := range 2000 {
+		h.add(strconv.Itoa(i))
+	}
+	require.Nil(t, h.sparse)
+	require.Len(t, h.registers, 1<<14)
+	require.InEpsilon(t, 2000, h.count(), 0.05)
+}
@@ -0,0 +1,122 @@
+package distinct
+
+import (
+	"math"
+	"math/bits"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// hyperLogLog estimates the number of distinct values following the
+// HyperLogLog algorithm using 64-bit hashes. The cardinality is computed using
+// the improved estimator from "New cardinality estimation algorithms for
+// HyperLogLog sketches" by Otmar Ertl which, in contrast to the raw estimate,
+// is unbiased over the whole range of cardinalities without requiring
+// empirical bias correction. For small cardinalities the hashes are kept in a
+// set providing exact counts until the set consumes more memory than the
+// dense registers.
+type hyperLogLog struct {
+	precision uint8
+	sparse    map[uint64]bool
+	registers []uint8
+}
+
+func newHyperLogLog(precision uint8) *hyperLogLog {
+	return &hyperLogLog{
+		precision: precision,
+		sparse:    make(map[uint64]bool),
+	}
+}
+
+func (h *hyperLogLog) add(value string) {
+	hash := xxhash.Sum64String(value)
+
+	if h.registers == nil {
+		h.sparse[hash] = true
+		// Each set entry takes at least 16 bytes while each register uses
+		// one byte
+		if len(h.sparse)*16 < 1<<h.precision {
+			return
+		}
+		h.registers = make([]uint8, 1<<h.precision)
+		for hs := range h.sparse {
+			h.insert(hs)
+		}
+		h.sparse = nil
+		return
+	}
+	h.insert(hash)
+}
+
+func (h *hyperLogLog) insert(hash uint64) {
+	idx := hash >> (64 - h.precision)
+	// Determine the position of the first set bit in the remaining bits
+	// making sure the rank does not exceed the number of available bits
+	w := hash<<h.precision | 1<<(h.precision-1)
+	rank := uint8(bits.LeadingZeros64(w)) + 1
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+func (h *hyperLogLog) count() uint64 {
+	if h.registers == nil {
+		return uint64(len(h.sparse))
+	}
+
+	// Compute the histogram of the register values ranging from zero for
+	// empty registers up to q + 1 with q being the number of hash bits not
+	// used for the register index
+	q := 64 - int(h.precision)
+	histogram := make([]float64, q+2)
+	for _, r := range h.registers {
+		histogram[r]++
+	}
+
+	m := float64(len(h.registers))
+	z := m * tau(1-histogram[q+1]/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + histogram[k])
+	}
+	z += m * sigma(histogram[0]/m)
+
+	return uint64(math.Round(m * m / (2 * math.Ln2 * z)))
+}
+
+// sigma computes x + sum_{k>=1} x^(2^k) * 2^(k-1) accounting for the empty
+// registers
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y := 1.0
+	z := x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+}
+
+// tau computes (1 - x - sum_{k>=1} (1 - x^(2^-k))^2 * 2^-k) / 3 accounting
+// for the saturated registers
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y := 1.0
+	z := 1 - x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+}
@@ -0,0 +1,272 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package downsample
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Downsample struct {
+	Resolutions     []config.Duration   `toml:"resolutions"`
+	Aggregates      []string            `toml:"aggregates"`
+	FieldAggregates map[string][]string `toml:"field_aggregates"`
+	ResolutionTag   string              `toml:"resolution_tag"`
+	Log             telegraf.Logger     `toml:"-"`
+
+	fieldAggregates []fieldAggregates
+	levels          []*level
+}
+
+// fieldAggregates holds the aggregate functions for fields matching the filter
+type fieldAggregates struct {
+	filter     filter.Filter
+	aggregates []string
+}
+
+// level holds the buckets of a single resolution
+type level struct {
+	resolution time.Duration
+	tag        string
+	buckets    map[bucketKey]*bucket
+	// Start of the oldest bucket not emitted yet
+	next time.Time
+}
+
+type bucketKey struct {
+	id    uint64
+	start int64
+}
+
+// bucket holds the statistics of the fields of a series within one interval
+type bucket struct {
+	name   string
+	tags   map[string]string
+	start  time.Time
+	fields map[string]*stats
+}
+
+type stats struct {
+	count     int64
+	sum       float64
+	min       float64
+	max       float64
+	first     float64
+	last      float64
+	firstTime time.Time
+	lastTime  time.Time
+}
+
+func (*Downsample) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Downsample) Init() error {
+	if len(d.Resolutions) == 0 {
+		return errors.New("no resolutions specified")
+	}
+	if d.ResolutionTag == "" {
+		d.ResolutionTag = "resolution"
+	}
+	if len(d.Aggregates) == 0 {
+		d.Aggregates = []string{"mean"}
+	}
+	if err := checkAggregates(d.Aggregates); err != nil {
+		return err
+	}
+
+	// Sort the patterns to get a deterministic order for overlapping patterns
+	patterns := make([]string, 0, len(d.FieldAggregates))
+	for pattern := range d.FieldAggregates {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		aggregates := d.FieldAggregates[pattern]
+		if err := checkAggregates(aggregates); err != nil {
+			return fmt.Errorf("field %q: %w", pattern, err)
+		}
+		f, err := filter.Compile([]string{pattern})
+		if err != nil {
+			return fmt.Errorf("creating filter for field %q failed: %w", pattern, err)
+		}
+		d.fieldAggregates = append(d.fieldAggregates, fieldAggregates{filter: f, aggregates: aggregates})
+	}
+
+	seen := make(map[time.Duration]bool, len(d.Resolutions))
+	for _, r := range d.Resolutions {
+		resolution := time.Duration(r)
+		if resolution <= 0 {
+			return errors.New("resolutions must be positive")
+		}
+		if seen[resolution] {
+			return fmt.Errorf("duplicate resolution %s", resolution)
+		}
+		seen[resolution] = true
+
+		d.levels = append(d.levels, &level{
+			resolution: resolution,
+			tag:        internal.ShortDuration(resolution),
+			buckets:    make(map[bucketKey]*bucket),
+		})
+	}
+
+	return nil
+}
+
+func (d *Downsample) Add(in telegraf.Metric) {
+	id := in.HashID()
+	for _, l := range d.levels {
+		start := in.Time().Truncate(l.resolution)
+		if start.Before(l.next) {
+			d.Log.Debugf("Dropping metric for already emitted %s interval starting at %v", l.tag, start)
+			continue
+		}
+
+		key := bucketKey{id: id, start: start.UnixNano()}
+		b, found := l.buckets[key]
+		if !found {
+			b = &bucket{
+				name:   in.Name(),
+				tags:   in.Tags(),
+				start:  start,
+				fields: make(map[string]*stats),
+			}
+			l.buckets[key] = b
+		}
+
+		for _, field := range in.FieldList() {
+			var v float64
+			switch fv := field.Value.(type) {
+			case int64:
+				v = float64(fv)
+			case uint64:
+				v = float64(fv)
+			case float64:
+				v = fv
+			default:
+				continue
+			}
+			s, found := b.fields[field.Key]
+			if !found {
+				b.fields[field.Key] = &stats{
+					count:     1,
+					sum:       v,
+					min:       v,
+					max:       v,
+					first:     v,
+					last:      v,
+					firstTime: in.Time(),
+					lastTime:  in.Time(),
+				}
+				continue
+			}
+			s.count++
+			s.sum += v
+			s.min = min(s.min, v)
+			s.max = max(s.max, v)
+			if in.Time().Before(s.firstTime) {
+				s.first = v
+				s.firstTime = in.Time()
+			}
+			if !in.Time().Before(s.lastTime) {
+				s.last = v
+				s.lastTime = in.Time()
+			}
+		}
+	}
+}
+
+func (d *Downsample) Push(acc telegraf.Accumulator) {
+	now := time.Now()
+	for _, l := range d.levels {
+		// Only emit completed intervals
+		until := now.Truncate(l.resolution)
+		for key, b := range l.buckets {
+			if b.start.Add(l.resolution).After(now) {
+				continue
+			}
+
+			fields := make(map[string]interface{}, len(b.fields))
+			for name, s := range b.fields {
+				for _, aggregate := range d.aggregatesFor(name) {
+					fields[name+"_"+aggregate] = s.value(aggregate)
+				}
+			}
+			if len(fields) > 0 {
+				tags := make(map[string]string, len(b.tags)+1)
+				for k, v := range b.tags {
+					tags[k] = v
+				}
+				tags[d.ResolutionTag] = l.tag
+				acc.AddFields(b.name, fields, tags, b.start)
+			}
+			delete(l.buckets, key)
+		}
+		l.next = until
+	}
+}
+
+// Reset is a no-op as the intervals of the resolutions span multiple
+// periods; completed intervals are removed when being pushed.
+func (*Downsample) Reset() {}
+
+// Persistent returns true as the intervals span multiple periods
+func (*Downsample) Persistent() bool {
+	return true
+}
+
+func (d *Downsample) aggregatesFor(field string) []string {
+	for _, fa := range d.fieldAggregates {
+		if fa.filter.Match(field) {
+			return fa.aggregates
+		}
+	}
+	return d.Aggregates
+}
+
+func (s *stats) value(aggregate string) interface{} {
+	switch aggregate {
+	case "count":
+		return s.count
+	case "sum":
+		return s.sum
+	case "min":
+		return s.min
+	case "max":
+		return s.max
+	case "first":
+		return s.first
+	case "last":
+		return s.last
+	}
+	return s.sum / float64(s.count)
+}
+
+func checkAggregates(aggregates []string) error {
+	for _, a := range aggregates {
+		switch a {
+		case "mean", "min", "max", "sum", "count", "first", "last":
+		default:
+			return fmt.Errorf("unknown aggregate %q", a)
+		}
+	}
+	return nil
+}
+
+func init() {
+	aggregators.Add("downsample", func() telegraf.Aggregator {
+		return &Downsample{}
+	})
+}
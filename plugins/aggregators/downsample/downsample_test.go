@@ -0,0 +1,141 @@
+package downsample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Downsample
+		expected string
+	}{
+		{
+			name:     "no resolutions",
+			plugin:   &Downsample{},
+			expected: "no resolutions specified",
+		},
+		{
+			name: "duplicate resolution",
+			plugin: &Downsample{
+				Resolutions: []config.Duration{config.Duration(time.Minute), config.Duration(60 * time.Second)},
+			},
+			expected: "duplicate resolution 1m0s",
+		},
+		{
+			name: "unknown aggregate",
+			plugin: &Downsample{
+				Resolutions: []config.Duration{config.Duration(time.Minute)},
+				Aggregates:  []string{"median"},
+			},
+			expected: `unknown aggregate "median"`,
+		},
+		{
+			name: "unknown field aggregate",
+			plugin: &Downsample{
+				Resolutions:     []config.Duration{config.Duration(time.Minute)},
+				FieldAggregates: map[string][]string{"temp*": {"p99"}},
+			},
+			expected: `field "temp*": unknown aggregate "p99"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestResolutions(t *testing.T) {
+	plugin := &Downsample{
+		Resolutions:     []config.Duration{config.Duration(time.Minute), config.Duration(time.Hour)},
+		Aggregates:      []string{"mean"},
+		FieldAggregates: map[string][]string{"bytes_*": {"sum", "count"}},
+		Log:             &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	// Two completed minutes within a completed hour
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i, v := range []float64{1, 3, 5, 7} {
+		ts := start.Add(time.Duration(i) * 30 * time.Second)
+		plugin.Add(metric.New("net", map[string]string{"host": "a"}, map[string]interface{}{"temp": v, "bytes_recv": int64(10), "state": "up"}, ts))
+	}
+
+	// Metrics of the current minute and hour must not be emitted
+	plugin.Add(metric.New("net", map[string]string{"host": "a"}, map[string]interface{}{"temp": 100.0}, time.Now()))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	plugin.Reset()
+
+	expected := []telegraf.Metric{
+		metric.New("net",
+			map[string]string{"host": "a", "resolution": "1m"},
+			map[string]interface{}{"temp_mean": 2.0, "bytes_recv_sum": 20.0, "bytes_recv_count": int64(2)},
+			start,
+		),
+		metric.New("net",
+			map[string]string{"host": "a", "resolution": "1m"},
+			map[string]interface{}{"temp_mean": 6.0, "bytes_recv_sum": 20.0, "bytes_recv_count": int64(2)},
+			start.Add(time.Minute),
+		),
+		metric.New("net",
+			map[string]string{"host": "a", "resolution": "1h"},
+			map[string]interface{}{"temp_mean": 4.0, "bytes_recv_sum": 40.0, "bytes_recv_count": int64(4)},
+			start,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+
+	// Late metrics of emitted intervals must be dropped
+	plugin.Add(metric.New("net", map[string]string{"host": "a"}, map[string]interface{}{"temp": 1.0}, start))
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestAggregates(t *testing.T) {
+	plugin := &Downsample{
+		Resolutions:   []config.Duration{config.Duration(10 * time.Second)},
+		Aggregates:    []string{"mean", "min", "max", "sum", "count", "first", "last"},
+		ResolutionTag: "res",
+		Log:           &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	start := time.Unix(1700000000, 0)
+	for _, offset := range []int{3, 1, 5, 2} {
+		ts := start.Add(time.Duration(offset) * time.Second)
+		plugin.Add(metric.New("m", map[string]string{}, map[string]interface{}{"value": uint64(offset)}, ts))
+	}
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("m",
+			map[string]string{"res": "10s"},
+			map[string]interface{}{
+				"value_mean":  2.75,
+				"value_min":   1.0,
+				"value_max":   5.0,
+				"value_sum":   11.0,
+				"value_count": int64(4),
+				"value_first": 1.0,
+				"value_last":  5.0,
+			},
+			start,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
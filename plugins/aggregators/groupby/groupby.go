@@ -0,0 +1,318 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package groupby
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type GroupBy struct {
+	DropTags     []string        `toml:"drop_tags"`
+	Fields       []string        `toml:"fields"`
+	Aggregates   []string        `toml:"aggregates"`
+	Percentiles  []float64       `toml:"percentiles"`
+	SeriesValues string          `toml:"series_values"`
+	Log          telegraf.Logger `toml:"-"`
+
+	tagFilter   filter.Filter
+	fieldFilter filter.Filter
+	suffixes    []string
+	cache       map[uint64]*group
+}
+
+// group collects the values of all series belonging to the group
+type group struct {
+	name   string
+	tags   map[string]string
+	series map[uint64]map[string]interface{}
+	values map[string][]interface{}
+}
+
+func (*GroupBy) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GroupBy) Init() error {
+	if len(g.DropTags) == 0 {
+		return errors.New("no tags to drop specified")
+	}
+	tagFilter, err := filter.Compile(g.DropTags)
+	if err != nil {
+		return fmt.Errorf("creating tag filter failed: %w", err)
+	}
+	g.tagFilter = tagFilter
+
+	if len(g.Fields) == 0 {
+		g.Fields = []string{"*"}
+	}
+	fieldFilter, err := filter.Compile(g.Fields)
+	if err != nil {
+		return fmt.Errorf("creating field filter failed: %w", err)
+	}
+	g.fieldFilter = fieldFilter
+
+	switch g.SeriesValues {
+	case "":
+		g.SeriesValues = "last"
+	case "last", "all":
+	default:
+		return fmt.Errorf("invalid series_values %q", g.SeriesValues)
+	}
+
+	if len(g.Aggregates) == 0 {
+		g.Aggregates = []string{"sum"}
+	}
+	for _, a := range g.Aggregates {
+		switch a {
+		case "sum", "avg", "min", "max", "count":
+		case "percentile":
+			if len(g.Percentiles) == 0 {
+				return errors.New("aggregate 'percentile' requires 'percentiles' to be set")
+			}
+		default:
+			return fmt.Errorf("unknown aggregate %q", a)
+		}
+	}
+
+	g.suffixes = make([]string, 0, len(g.Percentiles))
+	for _, p := range g.Percentiles {
+		if p <= 0 || p > 100 {
+			return fmt.Errorf("percentile %v out of range (0, 100]", p)
+		}
+		suffix := "_p" + strings.ReplaceAll(fmt.Sprintf("%v", p), ".", "_")
+		g.suffixes = append(g.suffixes, suffix)
+	}
+
+	g.Reset()
+
+	return nil
+}
+
+func (g *GroupBy) Add(in telegraf.Metric) {
+	// Determine the group by hashing the name and the remaining tags
+	h := fnv.New64a()
+	h.Write([]byte(in.Name()))
+	h.Write([]byte("\n"))
+	for _, tag := range in.TagList() {
+		if g.tagFilter.Match(tag.Key) {
+			continue
+		}
+		h.Write([]byte(tag.Key))
+		h.Write([]byte("\n"))
+		h.Write([]byte(tag.Value))
+		h.Write([]byte("\n"))
+	}
+	id := h.Sum64()
+
+	grp, found := g.cache[id]
+	if !found {
+		tags := make(map[string]string)
+		for _, tag := range in.TagList() {
+			if !g.tagFilter.Match(tag.Key) {
+				tags[tag.Key] = tag.Value
+			}
+		}
+		grp = &group{
+			name:   in.Name(),
+			tags:   tags,
+			series: make(map[uint64]map[string]interface{}),
+			values: make(map[string][]interface{}),
+		}
+		g.cache[id] = grp
+	}
+
+	for _, field := range in.FieldList() {
+		if !g.fieldFilter.Match(field.Key) {
+			continue
+		}
+		switch field.Value.(type) {
+		case int64, uint64, float64:
+		default:
+			continue
+		}
+
+		if g.SeriesValues == "all" {
+			grp.values[field.Key] = append(grp.values[field.Key], field.Value)
+			continue
+		}
+
+		// Only keep the latest value of each series
+		sid := in.HashID()
+		fields, found := grp.series[sid]
+		if !found {
+			fields = make(map[string]interface{})
+			grp.series[sid] = fields
+		}
+		fields[field.Key] = field.Value
+	}
+}
+
+func (g *GroupBy) Push(acc telegraf.Accumulator) {
+	for _, grp := range g.cache {
+		values := grp.values
+		if g.SeriesValues == "last" {
+			values = make(map[string][]interface{})
+			for _, fields := range grp.series {
+				for k, v := range fields {
+					values[k] = append(values[k], v)
+				}
+			}
+		}
+
+		fields := make(map[string]interface{})
+		for k, vs := range values {
+			if len(vs) == 0 {
+				continue
+			}
+			for _, a := range g.Aggregates {
+				switch a {
+				case "sum":
+					fields[k+"_sum"] = sum(vs)
+				case "avg":
+					fields[k+"_avg"] = avg(vs)
+				case "min":
+					fields[k+"_min"] = minimum(vs)
+				case "max":
+					fields[k+"_max"] = maximum(vs)
+				case "count":
+					fields[k+"_count"] = int64(len(vs))
+				case "percentile":
+					sorted := sortValues(vs)
+					for i, p := range g.Percentiles {
+						fields[k+g.suffixes[i]] = percentile(sorted, p)
+					}
+				}
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(grp.name, fields, grp.tags)
+		}
+	}
+}
+
+func (g *GroupBy) Reset() {
+	g.cache = make(map[uint64]*group)
+}
+
+// Determine the common type of all values, falling back to float if the
+// types differ
+func commonType(values []interface{}) string {
+	var t string
+	for _, v := range values {
+		var vt string
+		switch v.(type) {
+		case int64:
+			vt = "int"
+		case uint64:
+			vt = "uint"
+		default:
+			return "float"
+		}
+		if t != "" && t != vt {
+			return "float"
+		}
+		t = vt
+	}
+	return t
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return math.NaN()
+}
+
+func sum(values []interface{}) interface{} {
+	switch commonType(values) {
+	case "int":
+		var s int64
+		for _, v := range values {
+			s += v.(int64)
+		}
+		return s
+	case "uint":
+		var s uint64
+		for _, v := range values {
+			s += v.(uint64)
+		}
+		return s
+	}
+
+	var s float64
+	for _, v := range values {
+		s += toFloat(v)
+	}
+	return s
+}
+
+func avg(values []interface{}) float64 {
+	var s float64
+	for _, v := range values {
+		s += toFloat(v)
+	}
+	return s / float64(len(values))
+}
+
+func minimum(values []interface{}) interface{} {
+	sorted := sortValues(values)
+	return sorted[0]
+}
+
+func maximum(values []interface{}) interface{} {
+	sorted := sortValues(values)
+	return sorted[len(sorted)-1]
+}
+
+// Sort the values in ascending order converting them to float if the types
+// differ
+func sortValues(values []interface{}) []interface{} {
+	sorted := make([]interface{}, 0, len(values))
+	switch commonType(values) {
+	case "int":
+		sorted = append(sorted, values...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].(int64) < sorted[j].(int64) })
+	case "uint":
+		sorted = append(sorted, values...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].(uint64) < sorted[j].(uint64) })
+	default:
+		for _, v := range values {
+			sorted = append(sorted, toFloat(v))
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].(float64) < sorted[j].(float64) })
+	}
+	return sorted
+}
+
+// Compute the percentile using the nearest-rank method to return an actual
+// value of the series preserving its type
+func percentile(sorted []interface{}, p float64) interface{} {
+	rank := int(math.Ceil(p / 100.0 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func init() {
+	aggregators.Add("groupby", func() telegraf.Aggregator {
+		return &GroupBy{}
+	})
+}
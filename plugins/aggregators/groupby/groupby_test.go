@@ -0,0 +1,152 @@
+package groupby
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *GroupBy
+		expected string
+	}{
+		{
+			name:     "no tags",
+			plugin:   &GroupBy{},
+			expected: "no tags to drop specified",
+		},
+		{
+			name:     "invalid series values",
+			plugin:   &GroupBy{DropTags: []string{"cpu"}, SeriesValues: "first"},
+			expected: `invalid series_values "first"`,
+		},
+		{
+			name:     "unknown aggregate",
+			plugin:   &GroupBy{DropTags: []string{"cpu"}, Aggregates: []string{"median"}},
+			expected: `unknown aggregate "median"`,
+		},
+		{
+			name:     "percentile without percentiles",
+			plugin:   &GroupBy{DropTags: []string{"cpu"}, Aggregates: []string{"percentile"}},
+			expected: "requires 'percentiles' to be set",
+		},
+		{
+			name:     "percentile out of range",
+			plugin:   &GroupBy{DropTags: []string{"cpu"}, Aggregates: []string{"percentile"}, Percentiles: []float64{120}},
+			expected: "percentile 120 out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestLastValues(t *testing.T) {
+	plugin := &GroupBy{
+		DropTags:   []string{"cpu"},
+		Fields:     []string{"usage_*", "count"},
+		Aggregates: []string{"sum", "avg", "min", "max", "count"},
+		Log:        &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	input := []telegraf.Metric{
+		// The first sample of cpu0 must be overwritten by the second one
+		metric.New("cpu", map[string]string{"cpu": "cpu0", "host": "a"},
+			map[string]interface{}{"usage_user": 1.0, "count": int64(100)}, now),
+		metric.New("cpu", map[string]string{"cpu": "cpu0", "host": "a"},
+			map[string]interface{}{"usage_user": 10.0, "count": int64(1), "ignored": 1.0}, now.Add(time.Second)),
+		metric.New("cpu", map[string]string{"cpu": "cpu1", "host": "a"},
+			map[string]interface{}{"usage_user": 20.0, "count": int64(2), "name": "x"}, now),
+		metric.New("cpu", map[string]string{"cpu": "cpu0", "host": "b"},
+			map[string]interface{}{"usage_user": 5.0, "count": int64(3)}, now),
+	}
+	for _, m := range input {
+		plugin.Add(m)
+	}
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"},
+			map[string]interface{}{
+				"usage_user_sum":   30.0,
+				"usage_user_avg":   15.0,
+				"usage_user_min":   10.0,
+				"usage_user_max":   20.0,
+				"usage_user_count": int64(2),
+				"count_sum":        int64(3),
+				"count_avg":        1.5,
+				"count_min":        int64(1),
+				"count_max":        int64(2),
+				"count_count":      int64(2),
+			}, now),
+		metric.New("cpu", map[string]string{"host": "b"},
+			map[string]interface{}{
+				"usage_user_sum":   5.0,
+				"usage_user_avg":   5.0,
+				"usage_user_min":   5.0,
+				"usage_user_max":   5.0,
+				"usage_user_count": int64(1),
+				"count_sum":        int64(3),
+				"count_avg":        3.0,
+				"count_min":        int64(3),
+				"count_max":        int64(3),
+				"count_count":      int64(1),
+			}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics(), testutil.IgnoreTime())
+
+	// After reset nothing should be emitted
+	plugin.Reset()
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestAllValuesPercentiles(t *testing.T) {
+	plugin := &GroupBy{
+		DropTags:     []string{"device"},
+		Aggregates:   []string{"sum", "percentile"},
+		Percentiles:  []float64{50, 99.9},
+		SeriesValues: "all",
+		Log:          &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	for i := 1; i <= 10; i++ {
+		plugin.Add(metric.New("diskio", map[string]string{"device": "sda"},
+			map[string]interface{}{"reads": uint64(i), "mixed": int64(i)}, now))
+	}
+	plugin.Add(metric.New("diskio", map[string]string{"device": "sdb"},
+		map[string]interface{}{"reads": uint64(100), "mixed": 0.5}, now))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("diskio", map[string]string{},
+			map[string]interface{}{
+				"reads_sum":   uint64(155),
+				"reads_p50":   uint64(6),
+				"reads_p99_9": uint64(100),
+				"mixed_sum":   55.5,
+				"mixed_p50":   5.0,
+				"mixed_p99_9": 10.0,
+			}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
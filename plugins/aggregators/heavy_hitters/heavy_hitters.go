@@ -0,0 +1,192 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package heavy_hitters
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type HeavyHitters struct {
+	Keys      []string `toml:"keys"`
+	Field     string   `toml:"field"`
+	Top       int      `toml:"top"`
+	Algorithm string   `toml:"algorithm"`
+	Capacity  int      `toml:"capacity"`
+	Width     int      `toml:"width"`
+	Depth     int      `toml:"depth"`
+	GroupBy   []string `toml:"group_by"`
+
+	groupFilter filter.Filter
+	cache       map[uint64]*aggregate
+}
+
+// aggregate holds the summary of the heaviest keys for a group
+type aggregate struct {
+	name    string
+	tags    map[string]string
+	summary summary
+}
+
+func (*HeavyHitters) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HeavyHitters) Init() error {
+	if len(h.Keys) == 0 {
+		return errors.New("no keys specified")
+	}
+	if h.Top < 1 {
+		return errors.New("'top' must be positive")
+	}
+	if h.Capacity < h.Top {
+		return fmt.Errorf("capacity %d must not be smaller than top %d", h.Capacity, h.Top)
+	}
+	switch h.Algorithm {
+	case "space_saving":
+	case "count_min":
+		if h.Width < 1 || h.Depth < 1 {
+			return errors.New("'width' and 'depth' must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown algorithm %q", h.Algorithm)
+	}
+
+	if len(h.GroupBy) > 0 {
+		f, err := filter.Compile(h.GroupBy)
+		if err != nil {
+			return fmt.Errorf("creating group_by filter failed: %w", err)
+		}
+		h.groupFilter = f
+	}
+
+	h.Reset()
+
+	return nil
+}
+
+func (h *HeavyHitters) Add(in telegraf.Metric) {
+	// Determine the key, metrics without all key tags are ignored
+	keyTags := make(map[string]string, len(h.Keys))
+	values := make([]string, 0, len(h.Keys))
+	for _, key := range h.Keys {
+		v, found := in.GetTag(key)
+		if !found {
+			return
+		}
+		keyTags[key] = v
+		values = append(values, v)
+	}
+
+	weight := 1.0
+	if h.Field != "" {
+		raw, found := in.GetField(h.Field)
+		if !found {
+			return
+		}
+		switch v := raw.(type) {
+		case int64:
+			weight = float64(v)
+		case uint64:
+			weight = float64(v)
+		case float64:
+			weight = v
+		default:
+			return
+		}
+		if weight < 0 {
+			return
+		}
+	}
+
+	// Group the metric by name and the grouping tags, the key tags are never
+	// used for grouping
+	tags := make(map[string]string)
+	hash := fnv.New64a()
+	hash.Write([]byte(in.Name()))
+	hash.Write([]byte("\n"))
+	for _, tag := range in.TagList() {
+		if slices.Contains(h.Keys, tag.Key) {
+			continue
+		}
+		if h.groupFilter != nil && !h.groupFilter.Match(tag.Key) {
+			continue
+		}
+		tags[tag.Key] = tag.Value
+		hash.Write([]byte(tag.Key))
+		hash.Write([]byte("\n"))
+		hash.Write([]byte(tag.Value))
+		hash.Write([]byte("\n"))
+	}
+	id := hash.Sum64()
+
+	a, found := h.cache[id]
+	if !found {
+		a = &aggregate{
+			name: in.Name(),
+			tags: tags,
+		}
+		if h.Algorithm == "count_min" {
+			a.summary = newCountMin(h.Capacity, h.Width, h.Depth)
+		} else {
+			a.summary = newSpaceSaving(h.Capacity)
+		}
+		h.cache[id] = a
+	}
+
+	a.summary.add(strings.Join(values, "\x00"), keyTags, weight)
+}
+
+func (h *HeavyHitters) Push(acc telegraf.Accumulator) {
+	valueField := "count"
+	if h.Field != "" {
+		valueField = h.Field + "_sum"
+	}
+
+	for _, a := range h.cache {
+		for i, e := range a.summary.entries() {
+			if i >= h.Top {
+				break
+			}
+			tags := make(map[string]string, len(a.tags)+len(e.tags))
+			for k, v := range a.tags {
+				tags[k] = v
+			}
+			for k, v := range e.tags {
+				tags[k] = v
+			}
+			fields := map[string]interface{}{
+				valueField: e.weight,
+				"error":    a.summary.errorBound(e),
+				"rank":     int64(i + 1),
+			}
+			acc.AddFields(a.name, fields, tags)
+		}
+	}
+}
+
+func (h *HeavyHitters) Reset() {
+	h.cache = make(map[uint64]*aggregate)
+}
+
+func init() {
+	aggregators.Add("heavy_hitters", func() telegraf.Aggregator {
+		return &HeavyHitters{
+			Top:       10,
+			Algorithm: "space_saving",
+			Capacity:  100,
+			Width:     2048,
+			Depth:     5,
+		}
+	})
+}
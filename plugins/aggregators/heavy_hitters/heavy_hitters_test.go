@@ -0,0 +1,169 @@
+package heavy_hitters
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *HeavyHitters
+		expected string
+	}{
+		{
+			name:     "no keys",
+			plugin:   &HeavyHitters{Top: 10, Capacity: 100, Algorithm: "space_saving"},
+			expected: "no keys specified",
+		},
+		{
+			name:     "invalid top",
+			plugin:   &HeavyHitters{Keys: []string{"src"}, Capacity: 100, Algorithm: "space_saving"},
+			expected: "'top' must be positive",
+		},
+		{
+			name:     "capacity smaller than top",
+			plugin:   &HeavyHitters{Keys: []string{"src"}, Top: 10, Capacity: 5, Algorithm: "space_saving"},
+			expected: "capacity 5 must not be smaller than top 10",
+		},
+		{
+			name:     "unknown algorithm",
+			plugin:   &HeavyHitters{Keys: []string{"src"}, Top: 10, Capacity: 100, Algorithm: "lossy"},
+			expected: `unknown algorithm "lossy"`,
+		},
+		{
+			name:     "invalid sketch dimensions",
+			plugin:   &HeavyHitters{Keys: []string{"src"}, Top: 10, Capacity: 100, Algorithm: "count_min"},
+			expected: "'width' and 'depth' must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestSpaceSaving(t *testing.T) {
+	plugin := &HeavyHitters{
+		Keys:      []string{"src"},
+		Top:       2,
+		Algorithm: "space_saving",
+		Capacity:  2,
+	}
+	require.NoError(t, plugin.Init())
+
+	// The key "c" evicts "b" with the lowest count and inherits its count as
+	// error
+	for _, src := range []string{"a", "a", "a", "b", "c"} {
+		plugin.Add(metric.New("netflow", map[string]string{"src": src, "host": "r1"}, map[string]interface{}{"bytes": int64(1)}, time.Unix(0, 0)))
+	}
+	// Metrics without the key tags are ignored
+	plugin.Add(metric.New("netflow", map[string]string{"host": "r1"}, map[string]interface{}{"bytes": int64(1)}, time.Unix(0, 0)))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("netflow",
+			map[string]string{"host": "r1", "src": "a"},
+			map[string]interface{}{"count": 3.0, "error": 0.0, "rank": int64(1)},
+			time.Unix(0, 0),
+		),
+		metric.New("netflow",
+			map[string]string{"host": "r1", "src": "c"},
+			map[string]interface{}{"count": 2.0, "error": 1.0, "rank": int64(2)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	// The keys must be cleared on reset
+	plugin.Reset()
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestCountMin(t *testing.T) {
+	plugin := &HeavyHitters{
+		Keys:      []string{"src", "dst"},
+		Field:     "bytes",
+		Top:       2,
+		Algorithm: "count_min",
+		Capacity:  10,
+		Width:     1024,
+		Depth:     4,
+		GroupBy:   []string{"exporter"},
+	}
+	require.NoError(t, plugin.Init())
+
+	flows := []struct {
+		exporter string
+		src      string
+		dst      string
+		bytes    uint64
+	}{
+		{"r1", "10.0.0.1", "10.0.0.2", 100},
+		{"r1", "10.0.0.1", "10.0.0.3", 50},
+		{"r1", "10.0.0.1", "10.0.0.2", 200},
+		{"r1", "10.0.0.4", "10.0.0.2", 10},
+		{"r2", "10.0.0.5", "10.0.0.6", 40},
+	}
+	for _, f := range flows {
+		tags := map[string]string{"exporter": f.exporter, "src": f.src, "dst": f.dst, "port": "443"}
+		plugin.Add(metric.New("netflow", tags, map[string]interface{}{"bytes": f.bytes}, time.Unix(0, 0)))
+	}
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	errR1 := math.E / 1024 * 360
+	errR2 := math.E / 1024 * 40
+	expected := []telegraf.Metric{
+		metric.New("netflow",
+			map[string]string{"exporter": "r1", "src": "10.0.0.1", "dst": "10.0.0.2"},
+			map[string]interface{}{"bytes_sum": 300.0, "error": errR1, "rank": int64(1)},
+			time.Unix(0, 0),
+		),
+		metric.New("netflow",
+			map[string]string{"exporter": "r1", "src": "10.0.0.1", "dst": "10.0.0.3"},
+			map[string]interface{}{"bytes_sum": 50.0, "error": errR1, "rank": int64(2)},
+			time.Unix(0, 0),
+		),
+		metric.New("netflow",
+			map[string]string{"exporter": "r2", "src": "10.0.0.5", "dst": "10.0.0.6"},
+			map[string]interface{}{"bytes_sum": 40.0, "error": errR2, "rank": int64(1)},
+			time.Unix(0, 0),
+		),
+	}
+	options := []cmp.Option{testutil.IgnoreTime(), testutil.SortMetrics(), cmpopts.EquateApprox(0, 1e-9)}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), options...)
+}
+
+func TestSpaceSavingErrorBound(t *testing.T) {
+	// The estimated weight minus the error must never exceed the true weight
+	s := newSpaceSaving(5)
+	truth := make(map[string]float64)
+	for i := range 1000 {
+		key := string(rune('a' + (i*i)%17))
+		weight := float64(i%7 + 1)
+		truth[key] += weight
+		s.add(key, nil, weight)
+	}
+
+	for _, e := range s.entries() {
+		require.GreaterOrEqual(t, e.weight, truth[e.key], e.key)
+		require.LessOrEqual(t, e.weight-s.errorBound(e), truth[e.key], e.key)
+	}
+}
@@ -0,0 +1,185 @@
+package heavy_hitters
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// summary keeps track of the heaviest keys of a stream in bounded space
+type summary interface {
+	add(key string, tags map[string]string, weight float64)
+	entries() []*entry
+	// Upper bound of the overestimation of the entry's weight
+	errorBound(e *entry) float64
+}
+
+// entry is a monitored key with its estimated weight
+type entry struct {
+	key    string
+	tags   map[string]string
+	weight float64
+	err    float64
+	index  int
+}
+
+// entryHeap is a min-heap of the monitored keys ordered by weight
+type entryHeap []*entry
+
+func (h entryHeap) Len() int           { return len(h) }
+func (h entryHeap) Less(i, j int) bool { return h[i].weight < h[j].weight }
+
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return e
+}
+
+// Return the entries sorted by descending weight
+func sortedEntries(h entryHeap) []*entry {
+	sorted := make([]*entry, len(h))
+	copy(sorted, h)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].weight == sorted[j].weight {
+			return sorted[i].key < sorted[j].key
+		}
+		return sorted[i].weight > sorted[j].weight
+	})
+	return sorted
+}
+
+// spaceSaving implements the weighted Space-Saving algorithm of Metwally et
+// al. monitoring a fixed number of keys. A key not being monitored replaces
+// the key with the lowest weight and inherits its weight as error.
+type spaceSaving struct {
+	capacity  int
+	monitored map[string]*entry
+	heap      entryHeap
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{
+		capacity:  capacity,
+		monitored: make(map[string]*entry, capacity),
+		heap:      make(entryHeap, 0, capacity),
+	}
+}
+
+func (s *spaceSaving) add(key string, tags map[string]string, weight float64) {
+	if e, found := s.monitored[key]; found {
+		e.weight += weight
+		heap.Fix(&s.heap, e.index)
+		return
+	}
+
+	if len(s.heap) < s.capacity {
+		e := &entry{key: key, tags: tags, weight: weight}
+		s.monitored[key] = e
+		heap.Push(&s.heap, e)
+		return
+	}
+
+	// Replace the key with the lowest weight
+	e := s.heap[0]
+	delete(s.monitored, e.key)
+	e.key = key
+	e.tags = tags
+	e.err = e.weight
+	e.weight += weight
+	s.monitored[key] = e
+	heap.Fix(&s.heap, 0)
+}
+
+func (s *spaceSaving) entries() []*entry {
+	return sortedEntries(s.heap)
+}
+
+func (*spaceSaving) errorBound(e *entry) float64 {
+	return e.err
+}
+
+// countMin implements a Count-Min sketch tracking the keys with the highest
+// estimated weights as candidates. The estimates exceed the true weight by at
+// most e/width times the total weight with a probability of 1 - exp(-depth).
+type countMin struct {
+	capacity   int
+	counters   [][]float64
+	total      float64
+	candidates map[string]*entry
+	heap       entryHeap
+}
+
+func newCountMin(capacity, width, depth int) *countMin {
+	counters := make([][]float64, depth)
+	for i := range counters {
+		counters[i] = make([]float64, width)
+	}
+	return &countMin{
+		capacity:   capacity,
+		counters:   counters,
+		candidates: make(map[string]*entry, capacity),
+		heap:       make(entryHeap, 0, capacity),
+	}
+}
+
+func (c *countMin) add(key string, tags map[string]string, weight float64) {
+	c.total += weight
+
+	estimate := math.Inf(1)
+	for i, row := range c.counters {
+		idx := xxhash.Sum64String(strconv.Itoa(i)+"\x00"+key) % uint64(len(row))
+		row[idx] += weight
+		estimate = min(estimate, row[idx])
+	}
+
+	if e, found := c.candidates[key]; found {
+		e.weight = estimate
+		heap.Fix(&c.heap, e.index)
+		return
+	}
+
+	if len(c.heap) < c.capacity {
+		e := &entry{key: key, tags: tags, weight: estimate}
+		c.candidates[key] = e
+		heap.Push(&c.heap, e)
+		return
+	}
+
+	// Replace the candidate with the lowest weight if exceeded
+	e := c.heap[0]
+	if estimate <= e.weight {
+		return
+	}
+	delete(c.candidates, e.key)
+	e.key = key
+	e.tags = tags
+	e.weight = estimate
+	c.candidates[key] = e
+	heap.Fix(&c.heap, 0)
+}
+
+func (c *countMin) entries() []*entry {
+	return sortedEntries(c.heap)
+}
+
+func (c *countMin) errorBound(*entry) float64 {
+	return math.E / float64(len(c.counters[0])) * c.total
+}
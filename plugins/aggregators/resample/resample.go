@@ -0,0 +1,279 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package resample
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Resample struct {
+	Interval    config.Duration `toml:"interval"`
+	Method      string          `toml:"method"`
+	MaxGap      config.Duration `toml:"max_gap"`
+	FilledTag   string          `toml:"filled_tag"`
+	Fields      []string        `toml:"fields"`
+	ExpireAfter config.Duration `toml:"expire_after"`
+	Log         telegraf.Logger `toml:"-"`
+
+	fieldFilter filter.Filter
+	series      map[uint64]*series
+}
+
+// series holds the pending samples and the resampling state of each field
+type series struct {
+	name     string
+	tags     map[string]string
+	pending  map[string][]sample
+	fields   map[string]*fieldState
+	lastSeen time.Time
+}
+
+type sample struct {
+	t time.Time
+	v interface{}
+}
+
+// fieldState holds the latest sample and the next grid point to compute
+type fieldState struct {
+	prev sample
+	next time.Time
+}
+
+// point is a resampled value at a grid point
+type point struct {
+	t      time.Time
+	filled bool
+}
+
+func (*Resample) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Resample) Init() error {
+	if r.Interval <= 0 {
+		return errors.New("'interval' must be positive")
+	}
+	switch r.Method {
+	case "":
+		r.Method = "previous"
+	case "previous", "linear", "none":
+	default:
+		return fmt.Errorf("unknown method %q", r.Method)
+	}
+	if r.MaxGap < 0 {
+		return errors.New("'max_gap' must not be negative")
+	}
+	if r.MaxGap == 0 {
+		r.MaxGap = 10 * r.Interval
+	}
+	if r.FilledTag == "" {
+		r.FilledTag = "filled"
+	}
+
+	if len(r.Fields) == 0 {
+		r.Fields = []string{"*"}
+	}
+	f, err := filter.Compile(r.Fields)
+	if err != nil {
+		return fmt.Errorf("creating field filter failed: %w", err)
+	}
+	r.fieldFilter = f
+
+	r.series = make(map[uint64]*series)
+
+	return nil
+}
+
+func (r *Resample) Add(in telegraf.Metric) {
+	id := in.HashID()
+	s, found := r.series[id]
+	if !found {
+		s = &series{
+			name:    in.Name(),
+			tags:    in.Tags(),
+			pending: make(map[string][]sample),
+			fields:  make(map[string]*fieldState),
+		}
+		r.series[id] = s
+	}
+	s.lastSeen = time.Now()
+
+	for _, field := range in.FieldList() {
+		if r.fieldFilter.Match(field.Key) {
+			s.pending[field.Key] = append(s.pending[field.Key], sample{t: in.Time(), v: field.Value})
+		}
+	}
+}
+
+func (r *Resample) Push(acc telegraf.Accumulator) {
+	for _, s := range r.series {
+		points := make(map[point]map[string]interface{})
+		for name, samples := range s.pending {
+			sort.SliceStable(samples, func(i, j int) bool { return samples[i].t.Before(samples[j].t) })
+
+			state, found := s.fields[name]
+			for _, smpl := range samples {
+				if !found {
+					state = &fieldState{prev: smpl, next: r.firstGridPoint(smpl.t)}
+					s.fields[name] = state
+					found = true
+					continue
+				}
+				if smpl.t.Before(state.prev.t) {
+					r.Log.Debugf("Dropping late sample of field %q at %v", name, smpl.t)
+					continue
+				}
+				r.resample(state, smpl, func(p point, v interface{}) {
+					if points[p] == nil {
+						points[p] = make(map[string]interface{})
+					}
+					points[p][name] = v
+				})
+				state.prev = smpl
+			}
+		}
+		s.pending = make(map[string][]sample)
+
+		// Emit the points in chronological order
+		keys := make([]point, 0, len(points))
+		for p := range points {
+			keys = append(keys, p)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].t.Equal(keys[j].t) {
+				return !keys[i].filled
+			}
+			return keys[i].t.Before(keys[j].t)
+		})
+		for _, p := range keys {
+			tags := s.tags
+			if p.filled {
+				tags = make(map[string]string, len(s.tags)+1)
+				for k, v := range s.tags {
+					tags[k] = v
+				}
+				tags[r.FilledTag] = "true"
+			}
+			acc.AddFields(s.name, points[p], tags, p.t)
+		}
+	}
+
+	r.expire()
+}
+
+// Remove the state of series not seen for the configured expiry duration. The
+// state is kept for at least the maximum gap as the next sample would be used
+// for filling grid points otherwise.
+func (r *Resample) expire() {
+	if r.ExpireAfter <= 0 {
+		return
+	}
+
+	expiry := max(time.Duration(r.ExpireAfter), time.Duration(r.MaxGap))
+	for id, s := range r.series {
+		if time.Since(s.lastSeen) >= expiry {
+			delete(r.series, id)
+		}
+	}
+}
+
+// Reset is a no-op as the resampling state must be kept across periods and
+// the pending samples are consumed when pushing.
+func (*Resample) Reset() {}
+
+// Persistent returns true as the interpolation state spans multiple periods
+func (*Resample) Persistent() bool {
+	return true
+}
+
+// Compute all grid points before the given sample using the previous sample
+// of the field. Grid points with a sample in the preceding interval are always
+// emitted while the other grid points are only filled if the gap between the
+// samples does not exceed the maximum gap, independent of the method.
+func (r *Resample) resample(state *fieldState, next sample, emit func(point, interface{})) {
+	interval := time.Duration(r.Interval)
+	prev := state.prev
+	fill := r.Method != "none" && next.t.Sub(prev.t) <= time.Duration(r.MaxGap)
+
+	for ; state.next.Before(next.t); state.next = state.next.Add(interval) {
+		g := state.next
+
+		// The grid point is an actual sample if the previous sample is
+		// within the interval ending at the grid point
+		actual := g.Sub(prev.t) < interval
+		if !actual && !fill {
+			// No further grid point can be computed from the previous sample
+			// so skip all grid points before the next sample
+			state.next = r.firstGridPoint(next.t)
+			return
+		}
+
+		// Interpolate the value if possible and use the previous value
+		// otherwise, e.g. for non-numeric fields. Numeric values are always
+		// converted to float for the linear method to keep the field type.
+		value := prev.v
+		if r.Method == "linear" {
+			if v, ok := toFloat(prev.v); ok {
+				value = v
+			}
+			if fill {
+				if v, ok := interpolate(prev, next, g); ok {
+					value = v
+				}
+			}
+		}
+		emit(point{t: g, filled: !actual}, value)
+	}
+}
+
+func (r *Resample) firstGridPoint(t time.Time) time.Time {
+	g := t.Truncate(time.Duration(r.Interval))
+	if g.Before(t) {
+		g = g.Add(time.Duration(r.Interval))
+	}
+	return g
+}
+
+func interpolate(prev, next sample, t time.Time) (interface{}, bool) {
+	v0, ok := toFloat(prev.v)
+	if !ok {
+		return nil, false
+	}
+	v1, ok := toFloat(next.v)
+	if !ok {
+		return nil, false
+	}
+	ratio := float64(t.Sub(prev.t)) / float64(next.t.Sub(prev.t))
+	return v0 + (v1-v0)*ratio, true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func init() {
+	aggregators.Add("resample", func() telegraf.Aggregator {
+		return &Resample{
+			ExpireAfter: config.Duration(time.Hour),
+		}
+	})
+}
@@ -0,0 +1,267 @@
+package resample
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Resample
+		expected string
+	}{
+		{
+			name:     "no interval",
+			plugin:   &Resample{},
+			expected: "'interval' must be positive",
+		},
+		{
+			name:     "unknown method",
+			plugin:   &Resample{Interval: config.Duration(time.Second), Method: "cubic"},
+			expected: `unknown method "cubic"`,
+		},
+		{
+			name:     "negative max gap",
+			plugin:   &Resample{Interval: config.Duration(time.Second), MaxGap: config.Duration(-time.Second)},
+			expected: "'max_gap' must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	// Samples with jitter and a gap between 23s and 61s
+	samples := []struct {
+		offset time.Duration
+		value  float64
+	}{
+		{1 * time.Second, 1.0},
+		{12 * time.Second, 12.0},
+		{23 * time.Second, 23.0},
+		{61 * time.Second, 61.0},
+		{70 * time.Second, 70.0},
+		{75 * time.Second, 75.0},
+	}
+
+	filled := map[string]string{"id": "1", "filled": "true"}
+	actual := map[string]string{"id": "1"}
+
+	tests := []struct {
+		name     string
+		method   string
+		maxGap   time.Duration
+		expected []telegraf.Metric
+	}{
+		{
+			name:   "previous",
+			method: "previous",
+			maxGap: 20 * time.Second,
+			expected: []telegraf.Metric{
+				metric.New("sensor", actual, map[string]interface{}{"value": 1.0}, start.Add(10*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 12.0}, start.Add(20*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 23.0}, start.Add(30*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 70.0}, start.Add(70*time.Second)),
+			},
+		},
+		{
+			name:   "previous with large gap",
+			method: "previous",
+			maxGap: time.Minute,
+			expected: []telegraf.Metric{
+				metric.New("sensor", actual, map[string]interface{}{"value": 1.0}, start.Add(10*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 12.0}, start.Add(20*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 23.0}, start.Add(30*time.Second)),
+				metric.New("sensor", filled, map[string]interface{}{"value": 23.0}, start.Add(40*time.Second)),
+				metric.New("sensor", filled, map[string]interface{}{"value": 23.0}, start.Add(50*time.Second)),
+				metric.New("sensor", filled, map[string]interface{}{"value": 23.0}, start.Add(60*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 70.0}, start.Add(70*time.Second)),
+			},
+		},
+		{
+			name:   "linear",
+			method: "linear",
+			maxGap: 20 * time.Second,
+			expected: []telegraf.Metric{
+				metric.New("sensor", actual, map[string]interface{}{"value": 10.0}, start.Add(10*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 20.0}, start.Add(20*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 23.0}, start.Add(30*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 70.0}, start.Add(70*time.Second)),
+			},
+		},
+		{
+			name:   "linear with large gap",
+			method: "linear",
+			maxGap: time.Minute,
+			expected: []telegraf.Metric{
+				metric.New("sensor", actual, map[string]interface{}{"value": 10.0}, start.Add(10*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 20.0}, start.Add(20*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 30.0}, start.Add(30*time.Second)),
+				metric.New("sensor", filled, map[string]interface{}{"value": 40.0}, start.Add(40*time.Second)),
+				metric.New("sensor", filled, map[string]interface{}{"value": 50.0}, start.Add(50*time.Second)),
+				metric.New("sensor", filled, map[string]interface{}{"value": 60.0}, start.Add(60*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 70.0}, start.Add(70*time.Second)),
+			},
+		},
+		{
+			name:   "none",
+			method: "none",
+			expected: []telegraf.Metric{
+				metric.New("sensor", actual, map[string]interface{}{"value": 1.0}, start.Add(10*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 12.0}, start.Add(20*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 23.0}, start.Add(30*time.Second)),
+				metric.New("sensor", actual, map[string]interface{}{"value": 70.0}, start.Add(70*time.Second)),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Resample{
+				Interval: config.Duration(10 * time.Second),
+				Method:   tt.method,
+				MaxGap:   config.Duration(tt.maxGap),
+				Log:      &testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			for _, s := range samples {
+				m := metric.New("sensor", map[string]string{"id": "1"}, map[string]interface{}{"value": s.value}, start.Add(s.offset))
+				plugin.Add(m)
+			}
+
+			var acc testutil.Accumulator
+			plugin.Push(&acc)
+			plugin.Reset()
+			testutil.RequireMetricsEqual(t, tt.expected, acc.GetTelegrafMetrics())
+		})
+	}
+}
+
+func TestAcrossPeriods(t *testing.T) {
+	plugin := &Resample{
+		Interval: config.Duration(10 * time.Second),
+		Method:   "linear",
+		Fields:   []string{"value"},
+		Log:      &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	start := time.Unix(1700000000, 0)
+	var acc testutil.Accumulator
+
+	// The grid point is only computed once the next sample arrived
+	plugin.Add(metric.New("sensor", map[string]string{}, map[string]interface{}{"value": int64(0), "state": "on", "other": 1.0}, start.Add(5*time.Second)))
+	plugin.Push(&acc)
+	plugin.Reset()
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	plugin.Add(metric.New("sensor", map[string]string{}, map[string]interface{}{"value": int64(20), "state": "off"}, start.Add(25*time.Second)))
+	// Late samples are dropped
+	plugin.Add(metric.New("sensor", map[string]string{}, map[string]interface{}{"value": int64(100)}, start))
+	plugin.Push(&acc)
+	plugin.Reset()
+
+	expected := []telegraf.Metric{
+		metric.New("sensor", map[string]string{}, map[string]interface{}{"value": 5.0}, start.Add(10*time.Second)),
+		metric.New("sensor", map[string]string{"filled": "true"}, map[string]interface{}{"value": 15.0}, start.Add(20*time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestNonNumericFields(t *testing.T) {
+	plugin := &Resample{
+		Interval:  config.Duration(10 * time.Second),
+		Method:    "linear",
+		FilledTag: "resampled",
+		Log:       &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	start := time.Unix(1700000000, 0)
+	plugin.Add(metric.New("sensor", map[string]string{}, map[string]interface{}{"state": "on"}, start.Add(5*time.Second)))
+	plugin.Add(metric.New("sensor", map[string]string{}, map[string]interface{}{"state": "off"}, start.Add(25*time.Second)))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("sensor", map[string]string{}, map[string]interface{}{"state": "on"}, start.Add(10*time.Second)),
+		metric.New("sensor", map[string]string{"resampled": "true"}, map[string]interface{}{"state": "on"}, start.Add(20*time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestLinearFloat(t *testing.T) {
+	plugin := &Resample{
+		Interval: config.Duration(10 * time.Second),
+		Method:   "linear",
+		MaxGap:   config.Duration(20 * time.Second),
+		Log:      &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	// Integer samples on the grid and with a gap exceeding the maximum gap
+	start := time.Unix(1700000000, 0)
+	plugin.Add(metric.New("sensor", map[string]string{}, map[string]interface{}{"value": int64(0)}, start))
+	plugin.Add(metric.New("sensor", map[string]string{}, map[string]interface{}{"value": int64(10)}, start.Add(10*time.Second)))
+	plugin.Add(metric.New("sensor", map[string]string{}, map[string]interface{}{"value": int64(35)}, start.Add(35*time.Second)))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("sensor", map[string]string{}, map[string]interface{}{"value": 0.0}, start),
+		metric.New("sensor", map[string]string{}, map[string]interface{}{"value": 10.0}, start.Add(10*time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestExpireSeries(t *testing.T) {
+	plugin := &Resample{
+		Interval:    config.Duration(10 * time.Second),
+		ExpireAfter: config.Duration(time.Minute),
+		Log:         &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	start := time.Unix(1700000000, 0)
+	plugin.Add(metric.New("sensor", map[string]string{"id": "idle"}, map[string]interface{}{"value": 1.0}, start))
+	plugin.Add(metric.New("sensor", map[string]string{"id": "active"}, map[string]interface{}{"value": 1.0}, start))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	require.Len(t, plugin.series, 2)
+
+	// Pretend the series were last seen 80 seconds ago, this exceeds the
+	// expiry but is still within the default maximum gap of 100 seconds so
+	// both series must be kept
+	for _, s := range plugin.series {
+		s.lastSeen = time.Now().Add(-80 * time.Second)
+	}
+	plugin.Push(&acc)
+	require.Len(t, plugin.series, 2)
+
+	// Only the active series must be kept after exceeding the maximum gap
+	for _, s := range plugin.series {
+		s.lastSeen = time.Now().Add(-time.Hour)
+	}
+	active := metric.New("sensor", map[string]string{"id": "active"}, map[string]interface{}{"value": 2.0}, start.Add(10*time.Second))
+	plugin.Add(active)
+	plugin.Push(&acc)
+	require.Len(t, plugin.series, 1)
+	require.Contains(t, plugin.series, active.HashID())
+}
@@ -0,0 +1,244 @@
+package sketch
+
+import (
+	"math"
+	"sort"
+)
+
+// Scale limits of the exponential histogram as defined by the OpenTelemetry
+// data model
+const (
+	minScale = -10
+	maxScale = 20
+)
+
+// distribution is a mergeable approximation of the value distribution
+type distribution interface {
+	add(v float64)
+	buckets() []bucket
+	count() uint64
+	sum() float64
+}
+
+// bucket contains the number of values less or equal than the upper bound
+// and greater than the upper bound of the previous bucket
+type bucket struct {
+	upper float64
+	count uint64
+}
+
+// store holds the sparse bucket counts keyed by the bucket index
+type store map[int]uint64
+
+func (s store) span() int {
+	if len(s) == 0 {
+		return 0
+	}
+	lo, hi := math.MaxInt, math.MinInt
+	for idx := range s {
+		lo = min(lo, idx)
+		hi = max(hi, idx)
+	}
+	return hi - lo + 1
+}
+
+func (s store) indices() []int {
+	indices := make([]int, 0, len(s))
+	for idx := range s {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// Merge neighbouring buckets by halving the indices
+func (s store) downscale() store {
+	result := make(store, len(s))
+	for idx, c := range s {
+		result[idx>>1] += c
+	}
+	return result
+}
+
+// counts holds the values common to all distributions
+type counts struct {
+	positive   store
+	negative   store
+	zero       uint64
+	valueCount uint64
+	valueSum   float64
+}
+
+func newCounts() counts {
+	return counts{
+		positive: make(store),
+		negative: make(store),
+	}
+}
+
+func (c *counts) count() uint64 {
+	return c.valueCount
+}
+
+func (c *counts) sum() float64 {
+	return c.valueSum
+}
+
+// Convert the stores to buckets ordered by their upper bound. For the
+// positive store, the bucket with index 'idx' covers values in the range
+// (lower(idx), upper(idx)], for the negative store the absolute values are
+// stored so the bucket covers [-upper(idx), -lower(idx)).
+func (c *counts) buckets(lower, upper func(int) float64) []bucket {
+	result := make([]bucket, 0, len(c.negative)+len(c.positive)+1)
+
+	indices := c.negative.indices()
+	for i := len(indices) - 1; i >= 0; i-- {
+		idx := indices[i]
+		result = append(result, bucket{upper: -lower(idx), count: c.negative[idx]})
+	}
+	if c.zero > 0 {
+		result = append(result, bucket{upper: 0, count: c.zero})
+	}
+	for _, idx := range c.positive.indices() {
+		result = append(result, bucket{upper: upper(idx), count: c.positive[idx]})
+	}
+
+	return result
+}
+
+// Determine the store for the given value and account for the value in the
+// total count and sum. The returned value is the absolute value to use for
+// computing the index; a nil store is returned for zero values.
+func (c *counts) account(v float64) (store, float64) {
+	c.valueCount++
+	c.valueSum += v
+
+	switch {
+	case v > 0:
+		return c.positive, v
+	case v < 0:
+		return c.negative, -v
+	}
+	c.zero++
+	return nil, 0
+}
+
+// exponential is a base-2 exponential histogram as specified by OpenTelemetry.
+// The bucket with index 'idx' covers the range (base^idx, base^(idx+1)] with
+// base = 2^(2^-scale). The scale is reduced whenever the number of buckets
+// exceeds the configured limit.
+type exponential struct {
+	counts
+	scale      int
+	maxBuckets int
+}
+
+func newExponential(scale, maxBuckets int) *exponential {
+	return &exponential{
+		counts:     newCounts(),
+		scale:      scale,
+		maxBuckets: maxBuckets,
+	}
+}
+
+func (h *exponential) add(v float64) {
+	s, abs := h.account(v)
+	if s == nil {
+		return
+	}
+	s[h.index(abs)]++
+
+	for h.scale > minScale && (h.positive.span() > h.maxBuckets || h.negative.span() > h.maxBuckets) {
+		h.downscale()
+	}
+}
+
+func (h *exponential) buckets() []bucket {
+	return h.counts.buckets(h.lower, h.upper)
+}
+
+func (h *exponential) index(v float64) int {
+	// Compute the index for scale zero for exact powers of two to avoid
+	// rounding issues of the logarithm
+	frac, exp := math.Frexp(v)
+	if h.scale <= 0 {
+		idx := exp - 1
+		if frac == 0.5 {
+			idx--
+		}
+		return idx >> -h.scale
+	}
+	if frac == 0.5 {
+		return ((exp - 1) << h.scale) - 1
+	}
+	return int(math.Ceil(math.Ldexp(math.Log2(v), h.scale))) - 1
+}
+
+func (h *exponential) lower(idx int) float64 {
+	return math.Exp2(math.Ldexp(float64(idx), -h.scale))
+}
+
+func (h *exponential) upper(idx int) float64 {
+	return h.lower(idx + 1)
+}
+
+// Halve the resolution by merging neighbouring buckets
+func (h *exponential) downscale() {
+	h.positive = h.positive.downscale()
+	h.negative = h.negative.downscale()
+	h.scale--
+}
+
+// ddsketch is a sketch with logarithmically sized buckets guaranteeing the
+// configured relative accuracy for the quantiles. The bucket with index 'idx'
+// covers the range (gamma^(idx-1), gamma^idx]. If the number of buckets
+// exceeds the configured limit the lowest buckets are collapsed.
+type ddsketch struct {
+	counts
+	gamma      float64
+	logGamma   float64
+	maxBuckets int
+}
+
+func newDDSketch(accuracy float64, maxBuckets int) *ddsketch {
+	gamma := (1 + accuracy) / (1 - accuracy)
+	return &ddsketch{
+		counts:     newCounts(),
+		gamma:      gamma,
+		logGamma:   math.Log(gamma),
+		maxBuckets: maxBuckets,
+	}
+}
+
+func (d *ddsketch) add(v float64) {
+	s, abs := d.account(v)
+	if s == nil {
+		return
+	}
+	s[d.index(abs)]++
+
+	if len(s) > d.maxBuckets {
+		indices := s.indices()
+		target := indices[len(indices)-d.maxBuckets]
+		for _, idx := range indices[:len(indices)-d.maxBuckets] {
+			s[target] += s[idx]
+			delete(s, idx)
+		}
+	}
+}
+
+func (d *ddsketch) buckets() []bucket {
+	return d.counts.buckets(d.lower, d.upper)
+}
+
+func (d *ddsketch) index(v float64) int {
+	return int(math.Ceil(math.Log(v) / d.logGamma))
+}
+
+func (d *ddsketch) lower(idx int) float64 {
+	return math.Pow(d.gamma, float64(idx-1))
+}
+
+func (d *ddsketch) upper(idx int) float64 {
+	return math.Pow(d.gamma, float64(idx))
+}
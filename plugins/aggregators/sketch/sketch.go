@@ -0,0 +1,184 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package sketch
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Sketch struct {
+	Type             string   `toml:"type"`
+	Fields           []string `toml:"fields"`
+	MaxBuckets       int      `toml:"max_buckets"`
+	MaxScale         int      `toml:"max_scale"`
+	RelativeAccuracy float64  `toml:"relative_accuracy"`
+	ResetSketches    bool     `toml:"reset"`
+
+	fieldFilter filter.Filter
+	cache       map[uint64]*series
+}
+
+// series holds the distributions of all fields of a series
+type series struct {
+	name          string
+	tags          map[string]string
+	distributions map[string]distribution
+}
+
+func (*Sketch) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Sketch) Init() error {
+	switch s.Type {
+	case "":
+		s.Type = "exponential"
+	case "exponential", "ddsketch":
+	default:
+		return fmt.Errorf("invalid type %q", s.Type)
+	}
+
+	if s.MaxBuckets < 1 {
+		return errors.New("'max_buckets' must be greater than zero")
+	}
+	if s.MaxScale < minScale || s.MaxScale > maxScale {
+		return fmt.Errorf("'max_scale' must be between %d and %d", minScale, maxScale)
+	}
+	if s.RelativeAccuracy <= 0 || s.RelativeAccuracy >= 1 {
+		return errors.New("'relative_accuracy' must be between zero and one (exclusive)")
+	}
+
+	if len(s.Fields) == 0 {
+		s.Fields = []string{"*"}
+	}
+	f, err := filter.Compile(s.Fields)
+	if err != nil {
+		return fmt.Errorf("creating field filter failed: %w", err)
+	}
+	s.fieldFilter = f
+
+	s.cache = make(map[uint64]*series)
+
+	return nil
+}
+
+func (s *Sketch) Add(in telegraf.Metric) {
+	id := in.HashID()
+	entry, found := s.cache[id]
+	if !found {
+		entry = &series{
+			name:          in.Name(),
+			tags:          in.Tags(),
+			distributions: make(map[string]distribution),
+		}
+		s.cache[id] = entry
+	}
+
+	for _, field := range in.FieldList() {
+		if !s.fieldFilter.Match(field.Key) {
+			continue
+		}
+
+		var v float64
+		switch fv := field.Value.(type) {
+		case int64:
+			v = float64(fv)
+		case uint64:
+			v = float64(fv)
+		case float64:
+			v = fv
+		default:
+			continue
+		}
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+
+		d, found := entry.distributions[field.Key]
+		if !found {
+			d = s.newDistribution()
+			entry.distributions[field.Key] = d
+		}
+		d.add(v)
+	}
+}
+
+// Push emits the sketches as histograms in the layout of the Prometheus
+// parser, i.e. one metric with the count and sum and one metric per bucket
+// with the cumulative count and the upper bound as "le" tag.
+func (s *Sketch) Push(acc telegraf.Accumulator) {
+	now := time.Now()
+	for _, entry := range s.cache {
+		names := make([]string, 0, len(entry.distributions))
+		for field := range entry.distributions {
+			names = append(names, field)
+		}
+		sort.Strings(names)
+
+		for _, field := range names {
+			d := entry.distributions[field]
+			fields := map[string]interface{}{
+				field + "_count": float64(d.count()),
+				field + "_sum":   d.sum(),
+			}
+			acc.AddHistogram(entry.name, fields, entry.tags, now)
+
+			var cumulative uint64
+			for _, b := range d.buckets() {
+				cumulative += b.count
+				addBucket(acc, entry, field, strconv.FormatFloat(b.upper, 'g', -1, 64), cumulative, now)
+			}
+			addBucket(acc, entry, field, "+Inf", d.count(), now)
+		}
+	}
+}
+
+func (s *Sketch) Reset() {
+	if s.ResetSketches {
+		s.cache = make(map[uint64]*series)
+	}
+}
+
+// Persistent returns true if the sketches are kept across periods
+func (s *Sketch) Persistent() bool {
+	return !s.ResetSketches
+}
+
+func addBucket(acc telegraf.Accumulator, entry *series, field, le string, count uint64, now time.Time) {
+	tags := make(map[string]string, len(entry.tags)+1)
+	for k, v := range entry.tags {
+		tags[k] = v
+	}
+	tags["le"] = le
+	acc.AddHistogram(entry.name, map[string]interface{}{field + "_bucket": float64(count)}, tags, now)
+}
+
+func (s *Sketch) newDistribution() distribution {
+	if s.Type == "ddsketch" {
+		return newDDSketch(s.RelativeAccuracy, s.MaxBuckets)
+	}
+	return newExponential(s.MaxScale, s.MaxBuckets)
+}
+
+func init() {
+	aggregators.Add("sketch", func() telegraf.Aggregator {
+		return &Sketch{
+			MaxBuckets:       160,
+			MaxScale:         maxScale,
+			RelativeAccuracy: 0.01,
+			ResetSketches:    true,
+		}
+	})
+}
@@ -0,0 +1,362 @@
+package sketch
+
+import (
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/outputs/opentelemetry"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Sketch
+		expected string
+	}{
+		{
+			name:     "invalid type",
+			plugin:   &Sketch{Type: "tdigest", MaxBuckets: 160, RelativeAccuracy: 0.01},
+			expected: `invalid type "tdigest"`,
+		},
+		{
+			name:     "no buckets",
+			plugin:   &Sketch{RelativeAccuracy: 0.01},
+			expected: "'max_buckets' must be greater than zero",
+		},
+		{
+			name:     "scale too large",
+			plugin:   &Sketch{MaxBuckets: 160, MaxScale: 21, RelativeAccuracy: 0.01},
+			expected: "'max_scale' must be between -10 and 20",
+		},
+		{
+			name:     "invalid accuracy",
+			plugin:   &Sketch{MaxBuckets: 160, RelativeAccuracy: 1},
+			expected: "'relative_accuracy' must be between zero and one",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestExponentialIndex(t *testing.T) {
+	tests := []struct {
+		scale    int
+		value    float64
+		expected int
+	}{
+		{scale: 0, value: 1, expected: -1},
+		{scale: 0, value: 1.5, expected: 0},
+		{scale: 0, value: 2, expected: 0},
+		{scale: 0, value: 4, expected: 1},
+		{scale: 0, value: 0.25, expected: -3},
+		{scale: 1, value: 2, expected: 1},
+		{scale: 1, value: 2.5, expected: 2},
+		{scale: 3, value: 1024, expected: 79},
+		{scale: -1, value: 4, expected: 0},
+		{scale: -1, value: 5, expected: 1},
+		{scale: -2, value: 0.25, expected: -1},
+	}
+
+	for _, tt := range tests {
+		h := newExponential(tt.scale, 160)
+		idx := h.index(tt.value)
+		require.Equalf(t, tt.expected, idx, "index of %v at scale %d", tt.value, tt.scale)
+		require.Lessf(t, h.lower(idx), tt.value, "lower bound of %v at scale %d", tt.value, tt.scale)
+		require.GreaterOrEqualf(t, h.upper(idx), tt.value, "upper bound of %v at scale %d", tt.value, tt.scale)
+	}
+}
+
+func TestExponentialDownscale(t *testing.T) {
+	h := newExponential(maxScale, 4)
+	for _, v := range []float64{1, 2, 3, 100, 1000, -0.5, -8, 0} {
+		h.add(v)
+	}
+
+	require.LessOrEqual(t, h.positive.span(), 4)
+	require.LessOrEqual(t, h.negative.span(), 4)
+	require.Less(t, h.scale, maxScale)
+	require.Equal(t, uint64(8), h.count())
+	require.InDelta(t, 1097.5, h.sum(), 1e-9)
+
+	// All values must end up in the bucket covering them
+	var total uint64
+	buckets := h.buckets()
+	for _, b := range buckets {
+		total += b.count
+	}
+	require.Equal(t, h.count(), total)
+	require.True(t, sort.SliceIsSorted(buckets, func(i, j int) bool { return buckets[i].upper < buckets[j].upper }))
+	require.GreaterOrEqual(t, buckets[len(buckets)-1].upper, 1000.0)
+	require.Negative(t, buckets[0].upper)
+}
+
+func TestDDSketchAccuracy(t *testing.T) {
+	const accuracy = 0.01
+
+	d := newDDSketch(accuracy, 2048)
+	values := make([]float64, 0, 10000)
+	r := rand.New(rand.NewSource(42))
+	for range 10000 {
+		v := math.Exp(r.NormFloat64() * 3)
+		values = append(values, v)
+		d.add(v)
+	}
+	sort.Float64s(values)
+
+	// Determine the quantiles from the buckets and compare them with the
+	// exact values
+	buckets := d.buckets()
+	for _, q := range []float64{0.5, 0.9, 0.99} {
+		rank := uint64(q * float64(len(values)-1))
+		var cumulative uint64
+		var estimate float64
+		for _, b := range buckets {
+			cumulative += b.count
+			if cumulative > rank {
+				// Use the value with the lowest relative error in the bucket
+				estimate = 2 * b.upper / (1 + (1+accuracy)/(1-accuracy))
+				break
+			}
+		}
+		exact := values[rank]
+		require.InEpsilonf(t, exact, estimate, accuracy+1e-9, "quantile %v", q)
+	}
+}
+
+func TestDDSketchCollapse(t *testing.T) {
+	d := newDDSketch(0.01, 3)
+	for _, v := range []float64{1, 10, 100, 1000, 10000} {
+		d.add(v)
+	}
+
+	buckets := d.buckets()
+	require.Len(t, buckets, 3)
+	require.Equal(t, uint64(3), buckets[0].count)
+	require.InEpsilon(t, 100.0, buckets[0].upper, 0.02)
+}
+
+func TestPush(t *testing.T) {
+	plugin := &Sketch{
+		Fields:           []string{"latency"},
+		MaxBuckets:       160,
+		MaxScale:         0,
+		RelativeAccuracy: 0.01,
+	}
+	require.NoError(t, plugin.Init())
+
+	for _, v := range []float64{0, 1.5, 3, 3} {
+		plugin.Add(metric.New("http", map[string]string{"host": "a"}, map[string]interface{}{"latency": v, "code": 200}, time.Unix(0, 0)))
+	}
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	// At scale zero the buckets are (1, 2] and (2, 4]
+	expected := []telegraf.Metric{
+		metric.New("http",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"latency_count": 4.0, "latency_sum": 7.5},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+		bucketMetric(map[string]string{"host": "a"}, "0", 1),
+		bucketMetric(map[string]string{"host": "a"}, "2", 2),
+		bucketMetric(map[string]string{"host": "a"}, "4", 4),
+		bucketMetric(map[string]string{"host": "a"}, "+Inf", 4),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	// Without reset the sketches are kept across periods
+	plugin.Reset()
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	plugin.ResetSketches = true
+	plugin.Reset()
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestPushDDSketch(t *testing.T) {
+	plugin := &Sketch{
+		Type:             "ddsketch",
+		MaxBuckets:       160,
+		RelativeAccuracy: 0.5,
+	}
+	require.NoError(t, plugin.Init())
+
+	for _, v := range []float64{1, 2, 5, -2} {
+		plugin.Add(metric.New("http", map[string]string{}, map[string]interface{}{"latency": v}, time.Unix(0, 0)))
+	}
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	// With an accuracy of 0.5 gamma is 3 so the buckets are [-3, -1),
+	// (1/3, 1], (1, 3] and (3, 9]
+	expected := []telegraf.Metric{
+		metric.New("http",
+			map[string]string{},
+			map[string]interface{}{"latency_count": 4.0, "latency_sum": 6.0},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+		bucketMetric(map[string]string{}, "-1", 1),
+		bucketMetric(map[string]string{}, "1", 2),
+		bucketMetric(map[string]string{}, "3", 3),
+		bucketMetric(map[string]string{}, "9", 4),
+		bucketMetric(map[string]string{}, "+Inf", 4),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+}
+
+func TestSerializers(t *testing.T) {
+	plugin := &Sketch{
+		MaxBuckets:       160,
+		MaxScale:         0,
+		RelativeAccuracy: 0.01,
+	}
+	require.NoError(t, plugin.Init())
+
+	for _, v := range []float64{0, 1.5, 3, 3} {
+		plugin.Add(metric.New("http", map[string]string{"host": "a"}, map[string]interface{}{"latency": v}, time.Unix(0, 0)))
+	}
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	metrics := acc.GetTelegrafMetrics()
+
+	t.Run("prometheus", func(t *testing.T) {
+		s := &prometheus.Serializer{FormatConfig: prometheus.FormatConfig{SortMetrics: true}}
+		require.NoError(t, s.Init())
+		data, err := s.SerializeBatch(metrics)
+		require.NoError(t, err)
+
+		expected := []string{
+			`# TYPE http_latency histogram`,
+			`http_latency_bucket{host="a",le="0"} 1`,
+			`http_latency_bucket{host="a",le="2"} 2`,
+			`http_latency_bucket{host="a",le="4"} 4`,
+			`http_latency_bucket{host="a",le="+Inf"} 4`,
+			`http_latency_sum{host="a"} 7.5`,
+			`http_latency_count{host="a"} 4`,
+		}
+		for _, line := range expected {
+			require.Contains(t, string(data), line+"\n")
+		}
+	})
+
+	t.Run("prometheus native histogram", func(t *testing.T) {
+		s := &prometheusremotewrite.Serializer{Version: "2.0", NativeHistograms: true, Log: &testutil.Logger{}}
+		require.NoError(t, s.Init())
+		data, err := s.SerializeBatch(metrics)
+		require.NoError(t, err)
+
+		buf, err := snappy.Decode(nil, data)
+		require.NoError(t, err)
+		var req writev2.Request
+		require.NoError(t, req.Unmarshal(buf))
+		require.Len(t, req.Timeseries, 1)
+		require.Len(t, req.Timeseries[0].Histograms, 1)
+
+		native := req.Timeseries[0].Histograms[0].ToIntHistogram()
+		require.NoError(t, native.Validate())
+		require.Equal(t, uint64(4), native.Count)
+		require.InDelta(t, 7.5, native.Sum, 1e-9)
+		require.Equal(t, []float64{0, 2, 4}, native.CustomValues)
+	})
+
+	t.Run("opentelemetry", func(t *testing.T) {
+		var got pmetricotlp.ExportRequest
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			got = pmetricotlp.NewExportRequest()
+			if err := got.UnmarshalProto(body); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, err := pmetricotlp.NewExportResponse().MarshalProto()
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			if _, err := w.Write(data); err != nil {
+				t.Error(err)
+			}
+		}))
+		defer ts.Close()
+
+		output := &opentelemetry.OpenTelemetry{
+			ServiceAddress: ts.URL,
+			Protocol:       "http",
+			Compression:    "none",
+			Log:            &testutil.Logger{},
+		}
+		require.NoError(t, output.Init())
+		require.NoError(t, output.Connect())
+		defer output.Close()
+		require.NoError(t, output.Write(metrics))
+
+		scopeMetrics := got.Metrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+		require.Equal(t, 1, scopeMetrics.Len())
+		require.Equal(t, "http_latency", scopeMetrics.At(0).Name())
+		require.Equal(t, 1, scopeMetrics.At(0).Histogram().DataPoints().Len())
+
+		dp := scopeMetrics.At(0).Histogram().DataPoints().At(0)
+		require.Equal(t, uint64(4), dp.Count())
+		require.InDelta(t, 7.5, dp.Sum(), 1e-9)
+		require.Equal(t, []float64{0, 2, 4}, dp.ExplicitBounds().AsRaw())
+		require.Equal(t, []uint64{1, 1, 2, 0}, dp.BucketCounts().AsRaw())
+	})
+}
+
+func TestResetDefault(t *testing.T) {
+	creator, found := aggregators.Aggregators["sketch"]
+	require.True(t, found)
+	plugin, ok := creator().(*Sketch)
+	require.True(t, ok)
+	require.NoError(t, plugin.Init())
+
+	plugin.Add(metric.New("http", map[string]string{}, map[string]interface{}{"latency": 1.0}, time.Unix(0, 0)))
+	plugin.Reset()
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func bucketMetric(tags map[string]string, le string, count float64) telegraf.Metric {
+	bucketTags := map[string]string{"le": le}
+	for k, v := range tags {
+		bucketTags[k] = v
+	}
+	return metric.New("http", bucketTags, map[string]interface{}{"latency_bucket": count}, time.Unix(0, 0), telegraf.Histogram)
+}
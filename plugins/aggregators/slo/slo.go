@@ -0,0 +1,434 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package slo
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/ext"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type SLO struct {
+	Windows    []config.Duration `toml:"windows"`
+	Resolution config.Duration   `toml:"resolution"`
+	Objectives []*objective      `toml:"objective"`
+	Log        telegraf.Logger   `toml:"-"`
+
+	windows []window
+}
+
+type objective struct {
+	Name         string   `toml:"name"`
+	Target       float64  `toml:"target"`
+	Measurements []string `toml:"measurements"`
+	GroupBy      []string `toml:"group_by"`
+	GoodField    string   `toml:"good_field"`
+	TotalField   string   `toml:"total_field"`
+	Histogram    string   `toml:"histogram"`
+	Threshold    float64  `toml:"threshold"`
+	Condition    string   `toml:"condition"`
+	CounterMode  string   `toml:"counter_mode"`
+
+	measurements filter.Filter
+	condition    cel.Program
+	// Last values of cumulative counters keyed by series and role
+	last   map[string]counter
+	groups map[string]*group
+}
+
+// counter holds the last value of a cumulative counter and the time of the
+// metric it was taken from
+type counter struct {
+	value float64
+	seen  time.Time
+}
+
+type window struct {
+	duration time.Duration
+	suffix   string
+}
+
+// group holds the event counts of an objective per time bucket
+type group struct {
+	tags    map[string]string
+	buckets map[int64]*counts
+}
+
+type counts struct {
+	good  float64
+	total float64
+}
+
+func (*SLO) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SLO) Init() error {
+	if len(s.Objectives) == 0 {
+		return errors.New("no objectives specified")
+	}
+	if s.Resolution == 0 {
+		s.Resolution = config.Duration(time.Minute)
+	}
+	if s.Resolution < 0 {
+		return errors.New("'resolution' must be positive")
+	}
+	if len(s.Windows) == 0 {
+		s.Windows = []config.Duration{
+			config.Duration(5 * time.Minute),
+			config.Duration(time.Hour),
+			config.Duration(6 * time.Hour),
+			config.Duration(72 * time.Hour),
+		}
+	}
+	seen := make(map[time.Duration]bool, len(s.Windows))
+	for _, w := range s.Windows {
+		d := time.Duration(w)
+		if d < time.Duration(s.Resolution) {
+			return fmt.Errorf("window %s must not be smaller than the resolution", d)
+		}
+		if seen[d] {
+			return fmt.Errorf("duplicate window %s", d)
+		}
+		seen[d] = true
+		s.windows = append(s.windows, window{duration: d, suffix: internal.ShortDuration(d)})
+	}
+
+	env, err := cel.NewEnv(
+		cel.Declarations(
+			decls.NewVar("name", decls.String),
+			decls.NewVar("tags", decls.NewMapType(decls.String, decls.String)),
+			decls.NewVar("fields", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("time", decls.Timestamp),
+		),
+		ext.Math(),
+		ext.Strings(),
+	)
+	if err != nil {
+		return fmt.Errorf("creating environment failed: %w", err)
+	}
+
+	names := make(map[string]bool, len(s.Objectives))
+	for i, o := range s.Objectives {
+		if err := o.init(env); err != nil {
+			return fmt.Errorf("objective %d: %w", i+1, err)
+		}
+		if names[o.Name] {
+			return fmt.Errorf("duplicate objective %q", o.Name)
+		}
+		names[o.Name] = true
+	}
+
+	return nil
+}
+
+func (o *objective) init(env *cel.Env) error {
+	if o.Name == "" {
+		return errors.New("no name specified")
+	}
+	if o.Target <= 0 || o.Target >= 1 {
+		return errors.New("'target' must be between zero and one")
+	}
+
+	var sources int
+	if o.GoodField != "" || o.TotalField != "" {
+		if o.GoodField == "" || o.TotalField == "" {
+			return errors.New("both 'good_field' and 'total_field' must be specified")
+		}
+		sources++
+	}
+	if o.Histogram != "" {
+		if o.Threshold <= 0 {
+			return errors.New("'threshold' must be positive")
+		}
+		sources++
+	}
+	if o.Condition != "" {
+		ast, issues := env.Compile(o.Condition)
+		if issues.Err() != nil {
+			return fmt.Errorf("compiling condition failed: %w", issues.Err())
+		}
+		if ast.OutputType() != cel.BoolType {
+			return errors.New("condition needs to return a boolean")
+		}
+		program, err := env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+		if err != nil {
+			return fmt.Errorf("creating program failed: %w", err)
+		}
+		o.condition = program
+		sources++
+	}
+	if sources != 1 {
+		return errors.New("exactly one of counter fields, histogram or condition must be specified")
+	}
+
+	switch o.CounterMode {
+	case "":
+		o.CounterMode = "cumulative"
+	case "cumulative", "delta":
+	default:
+		return fmt.Errorf("unknown counter mode %q", o.CounterMode)
+	}
+
+	f, err := filter.Compile(o.Measurements)
+	if err != nil {
+		return fmt.Errorf("creating measurement filter failed: %w", err)
+	}
+	o.measurements = f
+
+	o.last = make(map[string]counter)
+	o.groups = make(map[string]*group)
+
+	return nil
+}
+
+func (s *SLO) Add(in telegraf.Metric) {
+	bucket := in.Time().Truncate(time.Duration(s.Resolution)).UnixNano()
+	for _, o := range s.Objectives {
+		if o.measurements != nil && !o.measurements.Match(in.Name()) {
+			continue
+		}
+
+		good, total, err := o.events(in)
+		if err != nil {
+			s.Log.Debugf("Evaluating objective %q for %v failed: %v", o.Name, in, err)
+			continue
+		}
+		if good == 0 && total == 0 {
+			continue
+		}
+
+		tags := o.groupTags(in)
+		key := seriesKey("", tags)
+		g, found := o.groups[key]
+		if !found {
+			g = &group{tags: tags, buckets: make(map[int64]*counts)}
+			o.groups[key] = g
+		}
+		c, found := g.buckets[bucket]
+		if !found {
+			c = &counts{}
+			g.buckets[bucket] = c
+		}
+		c.good += good
+		c.total += total
+	}
+}
+
+func (s *SLO) Push(acc telegraf.Accumulator) {
+	now := time.Now()
+	// Buckets starting before the oldest window are not needed anymore
+	var oldest time.Duration
+	for _, w := range s.windows {
+		oldest = max(oldest, w.duration)
+	}
+	limit := now.Add(-oldest).Truncate(time.Duration(s.Resolution)).UnixNano()
+
+	for _, o := range s.Objectives {
+		// Counters of series without metrics during the oldest window cannot
+		// contribute to any window anymore
+		for key, c := range o.last {
+			if c.seen.UnixNano() < limit {
+				delete(o.last, key)
+			}
+		}
+
+		for key, g := range o.groups {
+			for start := range g.buckets {
+				if start < limit {
+					delete(g.buckets, start)
+				}
+			}
+			if len(g.buckets) == 0 {
+				delete(o.groups, key)
+				continue
+			}
+
+			fields := map[string]interface{}{"target": o.Target}
+			for _, w := range s.windows {
+				since := now.Add(-w.duration).UnixNano()
+				var good, total float64
+				for start, c := range g.buckets {
+					if start >= since {
+						good += c.good
+						total += c.total
+					}
+				}
+				if total == 0 {
+					continue
+				}
+				errorRatio := max(1-good/total, 0)
+				fields["error_ratio_"+w.suffix] = errorRatio
+				fields["burn_rate_"+w.suffix] = errorRatio / (1 - o.Target)
+			}
+
+			tags := make(map[string]string, len(g.tags)+1)
+			for k, v := range g.tags {
+				tags[k] = v
+			}
+			tags["slo"] = o.Name
+			acc.AddFields("slo", fields, tags, now)
+		}
+	}
+}
+
+// Reset is a no-op as the windows span multiple periods; outdated buckets are
+// removed when pushing.
+func (*SLO) Reset() {}
+
+// Persistent returns true as the event buckets span multiple periods
+func (*SLO) Persistent() bool {
+	return true
+}
+
+// Determine the number of good and total events contained in the metric
+func (o *objective) events(in telegraf.Metric) (good, total float64, err error) {
+	if o.condition != nil {
+		vars := map[string]interface{}{
+			"name":   in.Name(),
+			"tags":   in.Tags(),
+			"fields": in.Fields(),
+			"time":   in.Time(),
+		}
+		result, _, err := o.condition.Eval(vars)
+		if err != nil {
+			return 0, 0, err
+		}
+		match, ok := result.Value().(bool)
+		if !ok {
+			return 0, 0, fmt.Errorf("invalid result type %T", result.Value())
+		}
+		if match {
+			return 1, 1, nil
+		}
+		return 0, 1, nil
+	}
+
+	series := seriesKey(in.Name(), seriesTags(in))
+	if o.Histogram == "" {
+		if v, found := in.GetField(o.GoodField); found {
+			good = o.increase(series+"good", v, in.Time())
+		}
+		if v, found := in.GetField(o.TotalField); found {
+			total = o.increase(series+"total", v, in.Time())
+		}
+		return good, total, nil
+	}
+
+	// Support both, the v1 layout of the Prometheus input with the histogram
+	// name as measurement and the bucket bounds as fields, and the v2 layout
+	// with one field per bucket and the bound in the "le" tag
+	if in.Name() == o.Histogram {
+		for _, field := range in.FieldList() {
+			if field.Key == "count" {
+				total = o.increase(series+"total", field.Value, in.Time())
+			} else if le, err := strconv.ParseFloat(field.Key, 64); err == nil && le == o.Threshold {
+				good = o.increase(series+"good", field.Value, in.Time())
+			}
+		}
+		return good, total, nil
+	}
+	if v, found := in.GetField(o.Histogram + "_bucket"); found {
+		if le, ok := in.GetTag("le"); ok {
+			if bound, err := strconv.ParseFloat(le, 64); err == nil && bound == o.Threshold {
+				good = o.increase(series+"good", v, in.Time())
+			}
+		}
+	}
+	if v, found := in.GetField(o.Histogram + "_count"); found {
+		total = o.increase(series+"total", v, in.Time())
+	}
+	return good, total, nil
+}
+
+// Compute the increase of a counter taking counter resets into account
+func (o *objective) increase(key string, value interface{}, t time.Time) float64 {
+	var v float64
+	switch fv := value.(type) {
+	case int64:
+		v = float64(fv)
+	case uint64:
+		v = float64(fv)
+	case float64:
+		v = fv
+	default:
+		return 0
+	}
+
+	if o.CounterMode == "delta" {
+		return v
+	}
+
+	last, found := o.last[key]
+	o.last[key] = counter{value: v, seen: t}
+	switch {
+	case !found:
+		return 0
+	case v < last.value:
+		return v
+	}
+	return v - last.value
+}
+
+func (o *objective) groupTags(in telegraf.Metric) map[string]string {
+	if len(o.GroupBy) == 0 {
+		return seriesTags(in)
+	}
+	tags := make(map[string]string, len(o.GroupBy))
+	for _, key := range o.GroupBy {
+		if v, found := in.GetTag(key); found {
+			tags[key] = v
+		}
+	}
+	return tags
+}
+
+// Tags identifying the series of a metric ignoring the bucket bound of
+// histograms
+func seriesTags(in telegraf.Metric) map[string]string {
+	tags := make(map[string]string)
+	for _, tag := range in.TagList() {
+		if tag.Key != "le" {
+			tags[tag.Key] = tag.Value
+		}
+	}
+	return tags
+}
+
+func seriesKey(name string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(name)
+	for _, k := range keys {
+		b.WriteString("\x00" + k + "=" + tags[k])
+	}
+	b.WriteString("\x00")
+	return b.String()
+}
+
+func init() {
+	aggregators.Add("slo", func() telegraf.Aggregator {
+		return &SLO{}
+	})
+}
@@ -0,0 +1,287 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name       string
+		windows    []config.Duration
+		objectives []*objective
+		expected   string
+	}{
+		{
+			name:     "no objectives",
+			expected: "no objectives specified",
+		},
+		{
+			name:       "window smaller than resolution",
+			windows:    []config.Duration{config.Duration(time.Second)},
+			objectives: []*objective{{Name: "a", Target: 0.99, Condition: "true"}},
+			expected:   "window 1s must not be smaller than the resolution",
+		},
+		{
+			name:       "no name",
+			objectives: []*objective{{Target: 0.99, Condition: "true"}},
+			expected:   "objective 1: no name specified",
+		},
+		{
+			name:       "invalid target",
+			objectives: []*objective{{Name: "a", Target: 99, Condition: "true"}},
+			expected:   "objective 1: 'target' must be between zero and one",
+		},
+		{
+			name:       "no source",
+			objectives: []*objective{{Name: "a", Target: 0.99}},
+			expected:   "objective 1: exactly one of counter fields, histogram or condition must be specified",
+		},
+		{
+			name:       "multiple sources",
+			objectives: []*objective{{Name: "a", Target: 0.99, Condition: "true", Histogram: "latency", Threshold: 0.5}},
+			expected:   "objective 1: exactly one of counter fields, histogram or condition must be specified",
+		},
+		{
+			name:       "missing total field",
+			objectives: []*objective{{Name: "a", Target: 0.99, GoodField: "ok"}},
+			expected:   "objective 1: both 'good_field' and 'total_field' must be specified",
+		},
+		{
+			name:       "non-boolean condition",
+			objectives: []*objective{{Name: "a", Target: 0.99, Condition: "fields.value + 1"}},
+			expected:   "objective 1: condition needs to return a boolean",
+		},
+		{
+			name:       "unknown counter mode",
+			objectives: []*objective{{Name: "a", Target: 0.99, Histogram: "latency", Threshold: 0.5, CounterMode: "gauge"}},
+			expected:   `objective 1: unknown counter mode "gauge"`,
+		},
+		{
+			name: "duplicate objective",
+			objectives: []*objective{
+				{Name: "a", Target: 0.99, Condition: "true"},
+				{Name: "a", Target: 0.9, Condition: "true"},
+			},
+			expected: `duplicate objective "a"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &SLO{Windows: tt.windows, Objectives: tt.objectives, Log: &testutil.Logger{}}
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestCounters(t *testing.T) {
+	plugin := &SLO{
+		Windows: []config.Duration{config.Duration(5 * time.Minute), config.Duration(time.Hour)},
+		Objectives: []*objective{
+			{
+				Name:       "availability",
+				Target:     0.99,
+				GoodField:  "requests_ok",
+				TotalField: "requests_total",
+			},
+		},
+		Log: &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	samples := []struct {
+		ago   time.Duration
+		ok    int64
+		total int64
+	}{
+		{30 * time.Minute, 990, 1000}, // baseline
+		{20 * time.Minute, 1080, 1100},
+		{2 * time.Minute, 45, 50}, // counter reset
+		{1 * time.Minute, 145, 150},
+	}
+	for _, s := range samples {
+		fields := map[string]interface{}{"requests_ok": s.ok, "requests_total": s.total}
+		plugin.Add(metric.New("api", map[string]string{"host": "a"}, fields, now.Add(-s.ago)))
+	}
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("slo",
+			map[string]string{"host": "a", "slo": "availability"},
+			map[string]interface{}{
+				"target":         0.99,
+				"error_ratio_5m": 5.0 / 150.0,
+				"burn_rate_5m":   5.0 / 150.0 / 0.01,
+				"error_ratio_1h": 15.0 / 250.0,
+				"burn_rate_1h":   15.0 / 250.0 / 0.01,
+			},
+			now,
+		),
+	}
+	options := []cmp.Option{testutil.IgnoreTime(), cmpopts.EquateApprox(0, 1e-9)}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), options...)
+}
+
+func TestExpireCounters(t *testing.T) {
+	plugin := &SLO{
+		Windows: []config.Duration{config.Duration(time.Hour)},
+		Objectives: []*objective{
+			{
+				Name:       "availability",
+				Target:     0.99,
+				GoodField:  "requests_ok",
+				TotalField: "requests_total",
+			},
+		},
+		Log: &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	fields := map[string]interface{}{"requests_ok": int64(990), "requests_total": int64(1000)}
+	plugin.Add(metric.New("api", map[string]string{"host": "idle"}, fields, now.Add(-2*time.Hour)))
+	plugin.Add(metric.New("api", map[string]string{"host": "active"}, fields, now.Add(-time.Minute)))
+	require.Len(t, plugin.Objectives[0].last, 4)
+
+	// Only the counters of the active series are kept
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	require.Len(t, plugin.Objectives[0].last, 2)
+	for key := range plugin.Objectives[0].last {
+		require.Contains(t, key, "active")
+	}
+}
+
+func TestCondition(t *testing.T) {
+	plugin := &SLO{
+		Windows: []config.Duration{config.Duration(5 * time.Minute)},
+		Objectives: []*objective{
+			{
+				Name:         "probe",
+				Target:       0.9,
+				Measurements: []string{"http_response"},
+				GroupBy:      []string{"region"},
+				Condition:    "fields.result_code == 0",
+			},
+		},
+		Log: &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	for i, code := range []int64{0, 0, 1, 0} {
+		host := "a"
+		if i%2 == 1 {
+			host = "b"
+		}
+		tags := map[string]string{"host": host, "region": "eu"}
+		plugin.Add(metric.New("http_response", tags, map[string]interface{}{"result_code": code}, now))
+	}
+	// Other measurements must be ignored
+	plugin.Add(metric.New("ping", map[string]string{"region": "eu"}, map[string]interface{}{"result_code": int64(1)}, now))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		metric.New("slo",
+			map[string]string{"region": "eu", "slo": "probe"},
+			map[string]interface{}{
+				"target":         0.9,
+				"error_ratio_5m": 0.25,
+				"burn_rate_5m":   2.5,
+			},
+			now,
+		),
+	}
+	options := []cmp.Option{testutil.IgnoreTime(), cmpopts.EquateApprox(0, 1e-9)}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), options...)
+}
+
+func TestHistogram(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		metrics []telegraf.Metric
+	}{
+		{
+			name: "v1 layout",
+			metrics: []telegraf.Metric{
+				metric.New("latency",
+					map[string]string{"path": "/"},
+					map[string]interface{}{"0.1": 50.0, "0.5": 80.0, "+Inf": 100.0, "count": 100.0, "sum": 20.0},
+					now.Add(-2*time.Minute),
+				),
+				metric.New("latency",
+					map[string]string{"path": "/"},
+					map[string]interface{}{"0.1": 100.0, "0.5": 170.0, "+Inf": 200.0, "count": 200.0, "sum": 40.0},
+					now.Add(-time.Minute),
+				),
+			},
+		},
+		{
+			name: "v2 layout",
+			metrics: []telegraf.Metric{
+				metric.New("prometheus", map[string]string{"path": "/", "le": "0.1"}, map[string]interface{}{"latency_bucket": 50.0}, now.Add(-2*time.Minute)),
+				metric.New("prometheus", map[string]string{"path": "/", "le": "0.5"}, map[string]interface{}{"latency_bucket": 80.0}, now.Add(-2*time.Minute)),
+				metric.New("prometheus", map[string]string{"path": "/"}, map[string]interface{}{"latency_count": 100.0, "latency_sum": 20.0}, now.Add(-2*time.Minute)),
+				metric.New("prometheus", map[string]string{"path": "/", "le": "0.1"}, map[string]interface{}{"latency_bucket": 100.0}, now.Add(-time.Minute)),
+				metric.New("prometheus", map[string]string{"path": "/", "le": "0.5"}, map[string]interface{}{"latency_bucket": 170.0}, now.Add(-time.Minute)),
+				metric.New("prometheus", map[string]string{"path": "/"}, map[string]interface{}{"latency_count": 200.0, "latency_sum": 40.0}, now.Add(-time.Minute)),
+			},
+		},
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("slo",
+			map[string]string{"path": "/", "slo": "latency"},
+			map[string]interface{}{
+				"target":         0.95,
+				"error_ratio_5m": 0.1,
+				"burn_rate_5m":   2.0,
+			},
+			now,
+		),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &SLO{
+				Windows: []config.Duration{config.Duration(5 * time.Minute)},
+				Objectives: []*objective{
+					{
+						Name:      "latency",
+						Target:    0.95,
+						Histogram: "latency",
+						Threshold: 0.5,
+					},
+				},
+				Log: &testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			for _, m := range tt.metrics {
+				plugin.Add(m)
+			}
+
+			var acc testutil.Accumulator
+			plugin.Push(&acc)
+
+			options := []cmp.Option{testutil.IgnoreTime(), cmpopts.EquateApprox(0, 1e-9)}
+			testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), options...)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+// Package kubernetes contains helpers for plugins accessing the Kubernetes API
+package kubernetes
+
+import (
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// NewClientset creates a client for the Kubernetes API server at the given
+// URL. If the URL is empty, the in-cluster configuration is used. The bearer
+// token file takes precedence over the bearer token if both are given.
+func NewClientset(baseURL, bearerTokenFile, bearerToken string, tlsConfig tls.ClientConfig) (*kubernetes.Clientset, error) {
+	var clientConfig *rest.Config
+	var err error
+
+	if baseURL == "" {
+		clientConfig, err = rest.InClusterConfig()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		clientConfig = &rest.Config{
+			TLSClientConfig: rest.TLSClientConfig{
+				ServerName: tlsConfig.ServerName,
+				Insecure:   tlsConfig.InsecureSkipVerify,
+				CAFile:     tlsConfig.TLSCA,
+				CertFile:   tlsConfig.TLSCert,
+				KeyFile:    tlsConfig.TLSKey,
+			},
+			Host:          baseURL,
+			ContentConfig: rest.ContentConfig{},
+		}
+
+		if bearerTokenFile != "" {
+			clientConfig.BearerTokenFile = bearerTokenFile
+		} else if bearerToken != "" {
+			clientConfig.BearerToken = bearerToken
+		}
+	}
+
+	return kubernetes.NewForConfig(clientConfig)
+}
@@ -0,0 +1,174 @@
+package pulsar
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	common_tls "github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// Config common to all Pulsar clients.
+type Config struct {
+	ServiceURL        string          `toml:"service_url"`
+	Token             config.Secret   `toml:"token"`
+	ConnectionTimeout config.Duration `toml:"connection_timeout"`
+	OperationTimeout  config.Duration `toml:"operation_timeout"`
+	common_tls.ClientConfig
+}
+
+// ReadConfig for Pulsar clients meaning to consume messages.
+type ReadConfig struct {
+	Config
+
+	Topics           []string `toml:"topics"`
+	TopicsPattern    string   `toml:"topics_pattern"`
+	SubscriptionName string   `toml:"subscription_name"`
+	SubscriptionType string   `toml:"subscription_type"`
+	InitialPosition  string   `toml:"initial_position"`
+}
+
+// WriteConfig for Pulsar clients meaning to produce messages.
+type WriteConfig struct {
+	Config
+
+	Compression             string          `toml:"compression"`
+	BatchingMaxPublishDelay config.Duration `toml:"batching_max_publish_delay"`
+	SendTimeout             config.Duration `toml:"send_timeout"`
+}
+
+// ClientOptions creates the options for creating a Pulsar client. The token
+// is not resolved here, use Authenticate right before creating the client to
+// keep the secret in memory as short as possible.
+func (c *Config) ClientOptions(log telegraf.Logger) (pulsar.ClientOptions, error) {
+	if c.ServiceURL == "" {
+		return pulsar.ClientOptions{}, errors.New("'service_url' must not be empty")
+	}
+	if c.ServerName != "" {
+		return pulsar.ClientOptions{}, errors.New("'tls_server_name' is not supported")
+	}
+
+	options := pulsar.ClientOptions{
+		URL:               c.ServiceURL,
+		ConnectionTimeout: time.Duration(c.ConnectionTimeout),
+		OperationTimeout:  time.Duration(c.OperationTimeout),
+		Logger:            NewLogger(log),
+	}
+
+	// The Pulsar client does not accept a TLS configuration, so check the
+	// settings and transfer them to the client options
+	tlsCfg, err := c.TLSConfig()
+	if err != nil {
+		return pulsar.ClientOptions{}, err
+	}
+	if tlsCfg == nil {
+		return options, nil
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return pulsar.ClientOptions{}, errors.New("'tls_cert' and 'tls_key' must be specified together")
+	}
+	options.TLSTrustCertsFilePath = c.TLSCA
+	options.TLSAllowInsecureConnection = c.InsecureSkipVerify
+	options.TLSValidateHostname = !c.InsecureSkipVerify
+	options.TLSMinVersion = tlsCfg.MinVersion
+	options.TLSCipherSuites = tlsCfg.CipherSuites
+
+	// Use the client certificate for authentication unless a token is given
+	if len(tlsCfg.Certificates) > 0 {
+		cert := tlsCfg.Certificates[0]
+		options.Authentication = pulsar.NewAuthenticationFromTLSCertSupplier(func() (*tls.Certificate, error) {
+			return &cert, nil
+		})
+	}
+
+	return options, nil
+}
+
+// Authenticate resolves the token, if any, and sets the authentication of the
+// given client options. The token takes precedence over a client certificate.
+func (c *Config) Authenticate(options *pulsar.ClientOptions) error {
+	if c.Token.Empty() {
+		return nil
+	}
+	token, err := c.Token.Get()
+	if err != nil {
+		return fmt.Errorf("getting token failed: %w", err)
+	}
+	options.Authentication = pulsar.NewAuthenticationToken(token.String())
+	token.Destroy()
+	return nil
+}
+
+// ConsumerOptions creates the options for subscribing to the configured
+// topics
+func (c *ReadConfig) ConsumerOptions() (pulsar.ConsumerOptions, error) {
+	if len(c.Topics) == 0 && c.TopicsPattern == "" {
+		return pulsar.ConsumerOptions{}, errors.New("either 'topics' or 'topics_pattern' must be specified")
+	}
+	if len(c.Topics) > 0 && c.TopicsPattern != "" {
+		return pulsar.ConsumerOptions{}, errors.New("'topics' and 'topics_pattern' cannot be used together")
+	}
+	if c.SubscriptionName == "" {
+		return pulsar.ConsumerOptions{}, errors.New("'subscription_name' must not be empty")
+	}
+
+	options := pulsar.ConsumerOptions{
+		Topics:           c.Topics,
+		TopicsPattern:    c.TopicsPattern,
+		SubscriptionName: c.SubscriptionName,
+	}
+
+	switch c.SubscriptionType {
+	case "", "shared":
+		options.Type = pulsar.Shared
+	case "exclusive":
+		options.Type = pulsar.Exclusive
+	case "failover":
+		options.Type = pulsar.Failover
+	case "key_shared":
+		options.Type = pulsar.KeyShared
+	default:
+		return pulsar.ConsumerOptions{}, fmt.Errorf("invalid subscription type %q", c.SubscriptionType)
+	}
+
+	switch c.InitialPosition {
+	case "", "latest":
+		options.SubscriptionInitialPosition = pulsar.SubscriptionPositionLatest
+	case "earliest":
+		options.SubscriptionInitialPosition = pulsar.SubscriptionPositionEarliest
+	default:
+		return pulsar.ConsumerOptions{}, fmt.Errorf("invalid initial position %q", c.InitialPosition)
+	}
+
+	return options, nil
+}
+
+// ProducerOptions creates the options for producing messages to the given
+// topic
+func (c *WriteConfig) ProducerOptions(topic string) (pulsar.ProducerOptions, error) {
+	options := pulsar.ProducerOptions{
+		Topic:                   topic,
+		BatchingMaxPublishDelay: time.Duration(c.BatchingMaxPublishDelay),
+		SendTimeout:             time.Duration(c.SendTimeout),
+	}
+
+	switch c.Compression {
+	case "", "none":
+		options.CompressionType = pulsar.NoCompression
+	case "lz4":
+		options.CompressionType = pulsar.LZ4
+	case "zlib":
+		options.CompressionType = pulsar.ZLib
+	case "zstd":
+		options.CompressionType = pulsar.ZSTD
+	default:
+		return pulsar.ProducerOptions{}, fmt.Errorf("invalid compression %q", c.Compression)
+	}
+
+	return options, nil
+}
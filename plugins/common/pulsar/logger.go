@@ -0,0 +1,103 @@
+package pulsar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/pulsar-client-go/pulsar/log"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Logger forwards the messages of the Pulsar client library to the plugin's
+// logger. Informational messages are logged at debug level as the library
+// is very verbose.
+type Logger struct {
+	log    telegraf.Logger
+	fields log.Fields
+}
+
+// NewLogger creates a Pulsar logger for the given plugin logger
+func NewLogger(l telegraf.Logger) *Logger {
+	return &Logger{log: l}
+}
+
+func (l *Logger) SubLogger(fields log.Fields) log.Logger {
+	return l.with(fields)
+}
+
+func (l *Logger) WithFields(fields log.Fields) log.Entry {
+	return l.with(fields)
+}
+
+func (l *Logger) WithField(name string, value interface{}) log.Entry {
+	return l.with(log.Fields{name: value})
+}
+
+func (l *Logger) WithError(err error) log.Entry {
+	return l.with(log.Fields{"error": err})
+}
+
+func (l *Logger) Debug(args ...interface{}) {
+	l.log.Debug(l.message(fmt.Sprint(args...)))
+}
+
+func (l *Logger) Info(args ...interface{}) {
+	l.log.Debug(l.message(fmt.Sprint(args...)))
+}
+
+func (l *Logger) Warn(args ...interface{}) {
+	l.log.Warn(l.message(fmt.Sprint(args...)))
+}
+
+func (l *Logger) Error(args ...interface{}) {
+	l.log.Error(l.message(fmt.Sprint(args...)))
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log.Debug(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log.Debug(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log.Warn(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log.Error(l.message(fmt.Sprintf(format, args...)))
+}
+
+func (l *Logger) with(fields log.Fields) *Logger {
+	merged := make(log.Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &Logger{log: l.log, fields: merged}
+}
+
+// Append the fields sorted by name to the message
+func (l *Logger) message(msg string) string {
+	if len(l.fields) == 0 {
+		return msg
+	}
+
+	keys := make([]string, 0, len(l.fields))
+	for k := range l.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for _, k := range keys {
+		fmt.Fprintf(&sb, " %s=%v", k, l.fields[k])
+	}
+	return sb.String()
+}
@@ -0,0 +1,58 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+)
+
+// Config common to all Redis clients.
+type Config struct {
+	Address  string          `toml:"address"`
+	Username config.Secret   `toml:"username"`
+	Password config.Secret   `toml:"password"`
+	Database int             `toml:"database"`
+	Timeout  config.Duration `toml:"timeout"`
+	tls.ClientConfig
+}
+
+// NewClient creates a Redis client from the configuration, the connection is
+// established lazily on the first command
+func (c *Config) NewClient() (*redis.Client, error) {
+	if c.Address == "" {
+		return nil, errors.New("'address' must not be empty")
+	}
+
+	tlsConfig, err := c.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := c.Username.Get()
+	if err != nil {
+		return nil, fmt.Errorf("getting username failed: %w", err)
+	}
+	defer username.Destroy()
+
+	password, err := c.Password.Get()
+	if err != nil {
+		return nil, fmt.Errorf("getting password failed: %w", err)
+	}
+	defer password.Destroy()
+
+	return redis.NewClient(&redis.Options{
+		Addr:         c.Address,
+		Username:     username.String(),
+		Password:     password.String(),
+		DB:           c.Database,
+		DialTimeout:  time.Duration(c.Timeout),
+		ReadTimeout:  time.Duration(c.Timeout),
+		WriteTimeout: time.Duration(c.Timeout),
+		TLSConfig:    tlsConfig,
+	}), nil
+}
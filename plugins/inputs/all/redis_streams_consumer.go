@@ -0,0 +1,5 @@
+//go:build !custom || inputs || inputs.redis_streams_consumer
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/inputs/redis_streams_consumer" // register plugin
@@ -3,17 +3,14 @@ package docker
 import (
 	"context"
 	"crypto/tls"
-	"net/http"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/swarm"
 	"github.com/docker/docker/api/types/system"
 	"github.com/docker/docker/client"
-)
 
-var (
-	defaultHeaders = map[string]string{"User-Agent": "engine-api-cli-1.0"}
+	"github.com/influxdata/telegraf/internal/docker"
 )
 
 type dockerClient interface {
@@ -30,7 +27,7 @@ type dockerClient interface {
 }
 
 func newEnvClient() (dockerClient, error) {
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv)
+	dockerClient, err := docker.NewEnvClient()
 	if err != nil {
 		return nil, err
 	}
@@ -38,20 +35,10 @@ func newEnvClient() (dockerClient, error) {
 }
 
 func newClient(host string, tlsConfig *tls.Config) (dockerClient, error) {
-	transport := &http.Transport{
-		TLSClientConfig: tlsConfig,
-	}
-	httpClient := &http.Client{Transport: transport}
-
-	dockerClient, err := client.NewClientWithOpts(
-		client.WithHTTPHeaders(defaultHeaders),
-		client.WithHTTPClient(httpClient),
-		client.WithAPIVersionNegotiation(),
-		client.WithHost(host))
+	dockerClient, err := docker.NewClient(host, tlsConfig)
 	if err != nil {
 		return nil, err
 	}
-
 	return &socketClient{dockerClient}, nil
 }
 
@@ -14,6 +14,7 @@ import (
 	"k8s.io/client-go/rest"
 
 	"github.com/influxdata/telegraf/config"
+	common_kubernetes "github.com/influxdata/telegraf/plugins/common/kubernetes"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 )
 
@@ -24,35 +25,7 @@ type client struct {
 }
 
 func newClient(baseURL, namespace, bearerTokenFile, bearerToken string, timeout time.Duration, tlsConfig tls.ClientConfig) (*client, error) {
-	var clientConfig *rest.Config
-	var err error
-
-	if baseURL == "" {
-		clientConfig, err = rest.InClusterConfig()
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		clientConfig = &rest.Config{
-			TLSClientConfig: rest.TLSClientConfig{
-				ServerName: tlsConfig.ServerName,
-				Insecure:   tlsConfig.InsecureSkipVerify,
-				CAFile:     tlsConfig.TLSCA,
-				CertFile:   tlsConfig.TLSCert,
-				KeyFile:    tlsConfig.TLSKey,
-			},
-			Host:          baseURL,
-			ContentConfig: rest.ContentConfig{},
-		}
-
-		if bearerTokenFile != "" {
-			clientConfig.BearerTokenFile = bearerTokenFile
-		} else if bearerToken != "" {
-			clientConfig.BearerToken = bearerToken
-		}
-	}
-
-	c, err := kubernetes.NewForConfig(clientConfig)
+	c, err := common_kubernetes.NewClientset(baseURL, bearerTokenFile, bearerToken, tlsConfig)
 	if err != nil {
 		return nil, err
 	}
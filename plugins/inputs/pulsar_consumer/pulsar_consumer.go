@@ -0,0 +1,286 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar_consumer
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+var once sync.Once
+
+const defaultMaxUndeliveredMessages = 1000
+
+type PulsarConsumer struct {
+	MaxUndeliveredMessages int             `toml:"max_undelivered_messages"`
+	MaxMessageLen          int             `toml:"max_message_len"`
+	TopicTag               string          `toml:"topic_tag"`
+	PropertiesAsTags       []string        `toml:"properties_as_tags"`
+	PropertyAsMetricName   string          `toml:"property_as_metric_name"`
+	TimestampSource        string          `toml:"timestamp_source"`
+	Log                    telegraf.Logger `toml:"-"`
+	common.ReadConfig
+
+	clientOptions   pulsar.ClientOptions
+	consumerOptions pulsar.ConsumerOptions
+	clientFunc      func(pulsar.ClientOptions) (pulsar.Client, error)
+	client          pulsar.Client
+	consumer        pulsar.Consumer
+
+	parser telegraf.Parser
+	acc    telegraf.TrackingAccumulator
+	sem    chan struct{}
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	undelivered map[telegraf.TrackingID]pulsar.Message
+}
+
+func (*PulsarConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PulsarConsumer) SetParser(parser telegraf.Parser) {
+	p.parser = parser
+}
+
+func (p *PulsarConsumer) Init() error {
+	if p.MaxUndeliveredMessages <= 0 {
+		return errors.New("'max_undelivered_messages' must be positive")
+	}
+
+	switch p.TimestampSource {
+	case "":
+		p.TimestampSource = "metric"
+	case "metric", "event", "publish":
+	default:
+		return fmt.Errorf("invalid timestamp source %q", p.TimestampSource)
+	}
+
+	options, err := p.ClientOptions(p.Log)
+	if err != nil {
+		return err
+	}
+	p.clientOptions = options
+
+	consumerOptions, err := p.ConsumerOptions()
+	if err != nil {
+		return err
+	}
+	p.consumerOptions = consumerOptions
+
+	return nil
+}
+
+func (p *PulsarConsumer) Start(acc telegraf.Accumulator) error {
+	options := p.clientOptions
+	if err := p.Authenticate(&options); err != nil {
+		return err
+	}
+	client, err := p.clientFunc(options)
+	if err != nil {
+		return &internal.StartupError{Err: err, Retry: true}
+	}
+
+	consumer, err := client.Subscribe(p.consumerOptions)
+	if err != nil {
+		client.Close()
+		return &internal.StartupError{
+			Err:   fmt.Errorf("subscribing failed: %w", err),
+			Retry: true,
+		}
+	}
+	p.client = client
+	p.consumer = consumer
+
+	p.acc = acc.WithTracking(p.MaxUndeliveredMessages)
+	p.sem = make(chan struct{}, p.MaxUndeliveredMessages)
+	p.undelivered = make(map[telegraf.TrackingID]pulsar.Message, p.MaxUndeliveredMessages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		p.receiver(ctx)
+	}()
+	go func() {
+		defer p.wg.Done()
+		p.deliveryHandler(ctx)
+	}()
+
+	return nil
+}
+
+func (*PulsarConsumer) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+func (p *PulsarConsumer) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+
+	// Messages not yet delivered will be redelivered by the broker after
+	// closing the consumer
+	if p.consumer != nil {
+		p.consumer.Close()
+		p.consumer = nil
+	}
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+}
+
+// receiver reads the messages from the subscription, limiting the number of
+// messages in flight to 'max_undelivered_messages'
+func (p *PulsarConsumer) receiver(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p.sem <- struct{}{}:
+		}
+
+		msg, err := p.consumer.Receive(ctx)
+		if err != nil {
+			<-p.sem
+			if ctx.Err() != nil {
+				return
+			}
+			p.acc.AddError(fmt.Errorf("receiving message failed: %w", err))
+			continue
+		}
+
+		if err := p.handle(msg); err != nil {
+			<-p.sem
+			p.acc.AddError(fmt.Errorf("topic %q: %w", msg.Topic(), err))
+			// Acknowledge invalid messages as they would be redelivered
+			// infinitely otherwise
+			p.ack(msg)
+		}
+	}
+}
+
+func (p *PulsarConsumer) handle(msg pulsar.Message) error {
+	payload := msg.Payload()
+	if p.MaxMessageLen != 0 && len(payload) > p.MaxMessageLen {
+		return fmt.Errorf("message longer than max_message_len (%d > %d)", len(payload), p.MaxMessageLen)
+	}
+
+	metrics, err := p.parser.Parse(payload)
+	if err != nil {
+		return err
+	}
+	if len(metrics) == 0 {
+		once.Do(func() {
+			p.Log.Debug(internal.NoMetricsCreatedMsg)
+		})
+	}
+
+	properties := msg.Properties()
+	for _, m := range metrics {
+		if p.TopicTag != "" {
+			m.AddTag(p.TopicTag, msg.Topic())
+		}
+		for _, key := range p.PropertiesAsTags {
+			if value, found := properties[key]; found {
+				m.AddTag(key, value)
+			}
+		}
+		if p.PropertyAsMetricName != "" {
+			if name, found := properties[p.PropertyAsMetricName]; found && name != "" {
+				m.SetName(name)
+			}
+		}
+		switch p.TimestampSource {
+		case "event":
+			if t := msg.EventTime(); !t.IsZero() {
+				m.SetTime(t)
+			}
+		case "publish":
+			m.SetTime(msg.PublishTime())
+		}
+	}
+
+	p.mu.Lock()
+	id := p.acc.AddTrackingMetricGroup(metrics)
+	p.undelivered[id] = msg
+	p.mu.Unlock()
+
+	return nil
+}
+
+// deliveryHandler acknowledges messages once all of their metrics have been
+// delivered by the outputs and negatively acknowledges rejected messages for
+// redelivery
+func (p *PulsarConsumer) deliveryHandler(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case track := <-p.acc.Delivered():
+			p.onDelivery(track)
+		}
+	}
+}
+
+func (p *PulsarConsumer) onDelivery(track telegraf.DeliveryInfo) {
+	p.mu.Lock()
+	msg, found := p.undelivered[track.ID()]
+	delete(p.undelivered, track.ID())
+	p.mu.Unlock()
+	if !found {
+		p.Log.Errorf("Could not mark message delivered: %d", track.ID())
+		return
+	}
+
+	if track.Delivered() {
+		p.ack(msg)
+	} else {
+		p.consumer.Nack(msg)
+	}
+	<-p.sem
+}
+
+func (p *PulsarConsumer) ack(msg pulsar.Message) {
+	if err := p.consumer.Ack(msg); err != nil {
+		p.Log.Errorf("Acknowledging message failed: %v", err)
+	}
+}
+
+func init() {
+	inputs.Add("pulsar_consumer", func() telegraf.Input {
+		return &PulsarConsumer{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+			TopicTag:               "topic",
+			ReadConfig: common.ReadConfig{
+				Config: common.Config{
+					ServiceURL:        "pulsar://localhost:6650",
+					ConnectionTimeout: config.Duration(10 * time.Second),
+					OperationTimeout:  config.Duration(30 * time.Second),
+				},
+				SubscriptionName: "telegraf_consumers",
+			},
+			clientFunc: pulsar.NewClient,
+		}
+	})
+}
@@ -0,0 +1,285 @@
+package pulsar_consumer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	common "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type mockClient struct {
+	pulsar.Client
+	consumer *mockConsumer
+}
+
+func (c *mockClient) Subscribe(pulsar.ConsumerOptions) (pulsar.Consumer, error) {
+	return c.consumer, nil
+}
+
+func (*mockClient) Close() {}
+
+type mockConsumer struct {
+	pulsar.Consumer
+	messages chan pulsar.Message
+	acked    []string
+	nacked   []string
+	sync.Mutex
+}
+
+func (c *mockConsumer) Receive(ctx context.Context) (pulsar.Message, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg := <-c.messages:
+		return msg, nil
+	}
+}
+
+func (c *mockConsumer) Ack(msg pulsar.Message) error {
+	c.Lock()
+	defer c.Unlock()
+	c.acked = append(c.acked, string(msg.Payload()))
+	return nil
+}
+
+func (c *mockConsumer) Nack(msg pulsar.Message) {
+	c.Lock()
+	defer c.Unlock()
+	c.nacked = append(c.nacked, string(msg.Payload()))
+}
+
+func (*mockConsumer) Close() {}
+
+type mockMessage struct {
+	pulsar.Message
+	topic      string
+	payload    string
+	properties map[string]string
+	eventTime  time.Time
+}
+
+func (m *mockMessage) Topic() string                 { return m.topic }
+func (m *mockMessage) Payload() []byte               { return []byte(m.payload) }
+func (m *mockMessage) Properties() map[string]string { return m.properties }
+func (m *mockMessage) EventTime() time.Time          { return m.eventTime }
+
+func newPlugin(consumer *mockConsumer) *PulsarConsumer {
+	return &PulsarConsumer{
+		MaxUndeliveredMessages: 10,
+		TopicTag:               "topic",
+		ReadConfig: common.ReadConfig{
+			Config:           common.Config{ServiceURL: "pulsar://localhost:6650"},
+			Topics:           []string{"telegraf"},
+			SubscriptionName: "telegraf",
+		},
+		Log: &testutil.Logger{},
+		clientFunc: func(pulsar.ClientOptions) (pulsar.Client, error) {
+			return &mockClient{consumer: consumer}, nil
+		},
+	}
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   common.ReadConfig
+		expected string
+	}{
+		{
+			name:     "no topics",
+			config:   common.ReadConfig{SubscriptionName: "telegraf"},
+			expected: "either 'topics' or 'topics_pattern' must be specified",
+		},
+		{
+			name:     "topics and pattern",
+			config:   common.ReadConfig{Topics: []string{"a"}, TopicsPattern: "b.*", SubscriptionName: "telegraf"},
+			expected: "'topics' and 'topics_pattern' cannot be used together",
+		},
+		{
+			name:     "invalid subscription type",
+			config:   common.ReadConfig{Topics: []string{"a"}, SubscriptionName: "telegraf", SubscriptionType: "broadcast"},
+			expected: `invalid subscription type "broadcast"`,
+		},
+		{
+			name:     "invalid initial position",
+			config:   common.ReadConfig{Topics: []string{"a"}, SubscriptionName: "telegraf", InitialPosition: "middle"},
+			expected: `invalid initial position "middle"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.ServiceURL = "pulsar://localhost:6650"
+			plugin := &PulsarConsumer{MaxUndeliveredMessages: 10, ReadConfig: tt.config}
+			require.ErrorContains(t, plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestTracking(t *testing.T) {
+	consumer := &mockConsumer{messages: make(chan pulsar.Message, 10)}
+	plugin := newPlugin(consumer)
+	plugin.PropertiesAsTags = []string{"region"}
+	plugin.PropertyAsMetricName = "name"
+	plugin.TimestampSource = "event"
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	consumer.messages <- &mockMessage{
+		topic:      "persistent://public/default/telegraf",
+		payload:    "cpu value=1 1000000000\n",
+		properties: map[string]string{"region": "eu", "name": "processor"},
+		eventTime:  time.Unix(5, 0),
+	}
+	consumer.messages <- &mockMessage{
+		topic:   "persistent://public/default/telegraf",
+		payload: "mem value=2 2000000000\n",
+	}
+	consumer.messages <- &mockMessage{
+		topic:   "persistent://public/default/telegraf",
+		payload: "invalid",
+	}
+	acc.Wait(2)
+	acc.WaitError(1)
+
+	expected := []telegraf.Metric{
+		metric.New("processor",
+			map[string]string{"topic": "persistent://public/default/telegraf", "region": "eu"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(5, 0),
+		),
+		metric.New("mem",
+			map[string]string{"topic": "persistent://public/default/telegraf"},
+			map[string]interface{}{"value": 2.0},
+			time.Unix(2, 0),
+		),
+	}
+	actual := acc.GetTelegrafMetrics()
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	// Invalid messages are acknowledged immediately, all others after the
+	// metrics are delivered or dropped
+	require.Eventually(t, func() bool {
+		consumer.Lock()
+		defer consumer.Unlock()
+		return len(consumer.acked) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	actual[0].Accept()
+	actual[1].Reject()
+	require.Eventually(t, func() bool {
+		consumer.Lock()
+		defer consumer.Unlock()
+		return len(consumer.acked) == 2 && len(consumer.nacked) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"invalid", "cpu value=1 1000000000\n"}, consumer.acked)
+	require.Equal(t, []string{"mem value=2 2000000000\n"}, consumer.nacked)
+}
+
+func TestMaxUndeliveredMessages(t *testing.T) {
+	consumer := &mockConsumer{messages: make(chan pulsar.Message, 10)}
+	plugin := newPlugin(consumer)
+	plugin.MaxUndeliveredMessages = 2
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	for i := range 3 {
+		consumer.messages <- &mockMessage{payload: fmt.Sprintf("cpu value=%d", i)}
+	}
+
+	// The third message must only be received after a delivery
+	acc.Wait(2)
+	require.Never(t, func() bool { return acc.NMetrics() > 2 }, 200*time.Millisecond, 10*time.Millisecond)
+	acc.GetTelegrafMetrics()[0].Accept()
+	acc.Wait(3)
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	container := testutil.Container{
+		Image:        "apachepulsar/pulsar:3.3.2",
+		Cmd:          []string{"bin/pulsar", "standalone"},
+		ExposedPorts: []string{"6650", "8080"},
+		WaitingFor: wait.ForAll(
+			wait.ForHTTP("/admin/v2/clusters").WithPort(nat.Port("8080")),
+			wait.ForListeningPort(nat.Port("6650")),
+		),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	serviceURL := fmt.Sprintf("pulsar://%s:%s", container.Address, container.Ports["6650"])
+	plugin := &PulsarConsumer{
+		MaxUndeliveredMessages: 10,
+		TopicTag:               "topic",
+		ReadConfig: common.ReadConfig{
+			Config: common.Config{
+				ServiceURL:        serviceURL,
+				ConnectionTimeout: config.Duration(10 * time.Second),
+				OperationTimeout:  config.Duration(30 * time.Second),
+			},
+			Topics:           []string{"persistent://public/default/telegraf"},
+			SubscriptionName: "telegraf",
+			InitialPosition:  "earliest",
+		},
+		Log:        &testutil.Logger{},
+		clientFunc: pulsar.NewClient,
+	}
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	client, err := pulsar.NewClient(pulsar.ClientOptions{URL: serviceURL, Logger: common.NewLogger(&testutil.Logger{})})
+	require.NoError(t, err)
+	defer client.Close()
+	producer, err := client.CreateProducer(pulsar.ProducerOptions{Topic: "persistent://public/default/telegraf"})
+	require.NoError(t, err)
+	defer producer.Close()
+	_, err = producer.Send(context.Background(), &pulsar.ProducerMessage{Payload: []byte("cpu value=42 1000000000\n")})
+	require.NoError(t, err)
+
+	acc.Wait(1)
+	expected := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"topic": "persistent://public/default/telegraf"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(1, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
@@ -0,0 +1,444 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package redis_streams_consumer
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common "github.com/influxdata/telegraf/plugins/common/redis"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+var once sync.Once
+
+const defaultMaxUndeliveredMessages = 1000
+
+// streamClient contains the subset of the Redis commands used by the plugin
+type streamClient interface {
+	Ping(ctx context.Context) *redis.StatusCmd
+	XGroupCreateMkStream(ctx context.Context, stream, group, start string) *redis.StatusCmd
+	XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd
+	XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) *redis.XAutoClaimCmd
+	XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd
+	Close() error
+}
+
+// entry identifies a stream entry
+type entry struct {
+	stream string
+	id     string
+}
+
+type RedisStreamsConsumer struct {
+	Streams                []string        `toml:"streams"`
+	ConsumerGroup          string          `toml:"consumer_group"`
+	ConsumerName           string          `toml:"consumer_name"`
+	CreateGroup            bool            `toml:"create_group"`
+	GroupStartID           string          `toml:"group_start_id"`
+	PayloadField           string          `toml:"payload_field"`
+	MetricNameField        string          `toml:"metric_name_field"`
+	StreamTag              string          `toml:"stream_tag"`
+	MaxUndeliveredMessages int             `toml:"max_undelivered_messages"`
+	BlockTimeout           config.Duration `toml:"block_timeout"`
+	ClaimMinIdle           config.Duration `toml:"claim_min_idle"`
+	ClaimInterval          config.Duration `toml:"claim_interval"`
+	Log                    telegraf.Logger `toml:"-"`
+	common.Config
+
+	clientFunc func() (streamClient, error)
+	client     streamClient
+
+	parser telegraf.Parser
+	acc    telegraf.TrackingAccumulator
+	sem    chan struct{}
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	undelivered map[telegraf.TrackingID]entry
+	inflight    map[entry]bool
+}
+
+func (*RedisStreamsConsumer) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RedisStreamsConsumer) SetParser(parser telegraf.Parser) {
+	r.parser = parser
+}
+
+func (r *RedisStreamsConsumer) Init() error {
+	if len(r.Streams) == 0 {
+		return errors.New("'streams' must not be empty")
+	}
+	if r.ConsumerGroup == "" {
+		return errors.New("'consumer_group' must not be empty")
+	}
+	if r.PayloadField == "" {
+		return errors.New("'payload_field' must not be empty")
+	}
+	if r.MaxUndeliveredMessages <= 0 {
+		return errors.New("'max_undelivered_messages' must be positive")
+	}
+	if r.BlockTimeout <= 0 {
+		return errors.New("'block_timeout' must be positive")
+	}
+	if r.ClaimMinIdle > 0 && r.ClaimInterval <= 0 {
+		return errors.New("'claim_interval' must be positive")
+	}
+
+	// Use the hostname to identify the consumer within the group by default
+	if r.ConsumerName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("determining consumer name failed: %w", err)
+		}
+		r.ConsumerName = hostname
+	}
+
+	if r.clientFunc == nil {
+		r.clientFunc = func() (streamClient, error) {
+			client, err := r.NewClient()
+			if err != nil {
+				return nil, err
+			}
+			return client, nil
+		}
+	}
+
+	return nil
+}
+
+func (r *RedisStreamsConsumer) Start(acc telegraf.Accumulator) error {
+	client, err := r.clientFunc()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Timeout))
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return &internal.StartupError{
+			Err:   fmt.Errorf("connecting to %q failed: %w", r.Address, err),
+			Retry: true,
+		}
+	}
+
+	if r.CreateGroup {
+		for _, stream := range r.Streams {
+			err := client.XGroupCreateMkStream(ctx, stream, r.ConsumerGroup, r.GroupStartID).Err()
+			if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+				client.Close()
+				return &internal.StartupError{
+					Err:   fmt.Errorf("creating group for stream %q failed: %w", stream, err),
+					Retry: true,
+				}
+			}
+		}
+	}
+	r.client = client
+
+	r.acc = acc.WithTracking(r.MaxUndeliveredMessages)
+	r.sem = make(chan struct{}, r.MaxUndeliveredMessages)
+	r.undelivered = make(map[telegraf.TrackingID]entry, r.MaxUndeliveredMessages)
+	r.inflight = make(map[entry]bool, r.MaxUndeliveredMessages)
+
+	rctx, rcancel := context.WithCancel(context.Background())
+	r.cancel = rcancel
+
+	r.wg.Add(2)
+	go func() {
+		defer r.wg.Done()
+		r.receiver(rctx)
+	}()
+	go func() {
+		defer r.wg.Done()
+		r.deliveryHandler(rctx)
+	}()
+
+	return nil
+}
+
+func (*RedisStreamsConsumer) Gather(telegraf.Accumulator) error {
+	return nil
+}
+
+func (r *RedisStreamsConsumer) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+
+	// Entries not yet acknowledged stay pending and are claimed again after
+	// being idle for 'claim_min_idle'
+	if r.client != nil {
+		if err := r.client.Close(); err != nil {
+			r.Log.Errorf("Closing connection failed: %v", err)
+		}
+		r.client = nil
+	}
+}
+
+// receiver reads new entries from the streams and periodically claims
+// entries pending for too long, limiting the number of entries in flight to
+// 'max_undelivered_messages'
+func (r *RedisStreamsConsumer) receiver(ctx context.Context) {
+	// Read new entries of all streams
+	streams := make([]string, 0, 2*len(r.Streams))
+	streams = append(streams, r.Streams...)
+	for range r.Streams {
+		streams = append(streams, ">")
+	}
+
+	var nextClaim time.Time
+	for ctx.Err() == nil {
+		if r.ClaimMinIdle > 0 && !time.Now().Before(nextClaim) {
+			r.claim(ctx)
+			nextClaim = time.Now().Add(time.Duration(r.ClaimInterval))
+		}
+
+		n := r.acquire(ctx)
+		if n == 0 {
+			return
+		}
+
+		result, err := r.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.ConsumerGroup,
+			Consumer: r.ConsumerName,
+			Streams:  streams,
+			Count:    int64(n),
+			Block:    time.Duration(r.BlockTimeout),
+		}).Result()
+		if err != nil {
+			r.release(n)
+			if ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, redis.Nil) {
+				r.acc.AddError(fmt.Errorf("reading streams failed: %w", err))
+			}
+			continue
+		}
+
+		for _, s := range result {
+			for _, msg := range s.Messages {
+				n--
+				r.process(s.Stream, msg)
+			}
+		}
+		r.release(n)
+	}
+}
+
+// claim takes over the entries of the consumer group being pending for longer
+// than 'claim_min_idle', e.g. because a consumer crashed or the metrics were
+// not delivered
+func (r *RedisStreamsConsumer) claim(ctx context.Context) {
+	for _, stream := range r.Streams {
+		start := "0-0"
+		for {
+			n := r.acquire(ctx)
+			if n == 0 {
+				return
+			}
+
+			messages, next, err := r.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   stream,
+				Group:    r.ConsumerGroup,
+				Consumer: r.ConsumerName,
+				MinIdle:  time.Duration(r.ClaimMinIdle),
+				Start:    start,
+				Count:    int64(n),
+			}).Result()
+			if err != nil {
+				r.release(n)
+				if ctx.Err() == nil {
+					r.acc.AddError(fmt.Errorf("claiming pending entries of stream %q failed: %w", stream, err))
+				}
+				break
+			}
+
+			for _, msg := range messages {
+				// Skip entries still being processed by this instance
+				r.mu.Lock()
+				busy := r.inflight[entry{stream, msg.ID}]
+				r.mu.Unlock()
+				if busy {
+					continue
+				}
+				n--
+				r.process(stream, msg)
+			}
+			r.release(n)
+
+			if next == "0-0" || next == "" {
+				break
+			}
+			start = next
+		}
+	}
+}
+
+// acquire blocks until at least one slot for an entry is available and takes
+// all other free slots, returning the number of slots taken or zero if the
+// context was cancelled
+func (r *RedisStreamsConsumer) acquire(ctx context.Context) int {
+	select {
+	case <-ctx.Done():
+		return 0
+	case r.sem <- struct{}{}:
+	}
+
+	n := 1
+	for n < cap(r.sem) {
+		select {
+		case r.sem <- struct{}{}:
+			n++
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+func (r *RedisStreamsConsumer) release(n int) {
+	for range n {
+		<-r.sem
+	}
+}
+
+// process parses the entry and adds the resulting metrics; the caller must
+// hold a slot for the entry
+func (r *RedisStreamsConsumer) process(stream string, msg redis.XMessage) {
+	e := entry{stream: stream, id: msg.ID}
+
+	metrics, err := r.parse(stream, msg)
+	if err != nil {
+		r.acc.AddError(fmt.Errorf("entry %q of stream %q: %w", msg.ID, stream, err))
+		// Acknowledge invalid entries as they would be claimed infinitely
+		// otherwise
+		r.ack(e)
+		r.release(1)
+		return
+	}
+
+	r.mu.Lock()
+	id := r.acc.AddTrackingMetricGroup(metrics)
+	r.undelivered[id] = e
+	r.inflight[e] = true
+	r.mu.Unlock()
+}
+
+func (r *RedisStreamsConsumer) parse(stream string, msg redis.XMessage) ([]telegraf.Metric, error) {
+	payload, found := msg.Values[r.PayloadField]
+	if !found {
+		return nil, fmt.Errorf("payload field %q not found", r.PayloadField)
+	}
+	data, ok := payload.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected payload type %T", payload)
+	}
+
+	metrics, err := r.parser.Parse([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) == 0 {
+		once.Do(func() {
+			r.Log.Debug(internal.NoMetricsCreatedMsg)
+		})
+	}
+
+	var name string
+	if r.MetricNameField != "" {
+		if v, found := msg.Values[r.MetricNameField]; found {
+			name, _ = v.(string)
+		}
+	}
+	for _, m := range metrics {
+		if r.StreamTag != "" {
+			m.AddTag(r.StreamTag, stream)
+		}
+		if name != "" {
+			m.SetName(name)
+		}
+	}
+
+	return metrics, nil
+}
+
+// deliveryHandler acknowledges entries once all of their metrics have been
+// delivered by the outputs. Entries of rejected metrics are left pending and
+// are claimed again after 'claim_min_idle'.
+func (r *RedisStreamsConsumer) deliveryHandler(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case track := <-r.acc.Delivered():
+			r.onDelivery(track)
+		}
+	}
+}
+
+func (r *RedisStreamsConsumer) onDelivery(track telegraf.DeliveryInfo) {
+	r.mu.Lock()
+	e, found := r.undelivered[track.ID()]
+	delete(r.undelivered, track.ID())
+	delete(r.inflight, e)
+	r.mu.Unlock()
+	if !found {
+		r.Log.Errorf("Could not mark entry delivered: %d", track.ID())
+		return
+	}
+
+	if track.Delivered() {
+		r.ack(e)
+	} else {
+		r.Log.Debugf("Metrics of entry %q of stream %q were not delivered", e.id, e.stream)
+	}
+	r.release(1)
+}
+
+func (r *RedisStreamsConsumer) ack(e entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Timeout))
+	defer cancel()
+	if err := r.client.XAck(ctx, e.stream, r.ConsumerGroup, e.id).Err(); err != nil {
+		r.Log.Errorf("Acknowledging entry %q of stream %q failed: %v", e.id, e.stream, err)
+	}
+}
+
+func init() {
+	inputs.Add("redis_streams_consumer", func() telegraf.Input {
+		return &RedisStreamsConsumer{
+			ConsumerGroup:          "telegraf",
+			CreateGroup:            true,
+			GroupStartID:           "$",
+			PayloadField:           "data",
+			StreamTag:              "stream",
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+			BlockTimeout:           config.Duration(time.Second),
+			ClaimMinIdle:           config.Duration(5 * time.Minute),
+			ClaimInterval:          config.Duration(time.Minute),
+			Config: common.Config{
+				Address: "127.0.0.1:6379",
+				Timeout: config.Duration(10 * time.Second),
+			},
+		}
+	})
+}
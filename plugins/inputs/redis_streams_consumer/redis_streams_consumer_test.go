@@ -0,0 +1,375 @@
+package redis_streams_consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	common "github.com/influxdata/telegraf/plugins/common/redis"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+type mockClient struct {
+	entries chan redis.XMessage
+	pending []redis.XMessage
+	acked   []string
+	claims  int
+	sync.Mutex
+}
+
+func (*mockClient) Ping(context.Context) *redis.StatusCmd {
+	return redis.NewStatusResult("PONG", nil)
+}
+
+func (*mockClient) XGroupCreateMkStream(context.Context, string, string, string) *redis.StatusCmd {
+	return redis.NewStatusResult("", errors.New("BUSYGROUP Consumer Group name already exists"))
+}
+
+func (c *mockClient) XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd {
+	select {
+	case <-ctx.Done():
+		return redis.NewXStreamSliceCmdResult(nil, ctx.Err())
+	case <-time.After(a.Block):
+		return redis.NewXStreamSliceCmdResult(nil, redis.Nil)
+	case msg := <-c.entries:
+		messages := []redis.XMessage{msg}
+		for int64(len(messages)) < a.Count {
+			select {
+			case msg := <-c.entries:
+				messages = append(messages, msg)
+				continue
+			default:
+			}
+			break
+		}
+		return redis.NewXStreamSliceCmdResult([]redis.XStream{{Stream: "telegraf", Messages: messages}}, nil)
+	}
+}
+
+func (c *mockClient) XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) *redis.XAutoClaimCmd {
+	c.Lock()
+	defer c.Unlock()
+	c.claims++
+
+	n := min(int(a.Count), len(c.pending))
+	next := "0-0"
+	if n < len(c.pending) {
+		next = c.pending[n].ID
+	}
+	cmd := redis.NewXAutoClaimCmd(ctx)
+	cmd.SetVal(c.pending[:n], next)
+	c.pending = c.pending[n:]
+	return cmd
+}
+
+func (c *mockClient) XAck(_ context.Context, _, _ string, ids ...string) *redis.IntCmd {
+	c.Lock()
+	defer c.Unlock()
+	c.acked = append(c.acked, ids...)
+	return redis.NewIntResult(int64(len(ids)), nil)
+}
+
+func (*mockClient) Close() error {
+	return nil
+}
+
+func (c *mockClient) ackedIDs() []string {
+	c.Lock()
+	defer c.Unlock()
+	return append([]string(nil), c.acked...)
+}
+
+func newPlugin(client *mockClient) *RedisStreamsConsumer {
+	return &RedisStreamsConsumer{
+		Streams:                []string{"telegraf"},
+		ConsumerGroup:          "telegraf",
+		ConsumerName:           "test",
+		CreateGroup:            true,
+		GroupStartID:           "$",
+		PayloadField:           "data",
+		StreamTag:              "stream",
+		MaxUndeliveredMessages: 10,
+		BlockTimeout:           config.Duration(50 * time.Millisecond),
+		Config: common.Config{
+			Address: "127.0.0.1:6379",
+			Timeout: config.Duration(time.Second),
+		},
+		Log: &testutil.Logger{},
+		clientFunc: func() (streamClient, error) {
+			return client, nil
+		},
+	}
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *RedisStreamsConsumer
+		expected string
+	}{
+		{
+			name:     "no streams",
+			plugin:   &RedisStreamsConsumer{},
+			expected: "'streams' must not be empty",
+		},
+		{
+			name:     "no group",
+			plugin:   &RedisStreamsConsumer{Streams: []string{"a"}},
+			expected: "'consumer_group' must not be empty",
+		},
+		{
+			name:     "no payload field",
+			plugin:   &RedisStreamsConsumer{Streams: []string{"a"}, ConsumerGroup: "g"},
+			expected: "'payload_field' must not be empty",
+		},
+		{
+			name: "invalid max undelivered",
+			plugin: &RedisStreamsConsumer{
+				Streams:       []string{"a"},
+				ConsumerGroup: "g",
+				PayloadField:  "data",
+			},
+			expected: "'max_undelivered_messages' must be positive",
+		},
+		{
+			name: "invalid block timeout",
+			plugin: &RedisStreamsConsumer{
+				Streams:                []string{"a"},
+				ConsumerGroup:          "g",
+				PayloadField:           "data",
+				MaxUndeliveredMessages: 10,
+			},
+			expected: "'block_timeout' must be positive",
+		},
+		{
+			name: "invalid claim interval",
+			plugin: &RedisStreamsConsumer{
+				Streams:                []string{"a"},
+				ConsumerGroup:          "g",
+				PayloadField:           "data",
+				MaxUndeliveredMessages: 10,
+				BlockTimeout:           config.Duration(time.Second),
+				ClaimMinIdle:           config.Duration(time.Minute),
+			},
+			expected: "'claim_interval' must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestTracking(t *testing.T) {
+	client := &mockClient{entries: make(chan redis.XMessage, 10)}
+	plugin := newPlugin(client)
+	plugin.MetricNameField = "name"
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	client.entries <- redis.XMessage{
+		ID:     "1-0",
+		Values: map[string]interface{}{"data": "cpu value=1 1000000000\n", "name": "processor"},
+	}
+	client.entries <- redis.XMessage{
+		ID:     "2-0",
+		Values: map[string]interface{}{"data": "mem value=2 2000000000\n"},
+	}
+	client.entries <- redis.XMessage{
+		ID:     "3-0",
+		Values: map[string]interface{}{"data": "invalid"},
+	}
+	client.entries <- redis.XMessage{
+		ID:     "4-0",
+		Values: map[string]interface{}{"other": "cpu value=3"},
+	}
+	acc.Wait(2)
+	acc.WaitError(2)
+
+	expected := []telegraf.Metric{
+		metric.New("processor",
+			map[string]string{"stream": "telegraf"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 0),
+		),
+		metric.New("mem",
+			map[string]string{"stream": "telegraf"},
+			map[string]interface{}{"value": 2.0},
+			time.Unix(2, 0),
+		),
+	}
+	actual := acc.GetTelegrafMetrics()
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	// Invalid entries are acknowledged immediately, all others after the
+	// metrics are delivered; rejected entries stay pending
+	require.Eventually(t, func() bool {
+		return len(client.ackedIDs()) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	actual[0].Accept()
+	actual[1].Reject()
+	require.Eventually(t, func() bool {
+		return len(client.ackedIDs()) == 3
+	}, time.Second, 10*time.Millisecond)
+	require.Never(t, func() bool {
+		return len(client.ackedIDs()) > 3
+	}, 100*time.Millisecond, 10*time.Millisecond)
+	require.Equal(t, []string{"3-0", "4-0", "1-0"}, client.ackedIDs())
+}
+
+func TestClaim(t *testing.T) {
+	client := &mockClient{
+		entries: make(chan redis.XMessage, 10),
+		pending: []redis.XMessage{
+			{ID: "1-0", Values: map[string]interface{}{"data": "cpu value=1 1000000000\n"}},
+			{ID: "2-0", Values: map[string]interface{}{"data": "cpu value=2 2000000000\n"}},
+			{ID: "3-0", Values: map[string]interface{}{"data": "cpu value=3 3000000000\n"}},
+		},
+	}
+	plugin := newPlugin(client)
+	plugin.MaxUndeliveredMessages = 2
+	plugin.ClaimMinIdle = config.Duration(time.Minute)
+	plugin.ClaimInterval = config.Duration(time.Hour)
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// The claimed entries are limited by the number of undelivered messages
+	acc.Wait(2)
+	require.Never(t, func() bool { return acc.NMetrics() > 2 }, 200*time.Millisecond, 10*time.Millisecond)
+	for _, m := range acc.GetTelegrafMetrics() {
+		m.Accept()
+	}
+	acc.Wait(3)
+	acc.GetTelegrafMetrics()[2].Accept()
+
+	require.Eventually(t, func() bool {
+		return len(client.ackedIDs()) == 3
+	}, time.Second, 10*time.Millisecond)
+	require.ElementsMatch(t, []string{"1-0", "2-0", "3-0"}, client.ackedIDs())
+
+	client.Lock()
+	defer client.Unlock()
+	require.Equal(t, 2, client.claims)
+}
+
+func TestMaxUndeliveredMessages(t *testing.T) {
+	client := &mockClient{entries: make(chan redis.XMessage, 10)}
+	plugin := newPlugin(client)
+	plugin.MaxUndeliveredMessages = 2
+
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	for i := range 3 {
+		client.entries <- redis.XMessage{
+			ID:     fmt.Sprintf("%d-0", i),
+			Values: map[string]interface{}{"data": fmt.Sprintf("cpu value=%d", i)},
+		}
+	}
+
+	// The third entry must only be read after a delivery
+	acc.Wait(2)
+	require.Never(t, func() bool { return acc.NMetrics() > 2 }, 200*time.Millisecond, 10*time.Millisecond)
+	acc.GetTelegrafMetrics()[0].Accept()
+	acc.Wait(3)
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	servicePort := "6379"
+	container := testutil.Container{
+		Image:        "redis:7-alpine",
+		ExposedPorts: []string{servicePort},
+		WaitingFor:   wait.ForListeningPort(nat.Port(servicePort)),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	address := fmt.Sprintf("%s:%s", container.Address, container.Ports[servicePort])
+	plugin := &RedisStreamsConsumer{
+		Streams:                []string{"telegraf"},
+		ConsumerGroup:          "telegraf",
+		CreateGroup:            true,
+		GroupStartID:           "0",
+		PayloadField:           "data",
+		StreamTag:              "stream",
+		MaxUndeliveredMessages: 10,
+		BlockTimeout:           config.Duration(100 * time.Millisecond),
+		Config: common.Config{
+			Address: address,
+			Timeout: config.Duration(10 * time.Second),
+		},
+		Log: &testutil.Logger{},
+	}
+	parser := &influx.Parser{}
+	require.NoError(t, parser.Init())
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	client := redis.NewClient(&redis.Options{Addr: address})
+	defer client.Close()
+	err := client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: "telegraf",
+		Values: []interface{}{"data", "cpu value=42 1000000000\n"},
+	}).Err()
+	require.NoError(t, err)
+
+	acc.Wait(1)
+	expected := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"stream": "telegraf"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(1, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	acc.GetTelegrafMetrics()[0].Accept()
+
+	// The entry must not be pending anymore after acknowledging it
+	require.Eventually(t, func() bool {
+		pending, err := client.XPending(context.Background(), "telegraf", "telegraf").Result()
+		return err == nil && pending.Count == 0
+	}, 5*time.Second, 100*time.Millisecond)
+}
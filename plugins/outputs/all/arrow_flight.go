@@ -0,0 +1,5 @@
+//go:build !custom || outputs || outputs.arrow_flight
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/outputs/arrow_flight" // register plugin
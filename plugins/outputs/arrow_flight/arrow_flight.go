@@ -0,0 +1,180 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package arrow_flight
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/flight"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/arrow"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type ArrowFlight struct {
+	Address         string            `toml:"address"`
+	Path            []string          `toml:"path"`
+	Token           config.Secret     `toml:"token"`
+	Headers         map[string]string `toml:"headers"`
+	Timeout         config.Duration   `toml:"timeout"`
+	Compression     string            `toml:"compression"`
+	TimestampColumn string            `toml:"timestamp_column"`
+	Log             telegraf.Logger   `toml:"-"`
+	tls.ClientConfig
+
+	serializer *serializer.Serializer
+	conn       *grpc.ClientConn
+	client     flight.Client
+}
+
+func (*ArrowFlight) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *ArrowFlight) Init() error {
+	if a.Address == "" {
+		return errors.New("'address' must not be empty")
+	}
+
+	a.serializer = &serializer.Serializer{
+		TimestampColumn: a.TimestampColumn,
+		Compression:     a.Compression,
+		Log:             a.Log,
+	}
+	return a.serializer.Init()
+}
+
+func (a *ArrowFlight) Connect() error {
+	tlsConfig, err := a.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(a.Address,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUserAgent(internal.ProductToken()),
+	)
+	if err != nil {
+		return fmt.Errorf("creating client failed: %w", err)
+	}
+	a.conn = conn
+	a.client = flight.NewClientFromConn(conn, nil)
+
+	return nil
+}
+
+func (a *ArrowFlight) Close() error {
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.conn = nil
+	return err
+}
+
+func (a *ArrowFlight) Write(metrics []telegraf.Metric) error {
+	records := a.serializer.Records(metrics)
+	defer func() {
+		for _, record := range records {
+			record.Release()
+		}
+	}()
+
+	ctx, err := a.context()
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := a.put(ctx, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create the request context containing the headers and the token
+func (a *ArrowFlight) context() (context.Context, error) {
+	md := metadata.New(a.Headers)
+	if !a.Token.Empty() {
+		token, err := a.Token.Get()
+		if err != nil {
+			return nil, fmt.Errorf("getting token failed: %w", err)
+		}
+		md.Set("authorization", "Bearer "+token.String())
+		token.Destroy()
+	}
+	return metadata.NewOutgoingContext(context.Background(), md), nil
+}
+
+// Send the record using a separate DoPut call as a stream can only carry a
+// single schema. The descriptor path is the configured path followed by the
+// measurement name.
+func (a *ArrowFlight) put(parent context.Context, record arrow.Record) error {
+	md := record.Schema().Metadata()
+	name := md.Values()[md.FindKey(serializer.MeasurementKey)]
+
+	ctx, cancel := context.WithTimeout(parent, time.Duration(a.Timeout))
+	defer cancel()
+
+	stream, err := a.client.DoPut(ctx)
+	if err != nil {
+		return fmt.Errorf("starting upload for %q failed: %w", name, err)
+	}
+
+	options := append([]ipc.Option{ipc.WithSchema(record.Schema())}, a.serializer.IPCOptions()...)
+	writer := flight.NewRecordWriter(stream, options...)
+	path := append(append(make([]string, 0, len(a.Path)+1), a.Path...), name)
+	writer.SetFlightDescriptor(&flight.FlightDescriptor{
+		Type: flight.DescriptorPATH,
+		Path: path,
+	})
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("uploading %q failed: %w", name, err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("finishing upload of %q failed: %w", name, err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		return fmt.Errorf("finishing upload of %q failed: %w", name, err)
+	}
+
+	// Wait for the server to acknowledge the upload
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("uploading %q failed: %w", name, err)
+		}
+	}
+}
+
+func init() {
+	outputs.Add("arrow_flight", func() telegraf.Output {
+		return &ArrowFlight{
+			Timeout: config.Duration(5 * time.Second),
+		}
+	})
+}
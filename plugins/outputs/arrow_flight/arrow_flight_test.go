@@ -0,0 +1,137 @@
+package arrow_flight
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow/flight"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// upload is a record batch received by the server
+type upload struct {
+	path          string
+	authorization string
+	rows          int64
+	columns       []string
+}
+
+type server struct {
+	flight.BaseFlightServer
+	uploads []upload
+	reject  bool
+	sync.Mutex
+}
+
+func (s *server) DoPut(stream flight.FlightService_DoPutServer) error {
+	if s.reject {
+		return status.Error(codes.PermissionDenied, "not allowed")
+	}
+
+	reader, err := flight.NewRecordReader(stream)
+	if err != nil {
+		return err
+	}
+	defer reader.Release()
+
+	u := upload{path: strings.Join(reader.LatestFlightDescriptor().Path, "/")}
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		u.authorization = strings.Join(md.Get("authorization"), ",")
+	}
+	for _, f := range reader.Schema().Fields() {
+		u.columns = append(u.columns, f.Name)
+	}
+	for reader.Next() {
+		u.rows += reader.Record().NumRows()
+	}
+	if err := reader.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	s.Lock()
+	s.uploads = append(s.uploads, u)
+	s.Unlock()
+
+	return stream.Send(&flight.PutResult{})
+}
+
+func startServer(t *testing.T, srv *server) string {
+	s := flight.NewServerWithMiddleware(nil)
+	require.NoError(t, s.Init("127.0.0.1:0"))
+	s.RegisterFlightService(srv)
+	go s.Serve() //nolint:errcheck // ignore the error on shutdown
+	t.Cleanup(s.Shutdown)
+	return s.Addr().String()
+}
+
+func TestInitFail(t *testing.T) {
+	plugin := &ArrowFlight{}
+	require.ErrorContains(t, plugin.Init(), "'address' must not be empty")
+
+	plugin = &ArrowFlight{Address: "localhost:8815", Compression: "gzip"}
+	require.ErrorContains(t, plugin.Init(), `invalid compression "gzip"`)
+}
+
+func TestWrite(t *testing.T) {
+	srv := &server{}
+	address := startServer(t, srv)
+
+	for _, compression := range []string{"none", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			srv.uploads = nil
+
+			plugin := &ArrowFlight{
+				Address:     address,
+				Path:        []string{"telegraf"},
+				Token:       config.NewSecret([]byte("secret")),
+				Timeout:     config.Duration(5 * time.Second),
+				Compression: compression,
+				Log:         &testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			metrics := []telegraf.Metric{
+				metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 42.5}, time.Unix(0, 1)),
+				metric.New("mem", map[string]string{"host": "a"}, map[string]interface{}{"used": int64(10)}, time.Unix(0, 2)),
+				metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"usage": 10.0}, time.Unix(0, 3)),
+			}
+			require.NoError(t, plugin.Write(metrics))
+
+			expected := []upload{
+				{path: "telegraf/cpu", authorization: "Bearer secret", rows: 2, columns: []string{"time", "host", "usage"}},
+				{path: "telegraf/mem", authorization: "Bearer secret", rows: 1, columns: []string{"time", "host", "used"}},
+			}
+			require.Equal(t, expected, srv.uploads)
+		})
+	}
+}
+
+func TestWriteRejected(t *testing.T) {
+	address := startServer(t, &server{reject: true})
+
+	plugin := &ArrowFlight{
+		Address: address,
+		Timeout: config.Duration(5 * time.Second),
+		Log:     &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"usage": 42.5}, time.Unix(0, 0))
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), `uploading "cpu" failed`)
+}
@@ -0,0 +1,479 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package clickhouse
+
+import (
+	"context"
+	gosql "database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	common_http "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type ClickHouse struct {
+	Address            string          `toml:"address"`
+	Protocol           string          `toml:"protocol"`
+	Database           string          `toml:"database"`
+	Username           config.Secret   `toml:"username"`
+	Password           config.Secret   `toml:"password"`
+	TimestampColumn    string          `toml:"timestamp_column"`
+	CreateTables       bool            `toml:"create_tables"`
+	AddColumns         bool            `toml:"add_columns"`
+	TableEngine        string          `toml:"table_engine"`
+	PartitionBy        string          `toml:"partition_by"`
+	LowCardinalityTags []string        `toml:"low_cardinality_tags"`
+	Log                telegraf.Logger `toml:"-"`
+	common_http.HTTPClientConfig
+
+	lowCardinality filter.Filter
+	client         client
+	// Columns and their types per table
+	tables map[string]map[string]string
+}
+
+func (*ClickHouse) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *ClickHouse) Init() error {
+	switch c.Protocol {
+	case "":
+		c.Protocol = "native"
+	case "native", "http":
+	default:
+		return fmt.Errorf("unknown protocol %q", c.Protocol)
+	}
+	if c.Address == "" {
+		if c.Protocol == "native" {
+			c.Address = "tcp://127.0.0.1:9000"
+		} else {
+			c.Address = "http://127.0.0.1:8123"
+		}
+	}
+	if c.TimestampColumn == "" {
+		return errors.New("'timestamp_column' must not be empty")
+	}
+
+	f, err := filter.Compile(c.LowCardinalityTags)
+	if err != nil {
+		return fmt.Errorf("creating low-cardinality filter failed: %w", err)
+	}
+	c.lowCardinality = f
+
+	return nil
+}
+
+func (c *ClickHouse) Connect() error {
+	tlsCfg, err := c.HTTPClientConfig.ClientConfig.TLSConfig()
+	if err != nil {
+		return fmt.Errorf("creating TLS config failed: %w", err)
+	}
+
+	username, err := c.Username.Get()
+	if err != nil {
+		return fmt.Errorf("getting username failed: %w", err)
+	}
+	user := username.String()
+	username.Destroy()
+
+	password, err := c.Password.Get()
+	if err != nil {
+		return fmt.Errorf("getting password failed: %w", err)
+	}
+	passwd := password.String()
+	password.Destroy()
+
+	u, err := url.Parse(c.Address)
+	if err != nil {
+		return fmt.Errorf("parsing address failed: %w", err)
+	}
+
+	switch c.Protocol {
+	case "native":
+		params := u.Query()
+		params.Set("database", c.Database)
+		if user != "" {
+			params.Set("username", user)
+		}
+		if passwd != "" {
+			params.Set("password", passwd)
+		}
+		if tlsCfg != nil {
+			key := "telegraf-" + u.Host
+			if err := clickhouse.RegisterTLSConfig(key, tlsCfg); err != nil {
+				return fmt.Errorf("registering TLS config failed: %w", err)
+			}
+			params.Set("tls_config", key)
+			params.Set("skip_verify", fmt.Sprintf("%t", c.InsecureSkipVerify))
+		}
+		// The driver does not support low-cardinality columns so let the
+		// server convert them to their base type
+		if !params.Has("low_cardinality_allow_in_native_format") {
+			params.Set("low_cardinality_allow_in_native_format", "0")
+		}
+		timeout := time.Duration(c.Timeout).Seconds()
+		params.Set("timeout", fmt.Sprintf("%g", timeout))
+		params.Set("read_timeout", fmt.Sprintf("%g", timeout))
+		params.Set("write_timeout", fmt.Sprintf("%g", timeout))
+		u.RawQuery = params.Encode()
+
+		db, err := gosql.Open("clickhouse", u.String())
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout))
+		defer cancel()
+		if err := db.PingContext(ctx); err != nil {
+			db.Close()
+			return fmt.Errorf("connecting to %q failed: %w", c.Address, err)
+		}
+		c.client = &nativeClient{db: db}
+	case "http":
+		params := u.Query()
+		params.Set("database", c.Database)
+		u.RawQuery = params.Encode()
+
+		client, err := c.HTTPClientConfig.CreateClient(context.Background(), c.Log)
+		if err != nil {
+			return fmt.Errorf("creating HTTP client failed: %w", err)
+		}
+		c.client = &httpClient{
+			address:  u.String(),
+			username: user,
+			password: passwd,
+			client:   client,
+		}
+	}
+
+	c.tables = make(map[string]map[string]string)
+
+	return nil
+}
+
+func (c *ClickHouse) Close() error {
+	if c.client == nil {
+		return nil
+	}
+	return c.client.close()
+}
+
+func (c *ClickHouse) Write(metrics []telegraf.Metric) error {
+	// Group the metrics by table
+	batches := make(map[string][]telegraf.Metric)
+	for _, m := range metrics {
+		batches[m.Name()] = append(batches[m.Name()], m)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout))
+	defer cancel()
+
+	for table, batch := range batches {
+		if err := c.writeTable(ctx, table, batch); err != nil {
+			return fmt.Errorf("writing to table %q failed: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func (c *ClickHouse) writeTable(ctx context.Context, table string, metrics []telegraf.Metric) error {
+	// Collect the required columns, the type of a field is determined by its
+	// first occurrence
+	tags := make(map[string]bool)
+	fields := make(map[string]string)
+	for _, m := range metrics {
+		for _, tag := range m.TagList() {
+			if tag.Key != c.TimestampColumn {
+				tags[tag.Key] = true
+			}
+		}
+		for _, field := range m.FieldList() {
+			if _, found := fields[field.Key]; !found {
+				if datatype := fieldType(field.Value); datatype != "" {
+					fields[field.Key] = datatype
+				}
+			}
+		}
+	}
+
+	// Fields named like the timestamp column or a tag would be stored in the
+	// same column, so omit them as the tags are part of the series identity
+	for _, key := range sortedKeys(fields) {
+		switch {
+		case key == c.TimestampColumn:
+			c.Log.Warnf("Omitting field %q colliding with the timestamp column in table %q", key, table)
+		case tags[key]:
+			c.Log.Warnf("Omitting field %q colliding with a tag in table %q", key, table)
+		default:
+			continue
+		}
+		delete(fields, key)
+	}
+
+	columns, err := c.ensureTable(ctx, table, tags, fields)
+	if err != nil {
+		return err
+	}
+
+	// Determine the columns to insert, fields without column are omitted
+	names := []string{c.TimestampColumn}
+	index := make(map[string]int, len(columns))
+	tagKeys := make([]string, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		if _, found := columns[key]; !found {
+			continue
+		}
+		tagKeys = append(tagKeys, key)
+		index[key] = len(names)
+		names = append(names, key)
+	}
+	for _, key := range sortedKeys(fields) {
+		if _, found := columns[key]; !found {
+			c.Log.Debugf("Omitting field %q without column in table %q", key, table)
+			continue
+		}
+		index[key] = len(names)
+		names = append(names, key)
+	}
+
+	rows := make([][]interface{}, 0, len(metrics))
+metrics:
+	for _, m := range metrics {
+		row := make([]interface{}, len(names))
+		row[0] = m.Time()
+		for _, key := range tagKeys {
+			row[index[key]] = ""
+		}
+		// Metrics with tags without column are dropped as the tags are
+		// part of the series identity
+		for _, tag := range m.TagList() {
+			if tag.Key == c.TimestampColumn {
+				c.Log.Warnf("Dropping metric with tag %q colliding with the timestamp column in table %q", tag.Key, table)
+				continue metrics
+			}
+			i, found := index[tag.Key]
+			if !found {
+				c.Log.Warnf("Dropping metric with tag %q without column in table %q", tag.Key, table)
+				continue metrics
+			}
+			row[i] = tag.Value
+		}
+		for _, field := range m.FieldList() {
+			if _, found := fields[field.Key]; !found {
+				continue
+			}
+			i, found := index[field.Key]
+			if !found {
+				continue
+			}
+			v, ok := convert(field.Value, columns[field.Key])
+			if !ok {
+				c.Log.Warnf("Omitting field %q of type %T not matching column type %q", field.Key, field.Value, columns[field.Key])
+				continue
+			}
+			row[i] = v
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	return c.client.insert(ctx, c.Database, table, names, rows)
+}
+
+// Make sure the table exists and contains the required columns if adding
+// columns is enabled.
+func (c *ClickHouse) ensureTable(ctx context.Context, table string, tags map[string]bool, fields map[string]string) (map[string]string, error) {
+	columns, found := c.tables[table]
+	if !found || !c.hasColumns(columns, tags, fields) {
+		// Refresh the columns as the table might have been modified by
+		// another instance
+		var err error
+		if columns, err = c.client.columns(ctx, c.Database, table); err != nil {
+			return nil, fmt.Errorf("querying columns failed: %w", err)
+		}
+	}
+
+	if len(columns) == 0 {
+		if !c.CreateTables {
+			return nil, errors.New("table does not exist")
+		}
+		query, created := c.createTable(table, tags, fields)
+		if err := c.client.exec(ctx, query); err != nil {
+			return nil, fmt.Errorf("creating table failed: %w", err)
+		}
+		c.tables[table] = created
+		return created, nil
+	}
+
+	var additions []string
+	for _, key := range sortedKeys(tags) {
+		if _, found := columns[key]; found || !c.AddColumns {
+			continue
+		}
+		datatype := c.tagType(key)
+		additions = append(additions, "ADD COLUMN IF NOT EXISTS "+quoteIdent(key)+" "+datatype)
+		columns[key] = datatype
+	}
+	if c.AddColumns {
+		for _, key := range sortedKeys(fields) {
+			if _, found := columns[key]; found {
+				continue
+			}
+			datatype := "Nullable(" + fields[key] + ")"
+			additions = append(additions, "ADD COLUMN IF NOT EXISTS "+quoteIdent(key)+" "+datatype)
+			columns[key] = datatype
+		}
+	}
+	if len(additions) > 0 {
+		query := fmt.Sprintf("ALTER TABLE %s.%s %s", quoteIdent(c.Database), quoteIdent(table), strings.Join(additions, ", "))
+		if err := c.client.exec(ctx, query); err != nil {
+			delete(c.tables, table)
+			return nil, fmt.Errorf("adding columns failed: %w", err)
+		}
+	}
+	c.tables[table] = columns
+
+	return columns, nil
+}
+
+func (c *ClickHouse) hasColumns(columns map[string]string, tags map[string]bool, fields map[string]string) bool {
+	for key := range tags {
+		if _, found := columns[key]; !found {
+			return false
+		}
+	}
+	if !c.AddColumns {
+		return true
+	}
+	for key := range fields {
+		if _, found := columns[key]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Generate the statement for creating the table ordered by the tags and time
+func (c *ClickHouse) createTable(table string, tags map[string]bool, fields map[string]string) (string, map[string]string) {
+	columns := map[string]string{c.TimestampColumn: "DateTime64(9)"}
+	definitions := []string{quoteIdent(c.TimestampColumn) + " DateTime64(9)"}
+	order := make([]string, 0, len(tags)+1)
+	for _, key := range sortedKeys(tags) {
+		datatype := c.tagType(key)
+		columns[key] = datatype
+		definitions = append(definitions, quoteIdent(key)+" "+datatype)
+		order = append(order, quoteIdent(key))
+	}
+	order = append(order, quoteIdent(c.TimestampColumn))
+	for _, key := range sortedKeys(fields) {
+		datatype := "Nullable(" + fields[key] + ")"
+		columns[key] = datatype
+		definitions = append(definitions, quoteIdent(key)+" "+datatype)
+	}
+
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s (%s) ENGINE = %s",
+		quoteIdent(c.Database), quoteIdent(table), strings.Join(definitions, ", "), c.TableEngine)
+	if c.PartitionBy != "" {
+		query += " PARTITION BY " + c.PartitionBy
+	}
+	query += " ORDER BY (" + strings.Join(order, ", ") + ")"
+
+	return query, columns
+}
+
+func (c *ClickHouse) tagType(key string) string {
+	if c.lowCardinality != nil && c.lowCardinality.Match(key) {
+		return "LowCardinality(String)"
+	}
+	return "String"
+}
+
+func fieldType(value interface{}) string {
+	switch value.(type) {
+	case int64:
+		return "Int64"
+	case uint64:
+		return "UInt64"
+	case float64:
+		return "Float64"
+	case string:
+		return "String"
+	case bool:
+		return "UInt8"
+	}
+	return ""
+}
+
+// Convert the field value to the type of an existing column
+func convert(value interface{}, datatype string) (interface{}, bool) {
+	datatype = strings.TrimSuffix(strings.TrimPrefix(datatype, "Nullable("), ")")
+	datatype = strings.TrimSuffix(strings.TrimPrefix(datatype, "LowCardinality("), ")")
+	switch {
+	case strings.HasPrefix(datatype, "Int"):
+		v, err := internal.ToInt64(value)
+		return v, err == nil
+	case strings.HasPrefix(datatype, "UInt"):
+		v, err := internal.ToUint64(value)
+		return v, err == nil
+	case strings.HasPrefix(datatype, "Float"):
+		v, err := internal.ToFloat64(value)
+		return v, err == nil
+	case datatype == "String":
+		v, err := internal.ToString(value)
+		return v, err == nil
+	case datatype == "Bool":
+		v, err := internal.ToBool(value)
+		return v, err == nil
+	}
+	return value, true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Quote an identifier (database, table or column name)
+func quoteIdent(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+// Quote a string literal
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func init() {
+	outputs.Add("clickhouse", func() telegraf.Output {
+		return &ClickHouse{
+			Database:        "default",
+			TimestampColumn: "time",
+			CreateTables:    true,
+			AddColumns:      true,
+			TableEngine:     "MergeTree",
+			HTTPClientConfig: common_http.HTTPClientConfig{
+				Timeout: config.Duration(5 * time.Second),
+			},
+		}
+	})
+}
@@ -0,0 +1,368 @@
+package clickhouse
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	common_http "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// server is a stub of the ClickHouse HTTP interface
+type server struct {
+	tables  map[string]string
+	queries []string
+	rows    []map[string]interface{}
+	err     string
+	sync.Mutex
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	if r.Header.Get("X-ClickHouse-User") != "telegraf" || r.Header.Get("X-ClickHouse-Key") != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.URL.Query().Get("database") != "metrics" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query().Get("query")
+	switch {
+	case strings.HasPrefix(query, "SELECT name, type FROM system.columns"):
+		for table, columns := range s.tables {
+			if strings.Contains(query, "table = '"+table+"'") {
+				fmt.Fprint(w, columns)
+			}
+		}
+		return
+	case strings.HasPrefix(query, "INSERT"):
+		if s.err != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, s.err)
+			return
+		}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var row map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			s.rows = append(s.rows, row)
+		}
+	default:
+		if _, err := io.Copy(io.Discard, r.Body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	s.queries = append(s.queries, query)
+}
+
+func newPlugin(address string) *ClickHouse {
+	return &ClickHouse{
+		Address:         address,
+		Protocol:        "http",
+		Database:        "metrics",
+		Username:        config.NewSecret([]byte("telegraf")),
+		Password:        config.NewSecret([]byte("secret")),
+		TimestampColumn: "time",
+		CreateTables:    true,
+		AddColumns:      true,
+		TableEngine:     "MergeTree",
+		Log:             &testutil.Logger{},
+		HTTPClientConfig: common_http.HTTPClientConfig{
+			Timeout: config.Duration(5 * time.Second),
+		},
+	}
+}
+
+func TestInitFail(t *testing.T) {
+	plugin := &ClickHouse{Protocol: "grpc"}
+	require.ErrorContains(t, plugin.Init(), `unknown protocol "grpc"`)
+
+	plugin = &ClickHouse{}
+	require.ErrorContains(t, plugin.Init(), "'timestamp_column' must not be empty")
+}
+
+func TestCreateTable(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.PartitionBy = "toYYYYMM(time)"
+	plugin.LowCardinalityTags = []string{"host"}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 42.5, "count": int64(3), "ok": true},
+			time.Unix(1700000000, 123),
+		),
+		metric.New("cpu",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"usage": int64(7), "state": "up"},
+			time.Unix(1700000001, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	expected := []string{
+		"CREATE TABLE IF NOT EXISTS `metrics`.`cpu` (" +
+			"`time` DateTime64(9), `cpu` String, `host` LowCardinality(String), " +
+			"`count` Nullable(Int64), `ok` Nullable(UInt8), `state` Nullable(String), `usage` Nullable(Float64)" +
+			") ENGINE = MergeTree PARTITION BY toYYYYMM(time) ORDER BY (`cpu`, `host`, `time`)",
+		"INSERT INTO `metrics`.`cpu` (`time`, `cpu`, `host`, `count`, `ok`, `state`, `usage`) FORMAT JSONEachRow",
+	}
+	require.Equal(t, expected, srv.queries)
+
+	// Missing tags are inserted as empty strings and missing fields as NULL
+	// with the values converted to the column type
+	rows := []map[string]interface{}{
+		{
+			"time":  "2023-11-14 22:13:20.000000123",
+			"cpu":   "cpu0",
+			"host":  "a",
+			"count": 3.0,
+			"ok":    1.0,
+			"state": nil,
+			"usage": 42.5,
+		},
+		{
+			"time":  "2023-11-14 22:13:21.000000000",
+			"cpu":   "",
+			"host":  "b",
+			"count": nil,
+			"ok":    nil,
+			"state": "up",
+			"usage": 7.0,
+		},
+	}
+	require.Equal(t, rows, srv.rows)
+
+	// The table must not be checked again for known columns
+	srv.queries = nil
+	require.NoError(t, plugin.Write(metrics[:1]))
+	require.Equal(t, []string{"INSERT INTO `metrics`.`cpu` (`time`, `cpu`, `host`, `count`, `ok`, `usage`) FORMAT JSONEachRow"}, srv.queries)
+}
+
+func TestAddColumns(t *testing.T) {
+	srv := &server{
+		tables: map[string]string{"mem": "time\tDateTime64(9)\nhost\tString\nused\tNullable(Float64)\n"},
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	m := metric.New("mem",
+		map[string]string{"host": "a", "region": "eu"},
+		map[string]interface{}{"used": int64(10), "free": uint64(20)},
+		time.Unix(1700000000, 0),
+	)
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+
+	expected := []string{
+		"ALTER TABLE `metrics`.`mem` ADD COLUMN IF NOT EXISTS `region` String, ADD COLUMN IF NOT EXISTS `free` Nullable(UInt64)",
+		"INSERT INTO `metrics`.`mem` (`time`, `host`, `region`, `free`, `used`) FORMAT JSONEachRow",
+	}
+	require.Equal(t, expected, srv.queries)
+	require.Len(t, srv.rows, 1)
+	require.InDelta(t, 10.0, srv.rows[0]["used"], 0)
+}
+
+func TestNoSchemaUpdates(t *testing.T) {
+	srv := &server{
+		tables: map[string]string{"mem": "time\tDateTime64(9)\nhost\tString\nused\tNullable(Float64)\n"},
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	plugin.CreateTables = false
+	plugin.AddColumns = false
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Fields without column are omitted
+	m := metric.New("mem",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"used": 10.0, "free": 20.0},
+		time.Unix(1700000000, 0),
+	)
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.Equal(t, []string{"INSERT INTO `metrics`.`mem` (`time`, `host`, `used`) FORMAT JSONEachRow"}, srv.queries)
+
+	// Metrics with tags without column are dropped
+	srv.queries = nil
+	metrics := []telegraf.Metric{
+		metric.New("mem", map[string]string{"region": "eu"}, map[string]interface{}{"used": 10.0}, time.Unix(1700000000, 0)),
+		metric.New("mem", map[string]string{"host": "b"}, map[string]interface{}{"used": 5.0}, time.Unix(1700000000, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, []string{"INSERT INTO `metrics`.`mem` (`time`, `host`, `used`) FORMAT JSONEachRow"}, srv.queries)
+	require.Len(t, srv.rows, 2)
+	require.Equal(t, "b", srv.rows[1]["host"])
+
+	// Missing tables are an error
+
+	m = metric.New("disk", map[string]string{}, map[string]interface{}{"used": 10.0}, time.Unix(1700000000, 0))
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), `writing to table "disk" failed: table does not exist`)
+}
+
+func TestColumnCollisions(t *testing.T) {
+	srv := &server{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	logger := &testutil.CaptureLogger{}
+	plugin := newPlugin(ts.URL)
+	plugin.Log = logger
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Fields named like the timestamp column or a tag are omitted and metrics
+	// with a tag named like the timestamp column are dropped
+	metrics := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5, "time": int64(1), "host": "b"},
+			time.Unix(1700000000, 0),
+		),
+		metric.New("cpu",
+			map[string]string{"host": "c", "time": "now"},
+			map[string]interface{}{"usage": 1.0},
+			time.Unix(1700000000, 0),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	expected := []string{
+		"CREATE TABLE IF NOT EXISTS `metrics`.`cpu` (" +
+			"`time` DateTime64(9), `host` String, `usage` Nullable(Float64)" +
+			") ENGINE = MergeTree ORDER BY (`host`, `time`)",
+		"INSERT INTO `metrics`.`cpu` (`time`, `host`, `usage`) FORMAT JSONEachRow",
+	}
+	require.Equal(t, expected, srv.queries)
+	require.Equal(t, []map[string]interface{}{
+		{"time": "2023-11-14 22:13:20.000000000", "host": "a", "usage": 42.5},
+	}, srv.rows)
+
+	warnings := logger.Warnings()
+	require.Len(t, warnings, 3)
+	require.Contains(t, warnings[0], `Omitting field "host" colliding with a tag`)
+	require.Contains(t, warnings[1], `Omitting field "time" colliding with the timestamp column`)
+	require.Contains(t, warnings[2], `Dropping metric with tag "time" colliding with the timestamp column`)
+}
+
+func TestServerError(t *testing.T) {
+	srv := &server{
+		tables: map[string]string{"mem": "time\tDateTime64(9)\nused\tNullable(Float64)\n"},
+		err:    "Code: 241. DB::Exception: Memory limit exceeded",
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	plugin := newPlugin(ts.URL)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	m := metric.New("mem", map[string]string{}, map[string]interface{}{"used": 10.0}, time.Unix(1700000000, 0))
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), "Memory limit exceeded")
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	for _, protocol := range []string{"native", "http"} {
+		t.Run(protocol, func(t *testing.T) {
+			container := testutil.Container{
+				Image:        "clickhouse/clickhouse-server",
+				ExposedPorts: []string{"9000", "8123"},
+				Env: map[string]string{
+					"CLICKHOUSE_USER":     "telegraf",
+					"CLICKHOUSE_PASSWORD": "secret",
+					"CLICKHOUSE_DB":       "metrics",
+				},
+				WaitingFor: wait.ForAll(
+					wait.NewHTTPStrategy("/ping").WithPort(nat.Port("8123")),
+					wait.ForListeningPort(nat.Port("9000")),
+				),
+			}
+			require.NoError(t, container.Start(), "failed to start container")
+			defer container.Terminate()
+
+			address := fmt.Sprintf("tcp://%s:%s", container.Address, container.Ports["9000"])
+			if protocol == "http" {
+				address = fmt.Sprintf("http://%s:%s", container.Address, container.Ports["8123"])
+			}
+			plugin := newPlugin(address)
+			plugin.Protocol = protocol
+			plugin.LowCardinalityTags = []string{"host"}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			metrics := []telegraf.Metric{
+				metric.New("cpu",
+					map[string]string{"host": "a"},
+					map[string]interface{}{"usage": 42.5},
+					time.Unix(1700000000, 0),
+				),
+			}
+			require.NoError(t, plugin.Write(metrics))
+
+			// New fields must be added to the existing table
+			metrics = []telegraf.Metric{
+				metric.New("cpu",
+					map[string]string{"host": "b", "cpu": "cpu0"},
+					map[string]interface{}{"usage": 10.0, "count": int64(3)},
+					time.Unix(1700000001, 0),
+				),
+			}
+			require.NoError(t, plugin.Write(metrics))
+
+			columns, err := plugin.client.columns(context.Background(), "metrics", "cpu")
+			require.NoError(t, err)
+			require.Equal(t, map[string]string{
+				"time":  "DateTime64(9)",
+				"host":  "LowCardinality(String)",
+				"usage": "Nullable(Float64)",
+				"cpu":   "String",
+				"count": "Nullable(Int64)",
+			}, columns)
+		})
+	}
+}
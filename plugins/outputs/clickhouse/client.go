@@ -0,0 +1,201 @@
+package clickhouse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	gosql "database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// client abstracts the protocol used to communicate with the server
+type client interface {
+	// Execute a statement without result
+	exec(ctx context.Context, query string) error
+	// Return the columns and their types of a table or an empty map if the
+	// table does not exist
+	columns(ctx context.Context, database, table string) (map[string]string, error)
+	// Insert the rows into the table using a single batch
+	insert(ctx context.Context, database, table string, columns []string, rows [][]interface{}) error
+	close() error
+}
+
+const columnsQuery = "SELECT name, type FROM system.columns WHERE database = %s AND table = %s"
+
+// nativeClient uses the native TCP protocol
+type nativeClient struct {
+	db *gosql.DB
+}
+
+func (c *nativeClient) exec(ctx context.Context, query string) error {
+	_, err := c.db.ExecContext(ctx, query)
+	return err
+}
+
+func (c *nativeClient) columns(ctx context.Context, database, table string) (map[string]string, error) {
+	rows, err := c.db.QueryContext(ctx, fmt.Sprintf(columnsQuery, quoteString(database), quoteString(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]string)
+	for rows.Next() {
+		var name, datatype string
+		if err := rows.Scan(&name, &datatype); err != nil {
+			return nil, err
+		}
+		columns[name] = datatype
+	}
+	return columns, rows.Err()
+}
+
+func (c *nativeClient) insert(ctx context.Context, database, table string, columns []string, rows [][]interface{}) error {
+	// The driver sends all rows of a prepared statement within a transaction
+	// as one block on commit
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin failed: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after successful commit
+
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, quoteIdent(column))
+	}
+	query := fmt.Sprintf("INSERT INTO %s.%s (%s) VALUES", quoteIdent(database), quoteIdent(table), strings.Join(quoted, ", "))
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("prepare failed: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("execution failed: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit failed: %w", err)
+	}
+	return nil
+}
+
+func (c *nativeClient) close() error {
+	return c.db.Close()
+}
+
+// httpClient uses the HTTP interface
+type httpClient struct {
+	address  string
+	username string
+	password string
+	client   *http.Client
+}
+
+func (c *httpClient) exec(ctx context.Context, query string) error {
+	_, err := c.do(ctx, query, nil)
+	return err
+}
+
+func (c *httpClient) columns(ctx context.Context, database, table string) (map[string]string, error) {
+	body, err := c.do(ctx, fmt.Sprintf(columnsQuery, quoteString(database), quoteString(table))+" FORMAT TabSeparated", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		name, datatype, found := strings.Cut(scanner.Text(), "\t")
+		if !found {
+			return nil, fmt.Errorf("invalid column description %q", scanner.Text())
+		}
+		columns[unescapeTSV(name)] = unescapeTSV(datatype)
+	}
+	return columns, scanner.Err()
+}
+
+func (c *httpClient) insert(ctx context.Context, database, table string, columns []string, rows [][]interface{}) error {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, quoteIdent(column))
+	}
+	query := fmt.Sprintf("INSERT INTO %s.%s (%s) FORMAT JSONEachRow", quoteIdent(database), quoteIdent(table), strings.Join(quoted, ", "))
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, row := range rows {
+		record := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			v := row[i]
+			// Encode timestamps with nanosecond precision
+			if t, ok := v.(time.Time); ok {
+				v = t.UTC().Format("2006-01-02 15:04:05.000000000")
+			}
+			record[column] = v
+		}
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("encoding row failed: %w", err)
+		}
+	}
+
+	_, err := c.do(ctx, query, &buf)
+	return err
+}
+
+func (c *httpClient) do(ctx context.Context, query string, body io.Reader) ([]byte, error) {
+	u, err := url.Parse(c.address)
+	if err != nil {
+		return nil, err
+	}
+	params := u.Query()
+	params.Set("query", query)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" {
+		req.Header.Set("X-ClickHouse-User", c.username)
+	}
+	if c.password != "" {
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg := strings.TrimSpace(string(content))
+		if msg == "" {
+			msg = resp.Status
+		}
+		return nil, errors.New(msg)
+	}
+	return content, nil
+}
+
+func (c *httpClient) close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+func unescapeTSV(s string) string {
+	return strings.NewReplacer(`\t`, "\t", `\n`, "\n", `\'`, "'", `\\`, `\`).Replace(s)
+}
@@ -2,12 +2,19 @@
 package file
 
 import (
+	"bytes"
 	_ "embed"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
 	"time"
 
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
@@ -26,12 +33,37 @@ type File struct {
 	UseBatchFormat       bool            `toml:"use_batch_format"`
 	CompressionAlgorithm string          `toml:"compression_algorithm"`
 	CompressionLevel     int             `toml:"compression_level"`
+	MaxOpenFiles         int             `toml:"max_open_files"`
+	CloseIdleAfter       config.Duration `toml:"close_idle_after"`
 	Log                  telegraf.Logger `toml:"-"`
 
 	encoder    internal.ContentEncoder
-	writer     io.Writer
+	writers    []staticFile
 	closers    []io.Closer
 	serializer telegraf.Serializer
+
+	// Files with templated paths are opened on demand
+	templates []*pathTemplate
+	handles   *simplelru.LRU[string, *handle]
+}
+
+// staticFile is a file with a fixed path receiving all metrics
+type staticFile struct {
+	name   string
+	writer io.Writer
+}
+
+// pathTemplate generates file paths below the directory given by the static
+// part of the template
+type pathTemplate struct {
+	*template.Template
+	prefix string
+}
+
+// handle is an open file of a templated path
+type handle struct {
+	writer   io.WriteCloser
+	lastUsed time.Time
 }
 
 func (*File) SampleConfig() string {
@@ -47,6 +79,26 @@ func (f *File) Init() error {
 	if len(f.Files) == 0 {
 		f.Files = []string{"stdout"}
 	}
+	if f.MaxOpenFiles <= 0 {
+		f.MaxOpenFiles = 100
+	}
+
+	// Setup templates for files generating the path from the metric
+	funcs := template.FuncMap{"now": time.Now}
+	for _, file := range f.Files {
+		if !strings.Contains(file, "{{") {
+			continue
+		}
+		tmpl, err := template.New(file).Funcs(funcs).Parse(file)
+		if err != nil {
+			return fmt.Errorf("parsing file template %q failed: %w", file, err)
+		}
+		static, _, _ := strings.Cut(file, "{{")
+		f.templates = append(f.templates, &pathTemplate{
+			Template: tmpl,
+			prefix:   static[:strings.LastIndexAny(static, `/`+string(filepath.Separator))+1],
+		})
+	}
 
 	var options []internal.EncodingOption
 	if f.CompressionAlgorithm == "" {
@@ -62,11 +114,13 @@ func (f *File) Init() error {
 }
 
 func (f *File) Connect() error {
-	var writers []io.Writer
-
+	f.writers = nil
 	for _, file := range f.Files {
+		if strings.Contains(file, "{{") {
+			continue
+		}
 		if file == "stdout" {
-			writers = append(writers, os.Stdout)
+			f.writers = append(f.writers, staticFile{name: file, writer: os.Stdout})
 		} else {
 			of, err := rotate.NewFileWriter(
 				file, time.Duration(f.RotationInterval), int64(f.RotationMaxSize), f.RotationMaxArchives)
@@ -74,11 +128,22 @@ func (f *File) Connect() error {
 				return err
 			}
 
-			writers = append(writers, of)
+			f.writers = append(f.writers, staticFile{name: file, writer: of})
 			f.closers = append(f.closers, of)
 		}
 	}
-	f.writer = io.MultiWriter(writers...)
+
+	if len(f.templates) > 0 {
+		handles, err := simplelru.NewLRU(f.MaxOpenFiles, func(fn string, h *handle) {
+			if err := h.writer.Close(); err != nil {
+				f.Log.Errorf("Closing file %q failed: %v", fn, err)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		f.handles = handles
+	}
 	return nil
 }
 
@@ -90,10 +155,164 @@ func (f *File) Close() error {
 			err = errClose
 		}
 	}
+	if f.handles != nil {
+		for _, fn := range f.handles.Keys() {
+			if h, found := f.handles.Peek(fn); found {
+				if errClose := h.writer.Close(); errClose != nil {
+					err = errClose
+				}
+			}
+		}
+		f.handles = nil
+	}
 	return err
 }
 
+// Write the metrics to all files. Metrics failing to be written to any of
+// their files are kept for the next write, metrics without any file are
+// dropped.
 func (f *File) Write(metrics []telegraf.Metric) error {
+	var errs []error
+	routed := make([]bool, len(metrics))
+	failed := make([]bool, len(metrics))
+
+	for _, sf := range f.writers {
+		err := f.write(sf.writer, metrics)
+		for i := range metrics {
+			routed[i] = true
+			failed[i] = failed[i] || err != nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("writing to %q failed: %w", sf.name, err))
+		}
+	}
+
+	if len(f.templates) > 0 {
+		errs = append(errs, f.writeTemplated(metrics, routed, failed)...)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var wErr internal.PartialWriteError
+	for i := range metrics {
+		switch {
+		case failed[i]:
+			// Keep the metric to write it again with the next write
+		case routed[i]:
+			wErr.MetricsAccept = append(wErr.MetricsAccept, i)
+		default:
+			wErr.MetricsReject = append(wErr.MetricsReject, i)
+		}
+	}
+	wErr.Err = errors.Join(errs...)
+	return &wErr
+}
+
+// writeTemplated writes the metrics to the files generated from the path
+// templates and marks the metrics written and failed
+func (f *File) writeTemplated(metrics []telegraf.Metric, routed, failed []bool) []error {
+	// Group the metrics per templated file
+	var buf bytes.Buffer
+	groups := make(map[string][]int)
+	for i, raw := range metrics {
+		m := raw
+		if wm, ok := raw.(telegraf.UnwrappableMetric); ok {
+			m = wm.Unwrap()
+		}
+
+		for _, tmpl := range f.templates {
+			buf.Reset()
+			if err := tmpl.Execute(&buf, m); err != nil {
+				f.Log.Errorf("Cannot create filename %q for metric %v: %v", tmpl.Name(), m, err)
+				continue
+			}
+			fn, err := tmpl.path(buf.String())
+			if err != nil {
+				f.Log.Errorf("Cannot create filename %q for metric %v: %v", tmpl.Name(), m, err)
+				continue
+			}
+			groups[fn] = append(groups[fn], i)
+		}
+	}
+
+	var errs []error
+	now := time.Now()
+	for fn, indices := range groups {
+		for _, i := range indices {
+			routed[i] = true
+		}
+
+		h, err := f.handle(fn, now)
+		if err != nil {
+			err = fmt.Errorf("opening file %q failed: %w", fn, err)
+		} else {
+			group := make([]telegraf.Metric, 0, len(indices))
+			for _, i := range indices {
+				group = append(group, metrics[i])
+			}
+			if err = f.write(h.writer, group); err != nil {
+				err = fmt.Errorf("writing to %q failed: %w", fn, err)
+			}
+		}
+		if err != nil {
+			for _, i := range indices {
+				failed[i] = true
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	// Close files not written to for a while
+	if f.CloseIdleAfter > 0 {
+		for _, fn := range f.handles.Keys() {
+			if h, found := f.handles.Peek(fn); found && now.Sub(h.lastUsed) > time.Duration(f.CloseIdleAfter) {
+				f.handles.Remove(fn)
+			}
+		}
+	}
+
+	return errs
+}
+
+// path checks the generated path to be located below the static part of the
+// template and to not contain empty or hidden elements, e.g. resulting from
+// missing tags.
+func (t *pathTemplate) path(generated string) (string, error) {
+	rel, found := strings.CutPrefix(generated, t.prefix)
+	if !found || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("path %q is outside of %q", generated, t.prefix)
+	}
+	for _, element := range strings.Split(filepath.ToSlash(rel), "/") {
+		if element == "" || strings.HasPrefix(element, ".") {
+			return "", fmt.Errorf("path %q contains empty or hidden elements", generated)
+		}
+	}
+	return filepath.Clean(generated), nil
+}
+
+// handle returns the open file for the given path, opening it if necessary.
+// The least recently used file is closed when exceeding the open files limit.
+func (f *File) handle(fn string, now time.Time) (*handle, error) {
+	if h, found := f.handles.Get(fn); found {
+		h.lastUsed = now
+		return h, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fn), 0750); err != nil {
+		return nil, err
+	}
+	w, err := rotate.NewFileWriter(fn, time.Duration(f.RotationInterval), int64(f.RotationMaxSize), f.RotationMaxArchives)
+	if err != nil {
+		return nil, err
+	}
+	h := &handle{writer: w, lastUsed: now}
+	f.handles.Add(fn, h)
+	return h, nil
+}
+
+func (f *File) write(writer io.Writer, metrics []telegraf.Metric) error {
 	var writeErr error
 
 	if f.UseBatchFormat {
@@ -107,9 +326,9 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 			f.Log.Errorf("Could not compress metrics: %v", err)
 		}
 
-		_, err = f.writer.Write(octets)
+		_, err = writer.Write(octets)
 		if err != nil {
-			f.Log.Errorf("Error writing to file: %v", err)
+			writeErr = fmt.Errorf("failed to write message: %w", err)
 		}
 	} else {
 		for _, metric := range metrics {
@@ -123,7 +342,7 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 				f.Log.Errorf("Could not compress metrics: %v", err)
 			}
 
-			_, err = f.writer.Write(b)
+			_, err = writer.Write(b)
 			if err != nil {
 				writeErr = fmt.Errorf("failed to write message: %w", err)
 			}
@@ -137,6 +356,7 @@ func init() {
 	outputs.Add("file", func() telegraf.Output {
 		return &File{
 			CompressionLevel: -1,
+			MaxOpenFiles:     100,
 		}
 	})
 }
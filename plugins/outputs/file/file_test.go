@@ -2,13 +2,20 @@ package file
 
 import (
 	"bytes"
+	"errors"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -344,3 +351,166 @@ func validateZlibCompressedFile(t *testing.T, fileName, expS string) {
 	require.NoError(t, err)
 	require.Equal(t, expS, string(buf))
 }
+
+func TestFileTemplate(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	s := &influx.Serializer{}
+	require.NoError(t, s.Init())
+
+	f := File{
+		Files:            []string{filepath.Join(tmpdir, `{{.Name}}`, `{{.Tag "host"}}.lp`)},
+		CompressionLevel: -1,
+		serializer:       s,
+		Log:              &testutil.Logger{},
+	}
+	require.NoError(t, f.Init())
+	require.NoError(t, f.Connect())
+	defer f.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+		metric.New("mem", map[string]string{"host": "a"}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 4}, time.Unix(1, 0)),
+	}
+	require.NoError(t, f.Write(metrics))
+
+	validateFile(t, filepath.Join(tmpdir, "cpu", "a.lp"), "cpu,host=a value=1i 0\ncpu,host=a value=4i 1000000000\n")
+	validateFile(t, filepath.Join(tmpdir, "cpu", "b.lp"), "cpu,host=b value=2i 0\n")
+	validateFile(t, filepath.Join(tmpdir, "mem", "a.lp"), "mem,host=a value=3i 0\n")
+}
+
+func TestFileTemplateOpenFiles(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	s := &influx.Serializer{}
+	require.NoError(t, s.Init())
+
+	f := File{
+		Files:            []string{filepath.Join(tmpdir, `{{.Name}}.lp`)},
+		CompressionLevel: -1,
+		MaxOpenFiles:     2,
+		serializer:       s,
+		Log:              &testutil.Logger{},
+	}
+	require.NoError(t, f.Init())
+	require.NoError(t, f.Connect())
+	defer f.Close()
+
+	for _, name := range []string{"a", "b", "c", "a"} {
+		m := metric.New(name, map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+		require.NoError(t, f.Write([]telegraf.Metric{m}))
+	}
+
+	// The least recently used file is closed and reopened for appending
+	require.ElementsMatch(t, []string{
+		filepath.Join(tmpdir, "c.lp"),
+		filepath.Join(tmpdir, "a.lp"),
+	}, f.handles.Keys())
+	validateFile(t, filepath.Join(tmpdir, "a.lp"), "a value=1i 0\na value=1i 0\n")
+
+	// Idle files are closed while the file just written to is kept open
+	f.CloseIdleAfter = config.Duration(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	m := metric.New("b", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	require.NoError(t, f.Write([]telegraf.Metric{m}))
+	require.Equal(t, []string{filepath.Join(tmpdir, "b.lp")}, f.handles.Keys())
+	validateFile(t, filepath.Join(tmpdir, "b.lp"), "b value=1i 0\nb value=1i 0\n")
+}
+
+func TestFileTemplateUnsafePaths(t *testing.T) {
+	tmpdir := t.TempDir()
+	dir := filepath.Join(tmpdir, "metrics")
+
+	s := &influx.Serializer{}
+	require.NoError(t, s.Init())
+
+	logger := &testutil.CaptureLogger{}
+	f := File{
+		Files:            []string{filepath.Join(dir, `{{.Name}}`, `{{.Tag "host"}}.lp`)},
+		CompressionLevel: -1,
+		serializer:       s,
+		Log:              logger,
+	}
+	require.NoError(t, f.Init())
+	require.NoError(t, f.Connect())
+	defer f.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+		metric.New("..", map[string]string{"host": "escape"}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "../../escape"}, map[string]interface{}{"value": 4}, time.Unix(0, 0)),
+		metric.New("", map[string]string{"host": "a"}, map[string]interface{}{"value": 5}, time.Unix(0, 0)),
+	}
+	require.NoError(t, f.Write(metrics))
+	require.Len(t, logger.Errors(), 4)
+
+	// Only the valid path is written and nothing outside of the directory
+	validateFile(t, filepath.Join(dir, "cpu", "a.lp"), "cpu,host=a value=1i 0\n")
+	var files []string
+	require.NoError(t, filepath.WalkDir(tmpdir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	}))
+	require.Equal(t, []string{filepath.Join(dir, "cpu", "a.lp")}, files)
+}
+
+func TestFilePartialWrite(t *testing.T) {
+	tmpdir := t.TempDir()
+
+	// A file in place of the directory lets opening the "mem" files fail
+	require.NoError(t, os.WriteFile(filepath.Join(tmpdir, "mem"), nil, 0640))
+
+	s := &influx.Serializer{}
+	require.NoError(t, s.Init())
+
+	f := File{
+		Files:            []string{filepath.Join(tmpdir, `{{.Name}}`, `{{.Tag "host"}}.lp`)},
+		CompressionLevel: -1,
+		serializer:       s,
+		Log:              &testutil.Logger{},
+	}
+	require.NoError(t, f.Init())
+	require.NoError(t, f.Connect())
+	defer f.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		metric.New("mem", map[string]string{"host": "a"}, map[string]interface{}{"value": 2}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 3}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 4}, time.Unix(0, 0)),
+	}
+
+	// Metrics of the failing file are kept while the unroutable one is dropped
+	err := f.Write(metrics)
+	var wErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &wErr)
+	require.ErrorContains(t, err, "opening file")
+	require.Equal(t, []int{0, 2}, wErr.MetricsAccept)
+	require.Equal(t, []int{3}, wErr.MetricsReject)
+	validateFile(t, filepath.Join(tmpdir, "cpu", "a.lp"), "cpu,host=a value=1i 0\n")
+	validateFile(t, filepath.Join(tmpdir, "cpu", "b.lp"), "cpu,host=b value=3i 0\n")
+
+	// A failing static file keeps all metrics
+	f.writers = append(f.writers, staticFile{name: "broken", writer: &failingWriter{}})
+	err = f.Write(metrics[:1])
+	require.ErrorAs(t, err, &wErr)
+	require.ErrorContains(t, err, `writing to "broken" failed`)
+	require.Empty(t, wErr.MetricsAccept)
+	require.Empty(t, wErr.MetricsReject)
+}
+
+type failingWriter struct{}
+
+func (*failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken")
+}
+
+func TestFileTemplateInvalid(t *testing.T) {
+	f := File{Files: []string{`{{.Name`}}
+	require.ErrorContains(t, f.Init(), "parsing file template")
+}
@@ -8,23 +8,33 @@ import (
 	"crypto/sha256"
 	_ "embed"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"net/http"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	aws_signer "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/tidwall/gjson"
 	"golang.org/x/oauth2"
 	"google.golang.org/api/idtoken"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/models"
 	common_aws "github.com/influxdata/telegraf/plugins/common/aws"
 	common_http "github.com/influxdata/telegraf/plugins/common/http"
 	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
 )
 
 //go:embed sample.conf
@@ -41,6 +51,20 @@ const (
 	defaultUseBatchFormat = true
 )
 
+// Actions for requests failing with a non-successful status code
+const (
+	actionRetry      = "retry"
+	actionDrop       = "drop"
+	actionDeadLetter = "dead_letter"
+)
+
+// Response headers of remote-write 2.0 receivers reporting the written data
+const (
+	remoteWriteSamplesWritten    = "X-Prometheus-Remote-Write-Samples-Written"
+	remoteWriteHistogramsWritten = "X-Prometheus-Remote-Write-Histograms-Written"
+	remoteWriteExemplarsWritten  = "X-Prometheus-Remote-Write-Exemplars-Written"
+)
+
 type HTTP struct {
 	URL                     string                    `toml:"url"`
 	Method                  string                    `toml:"method"`
@@ -51,12 +75,29 @@ type HTTP struct {
 	UseBatchFormat          bool                      `toml:"use_batch_format"`
 	AwsService              string                    `toml:"aws_service"`
 	NonRetryableStatusCodes []int                     `toml:"non_retryable_statuscodes"`
+	StatusActions           map[string]string         `toml:"status_actions"`
+	DeadLetterFile          string                    `toml:"dead_letter_file"`
+	RejectedIndicesPath     string                    `toml:"rejected_indices_path"`
+	RetryBackoffInitial     config.Duration           `toml:"retry_backoff_initial"`
+	RetryBackoffMax         config.Duration           `toml:"retry_backoff_max"`
+	RetryAfterMax           config.Duration           `toml:"retry_after_max"`
 	common_http.HTTPClientConfig
 	Log telegraf.Logger `toml:"-"`
 
 	client     *http.Client
 	serializer telegraf.Serializer
 
+	// State for delaying retries after failed requests
+	retryCount int
+	retryTime  time.Time
+
+	deadLetterSerializer *influx.Serializer
+
+	// Headers and protocol version required by the serializer
+	serializerHeaders  map[string]string
+	remoteWriteVersion string
+	warnedStats        bool
+
 	awsCfg *aws.Config
 	common_aws.CredentialConfig
 
@@ -69,6 +110,36 @@ func (*HTTP) SampleConfig() string {
 	return sampleConfig
 }
 
+func (h *HTTP) Init() error {
+	for status, action := range h.StatusActions {
+		if !validStatusKey(status) {
+			return fmt.Errorf("invalid status %q in status_actions", status)
+		}
+		switch action {
+		case actionRetry, actionDrop:
+		case actionDeadLetter:
+			if h.DeadLetterFile == "" {
+				return fmt.Errorf("action %q for status %q requires 'dead_letter_file'", action, status)
+			}
+		default:
+			return fmt.Errorf("invalid action %q for status %q", action, status)
+		}
+	}
+
+	if h.RetryBackoffMax < h.RetryBackoffInitial {
+		return errors.New("'retry_backoff_max' must not be smaller than 'retry_backoff_initial'")
+	}
+
+	if h.DeadLetterFile != "" {
+		h.deadLetterSerializer = &influx.Serializer{}
+		if err := h.deadLetterSerializer.Init(); err != nil {
+			return fmt.Errorf("initializing dead-letter serializer failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func (h *HTTP) SetSerializer(serializer telegraf.Serializer) {
 	h.serializer = serializer
 }
@@ -89,6 +160,19 @@ func (h *HTTP) Connect() error {
 		return fmt.Errorf("invalid method [%s] %s", h.URL, h.Method)
 	}
 
+	// Remote-write requires specific headers depending on the version
+	serializer := h.serializer
+	if unwrapped, ok := serializer.(*models.RunningSerializer); ok {
+		serializer = unwrapped.Serializer
+	}
+	if rw, ok := serializer.(*prometheusremotewrite.Serializer); ok {
+		if h.ContentEncoding != "" && h.ContentEncoding != "identity" {
+			return errors.New("'content_encoding' is not supported with prometheusremotewrite, the data is snappy encoded")
+		}
+		h.serializerHeaders = rw.Headers()
+		h.remoteWriteVersion = rw.Version
+	}
+
 	ctx := context.Background()
 	client, err := h.HTTPClientConfig.CreateClient(ctx, h.Log)
 	if err != nil {
@@ -109,29 +193,235 @@ func (h *HTTP) Close() error {
 }
 
 func (h *HTTP) Write(metrics []telegraf.Metric) error {
+	if h.retryTime.After(time.Now()) {
+		return fmt.Errorf("waiting until %s before retrying to write to [%s]", h.retryTime.Format(time.RFC3339), h.URL)
+	}
+
+	var wErr internal.PartialWriteError
 	if h.UseBatchFormat {
 		reqBody, err := h.serializer.SerializeBatch(metrics)
 		if err != nil {
 			return err
 		}
 
-		return h.writeMetric(reqBody)
+		indices := make([]int, len(metrics))
+		for i := range metrics {
+			indices[i] = i
+		}
+		if err := h.send(reqBody, metrics, indices, &wErr); err != nil {
+			return err
+		}
+	} else {
+		for i, metric := range metrics {
+			reqBody, err := h.serializer.Serialize(metric)
+			if err != nil {
+				if len(wErr.MetricsAccept) == 0 && len(wErr.MetricsReject) == 0 {
+					return err
+				}
+				wErr.Err = err
+				return &wErr
+			}
+
+			if err := h.send(reqBody, metrics, []int{i}, &wErr); err != nil {
+				return err
+			}
+		}
 	}
 
-	for _, metric := range metrics {
-		reqBody, err := h.serializer.Serialize(metric)
-		if err != nil {
-			return err
+	if len(wErr.MetricsReject) == 0 {
+		return nil
+	}
+	wErr.Err = fmt.Errorf("%d metrics were rejected by [%s]", len(wErr.MetricsReject), h.URL)
+	return &wErr
+}
+
+// send writes the request body containing the metrics with the given indices
+// and records the outcome in the write error. An error is returned if the
+// request should be retried later, stopping the write.
+func (h *HTTP) send(reqBody []byte, metrics []telegraf.Metric, indices []int, wErr *internal.PartialWriteError) error {
+	rejected, err := h.writeMetric(reqBody)
+	if err == nil {
+		h.retryCount = 0
+		h.retryTime = time.Time{}
+
+		// Map the indices rejected by the receiver to the batch indices
+		reject := make(map[int]bool, len(rejected))
+		for _, idx := range rejected {
+			if idx < 0 || idx >= len(indices) {
+				h.Log.Warnf("Ignoring rejected index %d out of range for %d metrics", idx, len(indices))
+				continue
+			}
+			reject[idx] = true
+		}
+		if len(reject) == 0 {
+			wErr.MetricsAccept = append(wErr.MetricsAccept, indices...)
+			return nil
 		}
 
-		if err := h.writeMetric(reqBody); err != nil {
-			return err
+		h.Log.Errorf("Receiver rejected %d of %d metrics", len(reject), len(indices))
+		dropped := make([]telegraf.Metric, 0, len(reject))
+		for i, idx := range indices {
+			if reject[i] {
+				wErr.MetricsReject = append(wErr.MetricsReject, idx)
+				dropped = append(dropped, metrics[idx])
+			} else {
+				wErr.MetricsAccept = append(wErr.MetricsAccept, idx)
+			}
+		}
+		if h.DeadLetterFile != "" {
+			if err := h.writeDeadLetter(dropped); err != nil {
+				h.Log.Errorf("Writing rejected metrics to dead-letter file failed: %v", err)
+			}
 		}
+		return nil
 	}
+
+	var sErr *statusError
+	if !errors.As(err, &sErr) {
+		return h.retry(err, 0, wErr)
+	}
+
+	// Keep the behavior of the non-retryable status codes for compatibility
+	if slices.Contains(h.NonRetryableStatusCodes, sErr.statusCode) {
+		h.Log.Errorf("Received non-retryable status %v. Metrics are lost.", sErr.statusCode)
+		wErr.MetricsAccept = append(wErr.MetricsAccept, indices...)
+		return nil
+	}
+
+	switch h.statusAction(sErr.statusCode) {
+	case actionDrop:
+		h.Log.Errorf("Dropping %d metrics: %v", len(indices), err)
+	case actionDeadLetter:
+		selected := make([]telegraf.Metric, 0, len(indices))
+		for _, idx := range indices {
+			selected = append(selected, metrics[idx])
+		}
+		if dlErr := h.writeDeadLetter(selected); dlErr != nil {
+			return h.retry(fmt.Errorf("%w; writing to dead-letter file failed: %w", err, dlErr), 0, wErr)
+		}
+		h.Log.Errorf("Wrote %d metrics to dead-letter file: %v", len(indices), err)
+	default:
+		return h.retry(err, sErr.retryAfter, wErr)
+	}
+	wErr.MetricsReject = append(wErr.MetricsReject, indices...)
 	return nil
 }
 
-func (h *HTTP) writeMetric(reqBody []byte) error {
+// retry delays the next write using exponential backoff or the delay
+// requested by the receiver, whichever is longer
+func (h *HTTP) retry(err error, retryAfter time.Duration, wErr *internal.PartialWriteError) error {
+	h.retryCount++
+	if wait := max(h.backoff(), retryAfter); wait > 0 {
+		h.retryTime = time.Now().Add(wait)
+		err = fmt.Errorf("%w; retrying in %s", err, wait)
+	}
+
+	if len(wErr.MetricsAccept) == 0 && len(wErr.MetricsReject) == 0 {
+		return err
+	}
+	wErr.Err = err
+	return wErr
+}
+
+// backoff computes the exponential backoff with jitter for the current retry
+func (h *HTTP) backoff() time.Duration {
+	if h.RetryBackoffInitial <= 0 {
+		return 0
+	}
+
+	limit := float64(h.RetryBackoffMax)
+	if limit <= 0 {
+		limit = math.MaxInt64
+	}
+	wait := math.Min(float64(h.RetryBackoffInitial)*math.Pow(2, float64(h.retryCount-1)), limit)
+
+	// Randomize the second half of the interval to avoid synchronized retries
+	return time.Duration(wait/2 + rand.Float64()*wait/2) //nolint:gosec // G404: not security critical
+}
+
+// statusAction returns the action for the given status code with specific
+// codes taking precedence over status classes such as "5xx"
+func (h *HTTP) statusAction(code int) string {
+	if action, found := h.StatusActions[strconv.Itoa(code)]; found {
+		return action
+	}
+	if action, found := h.StatusActions[strconv.Itoa(code/100)+"xx"]; found {
+		return action
+	}
+	return actionRetry
+}
+
+func (h *HTTP) writeDeadLetter(metrics []telegraf.Metric) error {
+	if h.deadLetterSerializer == nil {
+		return errors.New("no dead-letter file configured")
+	}
+
+	buf, err := h.deadLetterSerializer.SerializeBatch(metrics)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// validStatusKey checks for a status code or a status class like "4xx"
+func validStatusKey(key string) bool {
+	if len(key) != 3 {
+		return false
+	}
+	if strings.HasSuffix(key, "xx") {
+		return key[0] >= '1' && key[0] <= '5'
+	}
+	code, err := strconv.Atoi(key)
+	return err == nil && code >= 100 && code <= 599
+}
+
+// statusError is returned for requests failing with a non-successful status
+type statusError struct {
+	statusCode int
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+// parseRetryAfter returns the delay requested via the Retry-After header
+// given either in seconds or as HTTP date
+func (h *HTTP) parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.ParseUint(value, 10, 32); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		wait = time.Until(t)
+	} else {
+		h.Log.Debugf("Ignoring invalid Retry-After header %q", value)
+		return 0
+	}
+
+	if h.RetryAfterMax > 0 {
+		wait = min(wait, time.Duration(h.RetryAfterMax))
+	}
+	return max(wait, 0)
+}
+
+// writeMetric sends the request body and returns the indices of the metrics
+// rejected by the receiver on success
+func (h *HTTP) writeMetric(reqBody []byte) ([]int, error) {
 	var reqBodyBuffer io.Reader = bytes.NewBuffer(reqBody)
 
 	var err error
@@ -147,7 +437,7 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 		buf := new(bytes.Buffer)
 		_, err = io.Copy(buf, reqBodyBuffer)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		sum := sha256.Sum256(buf.Bytes())
@@ -160,7 +450,7 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 
 	req, err := http.NewRequest(h.Method, h.URL, reqBodyBuffer)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if h.awsCfg != nil {
@@ -169,24 +459,24 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 
 		credentials, err := h.awsCfg.Credentials.Retrieve(ctx)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		err = signer.SignHTTP(ctx, credentials, req, *payloadHash, h.AwsService, h.Region, time.Now().UTC())
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	if !h.Username.Empty() || !h.Password.Empty() {
 		username, err := h.Username.Get()
 		if err != nil {
-			return fmt.Errorf("getting username failed: %w", err)
+			return nil, fmt.Errorf("getting username failed: %w", err)
 		}
 		password, err := h.Password.Get()
 		if err != nil {
 			username.Destroy()
-			return fmt.Errorf("getting password failed: %w", err)
+			return nil, fmt.Errorf("getting password failed: %w", err)
 		}
 		req.SetBasicAuth(username.String(), password.String())
 		username.Destroy()
@@ -197,7 +487,7 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 	if h.CredentialsFile != "" {
 		token, err := h.getAccessToken(context.Background(), h.URL)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		token.SetAuthHeader(req)
 	}
@@ -207,11 +497,14 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 	if h.ContentEncoding == "gzip" {
 		req.Header.Set("Content-Encoding", "gzip")
 	}
+	for k, v := range h.serializerHeaders {
+		req.Header.Set(k, v)
+	}
 
 	for k, v := range h.Headers {
 		secret, err := v.Get()
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		headerVal := secret.String()
@@ -225,41 +518,77 @@ func (h *HTTP) writeMetric(reqBody []byte) error {
 
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		for _, nonRetryableStatusCode := range h.NonRetryableStatusCodes {
-			if resp.StatusCode == nonRetryableStatusCode {
-				h.Log.Errorf("Received non-retryable status %v. Metrics are lost.", resp.StatusCode)
-				return nil
-			}
-		}
-
 		errorLine := ""
 		scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxErrMsgLen))
 		if scanner.Scan() {
 			errorLine = scanner.Text()
 		}
 
-		return fmt.Errorf("when writing to [%s] received status code: %d. body: %s", h.URL, resp.StatusCode, errorLine)
+		sErr := &statusError{
+			statusCode: resp.StatusCode,
+			retryAfter: h.parseRetryAfter(resp.Header),
+			err:        fmt.Errorf("when writing to [%s] received status code: %d. body: %s", h.URL, resp.StatusCode, errorLine),
+		}
+		if resp.StatusCode == http.StatusUnsupportedMediaType && h.remoteWriteVersion == "2.0" {
+			sErr.err = fmt.Errorf("when writing to [%s] received status code: %d, the receiver might not support remote-write 2.0. body: %s",
+				h.URL, resp.StatusCode, errorLine)
+		}
+		return nil, sErr
 	}
 
-	_, err = io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("when writing to [%s] received error: %w", h.URL, err)
+		return nil, fmt.Errorf("when writing to [%s] received error: %w", h.URL, err)
 	}
 
-	return nil
+	if h.remoteWriteVersion == "2.0" {
+		h.checkRemoteWriteStats(resp.Header)
+	}
+
+	if h.RejectedIndicesPath == "" || len(respBody) == 0 {
+		return nil, nil
+	}
+	result := gjson.GetBytes(respBody, h.RejectedIndicesPath)
+	if !result.IsArray() {
+		return nil, nil
+	}
+	var rejected []int
+	for _, idx := range result.Array() {
+		rejected = append(rejected, int(idx.Int()))
+	}
+	return rejected, nil
+}
+
+// checkRemoteWriteStats evaluates the statistics reported by remote-write 2.0
+// receivers. Receivers not reporting the statistics only support
+// remote-write 1.0 and silently drop data such as native histograms.
+func (h *HTTP) checkRemoteWriteStats(header http.Header) {
+	samples := header.Get(remoteWriteSamplesWritten)
+	histograms := header.Get(remoteWriteHistogramsWritten)
+	exemplars := header.Get(remoteWriteExemplarsWritten)
+	if samples == "" && histograms == "" && exemplars == "" {
+		if !h.warnedStats {
+			h.Log.Warnf("Receiver at [%s] did not report the written data and might not support remote-write 2.0", h.URL)
+			h.warnedStats = true
+		}
+		return
+	}
+	h.Log.Tracef("Receiver wrote %s samples, %s histograms and %s exemplars", samples, histograms, exemplars)
 }
 
 func init() {
 	outputs.Add("http", func() telegraf.Output {
 		return &HTTP{
-			Method:         defaultMethod,
-			URL:            defaultURL,
-			UseBatchFormat: defaultUseBatchFormat,
+			Method:          defaultMethod,
+			URL:             defaultURL,
+			UseBatchFormat:  defaultUseBatchFormat,
+			RetryBackoffMax: config.Duration(5 * time.Minute),
+			RetryAfterMax:   config.Duration(10 * time.Minute),
 		}
 	})
 }
@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -23,6 +24,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/common/oauth"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -817,3 +819,365 @@ func TestAwsCredentials(t *testing.T) {
 		})
 	}
 }
+
+func TestRemoteWriteHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		expected map[string]string
+	}{
+		{
+			name:    "version 1.0",
+			version: "1.0",
+			expected: map[string]string{
+				"Content-Type":                      "application/x-protobuf",
+				"Content-Encoding":                  "snappy",
+				"X-Prometheus-Remote-Write-Version": "0.1.0",
+			},
+		},
+		{
+			name:    "version 2.0",
+			version: "2.0",
+			expected: map[string]string{
+				"Content-Type":                      "application/x-protobuf;proto=io.prometheus.write.v2.Request",
+				"Content-Encoding":                  "snappy",
+				"X-Prometheus-Remote-Write-Version": "2.0.0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.expected {
+					if actual := r.Header.Get(k); actual != v {
+						w.WriteHeader(http.StatusInternalServerError)
+						t.Errorf("Header %q not equal, expected: %q, actual: %q", k, v, actual)
+						return
+					}
+				}
+				w.Header().Set(remoteWriteSamplesWritten, "1")
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer ts.Close()
+
+			serializer := &prometheusremotewrite.Serializer{Version: tt.version, Log: &testutil.Logger{}}
+			require.NoError(t, serializer.Init())
+
+			plugin := &HTTP{
+				URL:    ts.URL,
+				Method: defaultMethod,
+				Log:    &testutil.Logger{},
+			}
+			plugin.SetSerializer(serializer)
+			require.NoError(t, plugin.Connect())
+			require.NoError(t, plugin.Write([]telegraf.Metric{getMetric()}))
+		})
+	}
+}
+
+func TestRemoteWriteContentEncoding(t *testing.T) {
+	serializer := &prometheusremotewrite.Serializer{Log: &testutil.Logger{}}
+	require.NoError(t, serializer.Init())
+
+	plugin := &HTTP{
+		URL:             "http://localhost",
+		Method:          defaultMethod,
+		ContentEncoding: "gzip",
+	}
+	plugin.SetSerializer(serializer)
+	require.ErrorContains(t, plugin.Connect(), "'content_encoding' is not supported with prometheusremotewrite")
+}
+
+func TestRemoteWriteV2MissingStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	serializer := &prometheusremotewrite.Serializer{Version: "2.0", Log: &testutil.Logger{}}
+	require.NoError(t, serializer.Init())
+
+	logger := &testutil.CaptureLogger{}
+	plugin := &HTTP{
+		URL:    ts.URL,
+		Method: defaultMethod,
+		Log:    logger,
+	}
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	// The warning must only be issued once
+	require.NoError(t, plugin.Write([]telegraf.Metric{getMetric()}))
+	require.NoError(t, plugin.Write([]telegraf.Metric{getMetric()}))
+	warnings := logger.Warnings()
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], "might not support remote-write 2.0")
+}
+
+func TestRemoteWriteV2UnsupportedMediaType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+	}))
+	defer ts.Close()
+
+	serializer := &prometheusremotewrite.Serializer{Version: "2.0", Log: &testutil.Logger{}}
+	require.NoError(t, serializer.Init())
+
+	plugin := &HTTP{
+		URL:    ts.URL,
+		Method: defaultMethod,
+		Log:    &testutil.Logger{},
+	}
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{getMetric()}), "the receiver might not support remote-write 2.0")
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *HTTP
+		expected string
+	}{
+		{
+			name:     "invalid status",
+			plugin:   &HTTP{StatusActions: map[string]string{"6xx": "drop"}},
+			expected: `invalid status "6xx" in status_actions`,
+		},
+		{
+			name:     "invalid status code",
+			plugin:   &HTTP{StatusActions: map[string]string{"40": "drop"}},
+			expected: `invalid status "40" in status_actions`,
+		},
+		{
+			name:     "invalid action",
+			plugin:   &HTTP{StatusActions: map[string]string{"4xx": "ignore"}},
+			expected: `invalid action "ignore" for status "4xx"`,
+		},
+		{
+			name:     "dead-letter without file",
+			plugin:   &HTTP{StatusActions: map[string]string{"400": "dead_letter"}},
+			expected: `action "dead_letter" for status "400" requires 'dead_letter_file'`,
+		},
+		{
+			name: "invalid backoff",
+			plugin: &HTTP{
+				RetryBackoffInitial: config.Duration(time.Minute),
+				RetryBackoffMax:     config.Duration(time.Second),
+			},
+			expected: "'retry_backoff_max' must not be smaller than 'retry_backoff_initial'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestStatusActions(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		rejected   bool
+		retry      bool
+	}{
+		{
+			name:       "class action",
+			statusCode: http.StatusBadRequest,
+			rejected:   true,
+		},
+		{
+			name:       "code takes precedence",
+			statusCode: http.StatusTooManyRequests,
+			retry:      true,
+		},
+		{
+			name:       "dead-letter",
+			statusCode: http.StatusConflict,
+			rejected:   true,
+		},
+		{
+			name:       "default is retry",
+			statusCode: http.StatusInternalServerError,
+			retry:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer ts.Close()
+
+			deadLetterFile := filepath.Join(t.TempDir(), "dead_letter.influx")
+			plugin := &HTTP{
+				URL:            ts.URL,
+				Method:         defaultMethod,
+				UseBatchFormat: true,
+				StatusActions: map[string]string{
+					"4xx": "drop",
+					"409": "dead_letter",
+					"429": "retry",
+				},
+				DeadLetterFile: deadLetterFile,
+				Log:            &testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+
+			serializer := &influx.Serializer{}
+			require.NoError(t, serializer.Init())
+			plugin.SetSerializer(serializer)
+			require.NoError(t, plugin.Connect())
+
+			err := plugin.Write(getMetrics(2))
+			require.Error(t, err)
+
+			var wErr *internal.PartialWriteError
+			if tt.retry {
+				require.NotErrorAs(t, err, &wErr)
+			} else {
+				require.ErrorAs(t, err, &wErr)
+				require.Empty(t, wErr.MetricsAccept)
+				require.Equal(t, []int{0, 1}, wErr.MetricsReject)
+			}
+
+			if tt.statusCode == http.StatusConflict {
+				buf, err := os.ReadFile(deadLetterFile)
+				require.NoError(t, err)
+				require.Len(t, strings.Split(strings.TrimSpace(string(buf)), "\n"), 2)
+			} else {
+				require.NoFileExists(t, deadLetterFile)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL:           ts.URL,
+		Method:        defaultMethod,
+		RetryAfterMax: config.Duration(time.Minute),
+		Log:           &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	// The requested delay is limited by the configured maximum
+	start := time.Now()
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{getMetric()}), "retrying in 1m0s")
+	require.WithinRange(t, plugin.retryTime, start.Add(time.Minute), time.Now().Add(time.Minute))
+
+	// Writes are not sent before the delay elapsed
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{getMetric()}), "waiting until")
+	require.Equal(t, 1, requests)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	plugin := &HTTP{
+		RetryBackoffInitial: config.Duration(time.Second),
+		RetryBackoffMax:     config.Duration(10 * time.Second),
+	}
+
+	for i, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second} {
+		plugin.retryCount = i + 1
+		wait := plugin.backoff()
+		require.GreaterOrEqual(t, wait, expected/2)
+		require.LessOrEqual(t, wait, expected)
+	}
+
+	// Backoff is disabled without initial value
+	plugin.RetryBackoffInitial = 0
+	require.Zero(t, plugin.backoff())
+}
+
+func TestRejectedIndices(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"errors": {"rejected": [1, 3, 7]}}`)); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	deadLetterFile := filepath.Join(t.TempDir(), "dead_letter.influx")
+	plugin := &HTTP{
+		URL:                 ts.URL,
+		Method:              defaultMethod,
+		UseBatchFormat:      true,
+		RejectedIndicesPath: "errors.rejected",
+		DeadLetterFile:      deadLetterFile,
+		Log:                 &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	// Index 7 is out of range and ignored
+	err := plugin.Write(getMetrics(4))
+	var wErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &wErr)
+	require.Equal(t, []int{0, 2}, wErr.MetricsAccept)
+	require.Equal(t, []int{1, 3}, wErr.MetricsReject)
+
+	buf, err := os.ReadFile(deadLetterFile)
+	require.NoError(t, err)
+	require.Len(t, strings.Split(strings.TrimSpace(string(buf)), "\n"), 2)
+}
+
+func TestUnbatchedPartialWrite(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.WriteHeader(http.StatusNoContent)
+		case 2:
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	plugin := &HTTP{
+		URL:           ts.URL,
+		Method:        defaultMethod,
+		StatusActions: map[string]string{"4xx": "drop"},
+		Log:           &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Connect())
+
+	// The first metric is written, the second dropped and the remaining ones
+	// are kept for retrying
+	err := plugin.Write(getMetrics(4))
+	var wErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &wErr)
+	require.ErrorContains(t, wErr, "received status code: 503")
+	require.Equal(t, []int{0}, wErr.MetricsAccept)
+	require.Equal(t, []int{1}, wErr.MetricsReject)
+	require.Equal(t, 3, requests)
+}
@@ -0,0 +1,148 @@
+package opentelemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/influxdata/telegraf/internal"
+)
+
+const maxErrMsgLen = 1024
+
+// otlpMessage is implemented by the OTLP export requests and responses of
+// all signal types
+type otlpMessage interface {
+	MarshalProto() ([]byte, error)
+	UnmarshalProto(data []byte) error
+	MarshalJSON() ([]byte, error)
+	UnmarshalJSON(data []byte) error
+}
+
+// httpExporter sends OTLP requests using the OTLP/HTTP transport
+type httpExporter struct {
+	client      *http.Client
+	baseURL     string
+	encoding    string
+	headers     map[string]string
+	contentType string
+	compression string
+	encoder     internal.ContentEncoder
+}
+
+func newHTTPExporter(o *OpenTelemetry) (*httpExporter, error) {
+	u, err := url.Parse(o.ServiceAddress)
+	if err != nil {
+		return nil, fmt.Errorf("parsing service address failed: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid scheme %q in service address for http protocol", u.Scheme)
+	}
+
+	client, err := o.HTTPClientConfig.CreateClient(context.Background(), o.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &httpExporter{
+		client:   client,
+		baseURL:  strings.TrimSuffix(o.ServiceAddress, "/"),
+		encoding: o.Encoding,
+		headers:  o.Headers,
+	}
+
+	switch o.Encoding {
+	case "json":
+		e.contentType = "application/json"
+	default:
+		e.contentType = "application/x-protobuf"
+	}
+
+	if o.Compression != "" && o.Compression != "none" {
+		enc, err := internal.NewContentEncoder(o.Compression)
+		if err != nil {
+			return nil, err
+		}
+		e.compression = o.Compression
+		e.encoder = enc
+	}
+
+	return e, nil
+}
+
+// export sends the request to the endpoint of the given signal path and
+// decodes the response into the given response message
+func (e *httpExporter) export(ctx context.Context, path string, request, response otlpMessage) error {
+	var body []byte
+	var err error
+	if e.encoding == "json" {
+		body, err = request.MarshalJSON()
+	} else {
+		body, err = request.MarshalProto()
+	}
+	if err != nil {
+		return fmt.Errorf("encoding request failed: %w", err)
+	}
+	if e.encoder != nil {
+		if body, err = e.encoder.Encode(body); err != nil {
+			return fmt.Errorf("compressing request failed: %w", err)
+		}
+	}
+
+	endpoint := e.baseURL + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", e.contentType)
+	req.Header.Set("User-Agent", userAgent)
+	if e.encoder != nil {
+		req.Header.Set("Content-Encoding", e.compression)
+	}
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request to %q failed: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errorLine string
+		scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxErrMsgLen))
+		if scanner.Scan() {
+			errorLine = scanner.Text()
+		}
+		return fmt.Errorf("when writing to %q received status code %d: %s", endpoint, resp.StatusCode, errorLine)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("reading response from %q failed: %w", endpoint, err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	// The server responds with the encoding of the request
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		err = response.UnmarshalJSON(data)
+	} else {
+		err = response.UnmarshalProto(data)
+	}
+	if err != nil {
+		return fmt.Errorf("decoding response from %q failed: %w", endpoint, err)
+	}
+	return nil
+}
+
+func (e *httpExporter) close() {
+	e.client.CloseIdleConnections()
+}
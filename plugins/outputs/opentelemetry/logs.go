@@ -0,0 +1,89 @@
+package opentelemetry
+
+import (
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Mapping of common severity names, including the syslog short levels, to
+// OpenTelemetry severity numbers
+var severityNumbers = map[string]plog.SeverityNumber{
+	"trace":    plog.SeverityNumberTrace,
+	"debug":    plog.SeverityNumberDebug,
+	"info":     plog.SeverityNumberInfo,
+	"notice":   plog.SeverityNumberInfo2,
+	"warn":     plog.SeverityNumberWarn,
+	"warning":  plog.SeverityNumberWarn,
+	"err":      plog.SeverityNumberError,
+	"error":    plog.SeverityNumberError,
+	"crit":     plog.SeverityNumberFatal,
+	"critical": plog.SeverityNumberFatal,
+	"fatal":    plog.SeverityNumberFatal,
+	"alert":    plog.SeverityNumberFatal3,
+	"emerg":    plog.SeverityNumberFatal4,
+}
+
+// convertLogs converts the given metrics to OpenTelemetry log records. The
+// metric name is used as instrumentation scope, the body is taken from the
+// configured field and all other tags and fields become attributes.
+func (o *OpenTelemetry) convertLogs(metrics []telegraf.Metric) plog.Logs {
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	for k, v := range o.Attributes {
+		rl.Resource().Attributes().PutStr(k, v)
+	}
+
+	scopes := make(map[string]plog.ScopeLogs)
+	observed := pcommon.NewTimestampFromTime(time.Now())
+	for _, m := range metrics {
+		scope, found := scopes[m.Name()]
+		if !found {
+			scope = rl.ScopeLogs().AppendEmpty()
+			scope.Scope().SetName(m.Name())
+			scopes[m.Name()] = scope
+		}
+
+		record := scope.LogRecords().AppendEmpty()
+		record.SetTimestamp(pcommon.NewTimestampFromTime(m.Time()))
+		record.SetObservedTimestamp(observed)
+
+		for _, tag := range m.TagList() {
+			if tag.Key == o.LogSeverityTag {
+				record.SetSeverityText(tag.Value)
+				record.SetSeverityNumber(severityNumbers[strings.ToLower(tag.Value)])
+				continue
+			}
+			record.Attributes().PutStr(tag.Key, tag.Value)
+		}
+
+		for _, field := range m.FieldList() {
+			if field.Key == o.LogBodyField {
+				if v, ok := field.Value.(string); ok {
+					record.Body().SetStr(v)
+					continue
+				}
+			}
+			switch v := field.Value.(type) {
+			case string:
+				record.Attributes().PutStr(field.Key, v)
+			case bool:
+				record.Attributes().PutBool(field.Key, v)
+			case int64:
+				record.Attributes().PutInt(field.Key, v)
+			case uint64:
+				record.Attributes().PutInt(field.Key, int64(v))
+			case float64:
+				record.Attributes().PutDouble(field.Key, v)
+			default:
+				o.Log.Debugf("Dropping field %q of log %q with unsupported type %T", field.Key, m.Name(), field.Value)
+			}
+		}
+	}
+
+	return logs
+}
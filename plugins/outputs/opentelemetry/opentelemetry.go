@@ -5,11 +5,15 @@ import (
 	"context"
 	ntls "crypto/tls"
 	_ "embed"
+	"errors"
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/influxdata/influxdb-observability/common"
 	"github.com/influxdata/influxdb-observability/influx2otel"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -19,8 +23,9 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
-	"github.com/influxdata/telegraf/plugins/common/tls"
+	common_http "github.com/influxdata/telegraf/plugins/common/http"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
@@ -31,20 +36,27 @@ var sampleConfig string
 
 type OpenTelemetry struct {
 	ServiceAddress string `toml:"service_address"`
-
-	tls.ClientConfig
-	Timeout     config.Duration   `toml:"timeout"`
-	Compression string            `toml:"compression"`
-	Headers     map[string]string `toml:"headers"`
-	Attributes  map[string]string `toml:"attributes"`
-	Coralogix   *CoralogixConfig  `toml:"coralogix"`
+	Protocol       string `toml:"protocol"`
+	Encoding       string `toml:"encoding"`
+
+	common_http.HTTPClientConfig
+	Compression    string            `toml:"compression"`
+	Logs           []string          `toml:"logs"`
+	LogBodyField   string            `toml:"log_body_field"`
+	LogSeverityTag string            `toml:"log_severity_tag"`
+	Headers        map[string]string `toml:"headers"`
+	Attributes     map[string]string `toml:"attributes"`
+	Coralogix      *CoralogixConfig  `toml:"coralogix"`
 
 	Log telegraf.Logger `toml:"-"`
 
+	logsFilter           filter.Filter
 	metricsConverter     *influx2otel.LineProtocolToOtelMetrics
 	grpcClientConn       *grpc.ClientConn
 	metricsServiceClient pmetricotlp.GRPCClient
+	logsServiceClient    plogotlp.GRPCClient
 	callOptions          []grpc.CallOption
+	httpExporter         *httpExporter
 }
 
 type CoralogixConfig struct {
@@ -57,12 +69,51 @@ func (*OpenTelemetry) SampleConfig() string {
 	return sampleConfig
 }
 
+func (o *OpenTelemetry) Init() error {
+	switch o.Protocol {
+	case "", "grpc":
+		o.Protocol = "grpc"
+		if o.ServiceAddress == "" {
+			o.ServiceAddress = defaultServiceAddress
+		}
+	case "http":
+		if o.ServiceAddress == "" {
+			o.ServiceAddress = defaultHTTPServiceAddress
+		}
+	default:
+		return fmt.Errorf("invalid protocol %q", o.Protocol)
+	}
+
+	switch o.Encoding {
+	case "":
+		o.Encoding = "protobuf"
+	case "protobuf":
+	case "json":
+		if o.Protocol != "http" {
+			return fmt.Errorf("encoding %q is only supported with the http protocol", o.Encoding)
+		}
+	default:
+		return fmt.Errorf("invalid encoding %q", o.Encoding)
+	}
+
+	switch o.Compression {
+	case "", "gzip", "none":
+	default:
+		return fmt.Errorf("invalid compression %q", o.Compression)
+	}
+
+	f, err := filter.Compile(o.Logs)
+	if err != nil {
+		return fmt.Errorf("compiling logs filter failed: %w", err)
+	}
+	o.logsFilter = f
+
+	return nil
+}
+
 func (o *OpenTelemetry) Connect() error {
 	logger := &otelLogger{o.Log}
 
-	if o.ServiceAddress == "" {
-		o.ServiceAddress = defaultServiceAddress
-	}
 	if o.Timeout <= 0 {
 		o.Timeout = defaultTimeout
 	}
@@ -82,6 +133,16 @@ func (o *OpenTelemetry) Connect() error {
 	if err != nil {
 		return err
 	}
+	o.metricsConverter = metricsConverter
+
+	if o.Protocol == "http" {
+		e, err := newHTTPExporter(o)
+		if err != nil {
+			return err
+		}
+		o.httpExporter = e
+		return nil
+	}
 
 	var grpcTLSDialOption grpc.DialOption
 	if tlsConfig, err := o.ClientConfig.TLSConfig(); err != nil {
@@ -100,11 +161,9 @@ func (o *OpenTelemetry) Connect() error {
 		return err
 	}
 
-	metricsServiceClient := pmetricotlp.NewGRPCClient(grpcClientConn)
-
-	o.metricsConverter = metricsConverter
 	o.grpcClientConn = grpcClientConn
-	o.metricsServiceClient = metricsServiceClient
+	o.metricsServiceClient = pmetricotlp.NewGRPCClient(grpcClientConn)
+	o.logsServiceClient = plogotlp.NewGRPCClient(grpcClientConn)
 
 	if o.Compression != "" && o.Compression != "none" {
 		o.callOptions = append(o.callOptions, grpc.UseCompressor(o.Compression))
@@ -114,6 +173,10 @@ func (o *OpenTelemetry) Connect() error {
 }
 
 func (o *OpenTelemetry) Close() error {
+	if o.httpExporter != nil {
+		o.httpExporter.close()
+		o.httpExporter = nil
+	}
 	if o.grpcClientConn != nil {
 		err := o.grpcClientConn.Close()
 		o.grpcClientConn = nil
@@ -124,29 +187,88 @@ func (o *OpenTelemetry) Close() error {
 
 // Split metrics up by timestamp and send to Google Cloud Stackdriver
 func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
-	metricBatch := make(map[int64][]telegraf.Metric)
+	// Send metrics originating from logs as log records and the remaining
+	// metrics grouped by timestamp. Accept the metrics of all successful
+	// requests to not send them again if other requests fail.
+	var wErr internal.PartialWriteError
+	var errs []error
+
+	logs := make([]int, 0, len(metrics))
+	metricBatch := make(map[int64][]int)
 	timestamps := make([]int64, 0, len(metrics))
-	for _, metric := range metrics {
+	for i, metric := range metrics {
+		if o.logsFilter != nil && o.logsFilter.Match(metric.Name()) {
+			logs = append(logs, i)
+			continue
+		}
 		timestamp := metric.Time().UnixNano()
 		if existingSlice, ok := metricBatch[timestamp]; ok {
-			metricBatch[timestamp] = append(existingSlice, metric)
+			metricBatch[timestamp] = append(existingSlice, i)
 		} else {
-			metricBatch[timestamp] = []telegraf.Metric{metric}
+			metricBatch[timestamp] = []int{i}
 			timestamps = append(timestamps, timestamp)
 		}
 	}
 
+	if len(logs) > 0 {
+		if err := o.sendLogs(selectMetrics(metrics, logs)); err != nil {
+			errs = append(errs, err)
+		} else {
+			wErr.MetricsAccept = append(wErr.MetricsAccept, logs...)
+		}
+	}
+
 	// sort the timestamps we collected
 	sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
 
-	o.Log.Debugf("Received %d metrics and split into %d groups by timestamp", len(metrics), len(metricBatch))
+	o.Log.Debugf("Received %d metrics and split into %d groups by timestamp", len(metrics)-len(logs), len(metricBatch))
 	for _, timestamp := range timestamps {
-		if err := o.sendBatch(metricBatch[timestamp]); err != nil {
-			return err
+		indices := metricBatch[timestamp]
+		if err := o.sendBatch(selectMetrics(metrics, indices)); err != nil {
+			errs = append(errs, err)
+			break
 		}
+		wErr.MetricsAccept = append(wErr.MetricsAccept, indices...)
 	}
 
-	return nil
+	if len(errs) == 0 {
+		return nil
+	}
+	wErr.Err = errors.Join(errs...)
+	return &wErr
+}
+
+func selectMetrics(metrics []telegraf.Metric, indices []int) []telegraf.Metric {
+	selected := make([]telegraf.Metric, 0, len(indices))
+	for _, idx := range indices {
+		selected = append(selected, metrics[idx])
+	}
+	return selected
+}
+
+// The converter only accepts histograms in the layout of the Prometheus parser
+// with metric version 2 if the measurement is named "prometheus". Convert other
+// histograms in this layout, e.g. emitted by the sketch aggregator, by
+// prefixing the fields with the measurement name as done by the Prometheus
+// serializer.
+func prometheusHistogram(metric telegraf.Metric) (string, map[string]interface{}) {
+	fields := metric.Fields()
+	if metric.Type() != telegraf.Histogram || metric.Name() == common.MeasurementPrometheus {
+		return metric.Name(), fields
+	}
+	if _, found := metric.GetTag("le"); !found {
+		for k := range fields {
+			if !strings.HasSuffix(k, "_count") && !strings.HasSuffix(k, "_sum") {
+				return metric.Name(), fields
+			}
+		}
+	}
+
+	prefixed := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		prefixed[metric.Name()+"_"+k] = v
+	}
+	return common.MeasurementPrometheus, prefixed
 }
 
 func (o *OpenTelemetry) sendBatch(metrics []telegraf.Metric) error {
@@ -168,7 +290,8 @@ func (o *OpenTelemetry) sendBatch(metrics []telegraf.Metric) error {
 			o.Log.Warnf("Unrecognized metric type %v", metric.Type())
 			continue
 		}
-		err := batch.AddPoint(metric.Name(), metric.Tags(), metric.Fields(), metric.Time(), vType)
+		name, fields := prometheusHistogram(metric)
+		err := batch.AddPoint(name, metric.Tags(), fields, metric.Time(), vType)
 		if err != nil {
 			o.Log.Warnf("Failed to add point: %v", err)
 			continue
@@ -189,27 +312,78 @@ func (o *OpenTelemetry) sendBatch(metrics []telegraf.Metric) error {
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.Timeout))
+	defer cancel()
+
+	resp := pmetricotlp.NewExportResponse()
+	if o.httpExporter != nil {
+		if err := o.httpExporter.export(ctx, "/v1/metrics", md, resp); err != nil {
+			return err
+		}
+	} else {
+		if len(o.Headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.Headers))
+		}
+		r, err := o.metricsServiceClient.Export(ctx, md, o.callOptions...)
+		if err != nil {
+			return err
+		}
+		resp = r
+	}
 
-	if len(o.Headers) > 0 {
-		ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.Headers))
+	if ps := resp.PartialSuccess(); ps.RejectedDataPoints() > 0 || ps.ErrorMessage() != "" {
+		o.Log.Warnf("Receiver rejected %d data points: %s", ps.RejectedDataPoints(), ps.ErrorMessage())
 	}
+	return nil
+}
+
+func (o *OpenTelemetry) sendLogs(metrics []telegraf.Metric) error {
+	req := plogotlp.NewExportRequestFromLogs(o.convertLogs(metrics))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.Timeout))
 	defer cancel()
-	_, err := o.metricsServiceClient.Export(ctx, md, o.callOptions...)
-	return err
+
+	resp := plogotlp.NewExportResponse()
+	if o.httpExporter != nil {
+		if err := o.httpExporter.export(ctx, "/v1/logs", req, resp); err != nil {
+			return err
+		}
+	} else {
+		if len(o.Headers) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(o.Headers))
+		}
+		r, err := o.logsServiceClient.Export(ctx, req, o.callOptions...)
+		if err != nil {
+			return err
+		}
+		resp = r
+	}
+
+	if ps := resp.PartialSuccess(); ps.RejectedLogRecords() > 0 || ps.ErrorMessage() != "" {
+		o.Log.Warnf("Receiver rejected %d log records: %s", ps.RejectedLogRecords(), ps.ErrorMessage())
+	}
+	return nil
 }
 
 const (
-	defaultServiceAddress = "localhost:4317"
-	defaultTimeout        = config.Duration(5 * time.Second)
-	defaultCompression    = "gzip"
+	defaultServiceAddress     = "localhost:4317"
+	defaultHTTPServiceAddress = "http://localhost:4318"
+	defaultTimeout            = config.Duration(5 * time.Second)
+	defaultCompression        = "gzip"
+	defaultLogBodyField       = "message"
+	defaultLogSeverityTag     = "severity"
 )
 
 func init() {
 	outputs.Add("opentelemetry", func() telegraf.Output {
 		return &OpenTelemetry{
-			ServiceAddress: defaultServiceAddress,
-			Timeout:        defaultTimeout,
+			Protocol:       "grpc",
+			Encoding:       "protobuf",
 			Compression:    defaultCompression,
+			LogBodyField:   defaultLogBodyField,
+			LogSeverityTag: defaultLogSeverityTag,
+			HTTPClientConfig: common_http.HTTPClientConfig{
+				Timeout: defaultTimeout,
+			},
 		}
 	})
 }
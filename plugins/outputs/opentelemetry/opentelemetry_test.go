@@ -1,8 +1,12 @@
 package opentelemetry
 
 import (
+	"compress/gzip"
 	"context"
+	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -10,6 +14,8 @@ import (
 	"github.com/influxdata/influxdb-observability/influx2otel"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
 	"google.golang.org/grpc"
@@ -19,6 +25,9 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	common_http "github.com/influxdata/telegraf/plugins/common/http"
 	"github.com/influxdata/telegraf/testutil"
 )
 
@@ -45,7 +54,7 @@ func TestOpenTelemetry(t *testing.T) {
 	require.NoError(t, err)
 	plugin := &OpenTelemetry{
 		ServiceAddress:       m.Address(),
-		Timeout:              config.Duration(time.Second),
+		HTTPClientConfig:     common_http.HTTPClientConfig{Timeout: config.Duration(time.Second)},
 		Headers:              map[string]string{"test": "header1"},
 		Attributes:           map[string]string{"attr-key": "attr-val"},
 		metricsConverter:     metricsConverter,
@@ -81,6 +90,368 @@ func TestOpenTelemetry(t *testing.T) {
 	require.JSONEq(t, string(expectJSON), string(gotJSON))
 }
 
+func TestHistogramPrometheusLayout(t *testing.T) {
+	m := newMockOtelService(t)
+	t.Cleanup(m.Cleanup)
+
+	metricsConverter, err := influx2otel.NewLineProtocolToOtelMetrics(common.NoopLogger{})
+	require.NoError(t, err)
+	plugin := &OpenTelemetry{
+		ServiceAddress:       m.Address(),
+		HTTPClientConfig:     common_http.HTTPClientConfig{Timeout: config.Duration(time.Second)},
+		Headers:              map[string]string{"test": "header1"},
+		metricsConverter:     metricsConverter,
+		grpcClientConn:       m.GrpcClient(),
+		metricsServiceClient: pmetricotlp.NewGRPCClient(m.GrpcClient()),
+		Log:                  testutil.Logger{},
+	}
+
+	// Histogram in the layout of the Prometheus parser with metric version 2
+	// but with a different measurement name
+	ts := time.Unix(0, 1622848686000000000)
+	metrics := []telegraf.Metric{
+		metric.New("http", map[string]string{"host": "a"}, map[string]interface{}{"latency_count": 4.0, "latency_sum": 7.5}, ts, telegraf.Histogram),
+		metric.New("http", map[string]string{"host": "a", "le": "0"}, map[string]interface{}{"latency_bucket": 1.0}, ts, telegraf.Histogram),
+		metric.New("http", map[string]string{"host": "a", "le": "2"}, map[string]interface{}{"latency_bucket": 2.0}, ts, telegraf.Histogram),
+		metric.New("http", map[string]string{"host": "a", "le": "4"}, map[string]interface{}{"latency_bucket": 4.0}, ts, telegraf.Histogram),
+		metric.New("http", map[string]string{"host": "a", "le": "+Inf"}, map[string]interface{}{"latency_bucket": 4.0}, ts, telegraf.Histogram),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	got := m.GotMetrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 1, got.Len())
+	require.Equal(t, "http_latency", got.At(0).Name())
+	require.Equal(t, pmetric.MetricTypeHistogram, got.At(0).Type())
+	require.Equal(t, 1, got.At(0).Histogram().DataPoints().Len())
+
+	dp := got.At(0).Histogram().DataPoints().At(0)
+	require.Equal(t, map[string]interface{}{"host": "a"}, dp.Attributes().AsRaw())
+	require.Equal(t, uint64(4), dp.Count())
+	require.InDelta(t, 7.5, dp.Sum(), 1e-9)
+	require.Equal(t, []float64{0, 2, 4}, dp.ExplicitBounds().AsRaw())
+	require.Equal(t, []uint64{1, 1, 2, 0}, dp.BucketCounts().AsRaw())
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *OpenTelemetry
+		expected string
+	}{
+		{
+			name:     "invalid protocol",
+			plugin:   &OpenTelemetry{Protocol: "udp"},
+			expected: `invalid protocol "udp"`,
+		},
+		{
+			name:     "invalid encoding",
+			plugin:   &OpenTelemetry{Protocol: "http", Encoding: "xml"},
+			expected: `invalid encoding "xml"`,
+		},
+		{
+			name:     "json with grpc",
+			plugin:   &OpenTelemetry{Protocol: "grpc", Encoding: "json"},
+			expected: `encoding "json" is only supported with the http protocol`,
+		},
+		{
+			name:     "invalid compression",
+			plugin:   &OpenTelemetry{Compression: "zstd"},
+			expected: `invalid compression "zstd"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestLogs(t *testing.T) {
+	m := newMockOtelService(t)
+	t.Cleanup(m.Cleanup)
+
+	plugin := &OpenTelemetry{
+		HTTPClientConfig:  common_http.HTTPClientConfig{Timeout: config.Duration(time.Second)},
+		Compression:       "none",
+		Logs:              []string{"syslog"},
+		LogBodyField:      defaultLogBodyField,
+		LogSeverityTag:    defaultLogSeverityTag,
+		Headers:           map[string]string{"test": "header1"},
+		Attributes:        map[string]string{"service.name": "demo"},
+		logsServiceClient: plogotlp.NewGRPCClient(m.GrpcClient()),
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	input := testutil.MustMetric(
+		"syslog",
+		map[string]string{
+			"appname":  "sshd",
+			"severity": "err",
+		},
+		map[string]interface{}{
+			"message":       "connection refused",
+			"severity_code": 3,
+		},
+		time.Unix(0, 1622848686000000000),
+	)
+	require.NoError(t, plugin.Write([]telegraf.Metric{input}))
+
+	logs := m.GotLogs()
+	require.Equal(t, 1, logs.LogRecordCount())
+	rl := logs.ResourceLogs().At(0)
+	serviceName, found := rl.Resource().Attributes().Get("service.name")
+	require.True(t, found)
+	require.Equal(t, "demo", serviceName.Str())
+
+	scope := rl.ScopeLogs().At(0)
+	require.Equal(t, "syslog", scope.Scope().Name())
+
+	record := scope.LogRecords().At(0)
+	require.Equal(t, pcommon.Timestamp(1622848686000000000), record.Timestamp())
+	require.Equal(t, "connection refused", record.Body().Str())
+	require.Equal(t, "err", record.SeverityText())
+	require.Equal(t, plog.SeverityNumberError, record.SeverityNumber())
+	require.Equal(t, map[string]interface{}{
+		"appname":       "sshd",
+		"severity_code": int64(3),
+	}, record.Attributes().AsRaw())
+}
+
+func TestHTTP(t *testing.T) {
+	for _, encoding := range []string{"protobuf", "json"} {
+		t.Run(encoding, func(t *testing.T) {
+			var gotMetrics pmetricotlp.ExportRequest
+			var gotLogs plogotlp.ExportRequest
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("test") != "header1" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if r.Header.Get("Content-Encoding") != "gzip" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				reader, err := gzip.NewReader(r.Body)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				body, err := io.ReadAll(reader)
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				var request otlpMessage
+				var response otlpMessage
+				switch r.URL.Path {
+				case "/v1/metrics":
+					gotMetrics = pmetricotlp.NewExportRequest()
+					request = gotMetrics
+					response = pmetricotlp.NewExportResponse()
+				case "/v1/logs":
+					gotLogs = plogotlp.NewExportRequest()
+					request = gotLogs
+					response = plogotlp.NewExportResponse()
+				default:
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				var data []byte
+				if encoding == "json" {
+					if r.Header.Get("Content-Type") != "application/json" {
+						w.WriteHeader(http.StatusUnsupportedMediaType)
+						return
+					}
+					err = request.UnmarshalJSON(body)
+					if err == nil {
+						data, err = response.MarshalJSON()
+					}
+				} else {
+					if r.Header.Get("Content-Type") != "application/x-protobuf" {
+						w.WriteHeader(http.StatusUnsupportedMediaType)
+						return
+					}
+					err = request.UnmarshalProto(body)
+					if err == nil {
+						data, err = response.MarshalProto()
+					}
+				}
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+				if _, err := w.Write(data); err != nil {
+					t.Error(err)
+				}
+			}))
+			defer ts.Close()
+
+			plugin := &OpenTelemetry{
+				ServiceAddress:   ts.URL,
+				Protocol:         "http",
+				Encoding:         encoding,
+				HTTPClientConfig: common_http.HTTPClientConfig{Timeout: config.Duration(time.Second)},
+				Compression:      "gzip",
+				Logs:             []string{"docker_log"},
+				LogBodyField:     defaultLogBodyField,
+				LogSeverityTag:   defaultLogSeverityTag,
+				Headers:          map[string]string{"test": "header1"},
+				Log:              testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			metrics := []telegraf.Metric{
+				testutil.MustMetric(
+					"cpu_temp",
+					map[string]string{"foo": "bar"},
+					map[string]interface{}{"gauge": 87.332},
+					time.Unix(0, 1622848686000000000),
+				),
+				testutil.MustMetric(
+					"docker_log",
+					map[string]string{"container_name": "app", "stream": "stderr"},
+					map[string]interface{}{"message": "panic", "container_id": "abc"},
+					time.Unix(0, 1622848686000000000),
+				),
+			}
+			require.NoError(t, plugin.Write(metrics))
+
+			require.Equal(t, 1, gotMetrics.Metrics().DataPointCount())
+			require.Equal(t, "cpu_temp", gotMetrics.Metrics().ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Name())
+
+			require.Equal(t, 1, gotLogs.Logs().LogRecordCount())
+			record := gotLogs.Logs().ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			require.Equal(t, "panic", record.Body().Str())
+			require.Equal(t, map[string]interface{}{
+				"container_name": "app",
+				"stream":         "stderr",
+				"container_id":   "abc",
+			}, record.Attributes().AsRaw())
+		})
+	}
+}
+
+func TestHTTPPartialSuccess(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		response := pmetricotlp.NewExportResponse()
+		response.PartialSuccess().SetRejectedDataPoints(1)
+		response.PartialSuccess().SetErrorMessage("invalid data point")
+		data, err := response.MarshalProto()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		if _, err := w.Write(data); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	logger := &testutil.CaptureLogger{}
+	plugin := &OpenTelemetry{
+		ServiceAddress:   ts.URL,
+		Protocol:         "http",
+		HTTPClientConfig: common_http.HTTPClientConfig{Timeout: config.Duration(time.Second)},
+		Compression:      "none",
+		Log:              logger,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := testutil.MustMetric(
+		"cpu_temp",
+		map[string]string{},
+		map[string]interface{}{"gauge": 87.332},
+		time.Unix(0, 1622848686000000000),
+	)
+	require.NoError(t, plugin.Write([]telegraf.Metric{input}))
+	warnings := logger.Warnings()
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0], "Receiver rejected 1 data points: invalid data point")
+}
+
+func TestHTTPErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if _, err := w.Write([]byte("overloaded")); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	plugin := &OpenTelemetry{
+		ServiceAddress:   ts.URL,
+		Protocol:         "http",
+		HTTPClientConfig: common_http.HTTPClientConfig{Timeout: config.Duration(time.Second)},
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	input := testutil.MustMetric(
+		"cpu_temp",
+		map[string]string{},
+		map[string]interface{}{"gauge": 87.332},
+		time.Unix(0, 1622848686000000000),
+	)
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{input}), "received status code 503: overloaded")
+}
+
+func TestHTTPPartialWrite(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/metrics" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	plugin := &OpenTelemetry{
+		ServiceAddress:   ts.URL,
+		Protocol:         "http",
+		HTTPClientConfig: common_http.HTTPClientConfig{Timeout: config.Duration(time.Second)},
+		Logs:             []string{"docker_log"},
+		LogBodyField:     defaultLogBodyField,
+		LogSeverityTag:   defaultLogSeverityTag,
+		Log:              testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu_temp",
+			map[string]string{},
+			map[string]interface{}{"gauge": 87.332},
+			time.Unix(0, 1622848686000000000),
+		),
+		testutil.MustMetric(
+			"docker_log",
+			map[string]string{"container_name": "app"},
+			map[string]interface{}{"message": "panic"},
+			time.Unix(0, 1622848686000000000),
+		),
+	}
+
+	// The logs were sent successfully and must not be sent again
+	err := plugin.Write(metrics)
+	require.ErrorContains(t, err, "received status code 503")
+	var wErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &wErr)
+	require.Equal(t, []int{1}, wErr.MetricsAccept)
+	require.Empty(t, wErr.MetricsReject)
+}
+
 var _ pmetricotlp.GRPCServer = (*mockOtelService)(nil)
 
 type mockOtelService struct {
@@ -91,6 +462,7 @@ type mockOtelService struct {
 	grpcClient *grpc.ClientConn
 
 	metrics pmetric.Metrics
+	logs    plog.Logs
 }
 
 func newMockOtelService(t *testing.T) *mockOtelService {
@@ -105,6 +477,7 @@ func newMockOtelService(t *testing.T) *mockOtelService {
 	}
 
 	pmetricotlp.RegisterGRPCServer(grpcServer, mockOtelService)
+	plogotlp.RegisterGRPCServer(grpcServer, &mockLogsService{mockOtelService: mockOtelService})
 	go func() {
 		if err := grpcServer.Serve(listener); err != nil {
 			t.Error(err)
@@ -144,3 +517,25 @@ func (m *mockOtelService) Export(ctx context.Context, request pmetricotlp.Export
 	require.True(m.t, ok)
 	return pmetricotlp.NewExportResponse(), nil
 }
+
+// mockLogsService implements the logs service for the mock as both services
+// use the same method name
+type mockLogsService struct {
+	plogotlp.UnimplementedGRPCServer
+	*mockOtelService
+}
+
+var _ plogotlp.GRPCServer = (*mockLogsService)(nil)
+
+func (m *mockLogsService) Export(ctx context.Context, request plogotlp.ExportRequest) (plogotlp.ExportResponse, error) {
+	m.logs = plog.NewLogs()
+	request.Logs().CopyTo(m.logs)
+	ctxMetadata, ok := metadata.FromIncomingContext(ctx)
+	require.Equal(m.t, []string{"header1"}, ctxMetadata.Get("test"))
+	require.True(m.t, ok)
+	return plogotlp.NewExportResponse(), nil
+}
+
+func (m *mockOtelService) GotLogs() plog.Logs {
+	return m.logs
+}
@@ -2,20 +2,28 @@
 package parquet
 
 import (
+	"bytes"
 	_ "embed"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"text/template"
 	"time"
 
 	"github.com/apache/arrow/go/v18/arrow"
 	"github.com/apache/arrow/go/v18/arrow/array"
 	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/apache/arrow/go/v18/arrow/util"
 	"github.com/apache/arrow/go/v18/parquet"
+	"github.com/apache/arrow/go/v18/parquet/compress"
 	"github.com/apache/arrow/go/v18/parquet/pqarrow"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/outputs"
 )
 
@@ -24,19 +32,58 @@ var sampleConfig string
 
 var defaultTimestampFieldName = "timestamp"
 
+var codecs = map[string]compress.Compression{
+	"none":   compress.Codecs.Uncompressed,
+	"snappy": compress.Codecs.Snappy,
+	"gzip":   compress.Codecs.Gzip,
+	"brotli": compress.Codecs.Brotli,
+	"zstd":   compress.Codecs.Zstd,
+}
+
 type metricGroup struct {
 	filename string
+	created  time.Time
+	written  time.Time
 	builder  *array.RecordBuilder
 	schema   *arrow.Schema
+	file     *countingWriter
 	writer   *pqarrow.FileWriter
+
+	// Number of rows and estimated size of the buffered row group
+	rows     int64
+	buffered int64
+}
+
+// countingWriter keeps track of the number of bytes written to the file
+type countingWriter struct {
+	file    *os.File
+	written int64
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.file.Write(b)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *countingWriter) Close() error {
+	return w.file.Close()
 }
 
 type Parquet struct {
 	Directory          string          `toml:"directory"`
+	PathTemplate       string          `toml:"path_template"`
+	Compression        string          `toml:"compression"`
+	RowGroupMaxRows    int64           `toml:"row_group_max_rows"`
+	RowGroupMaxSize    config.Size     `toml:"row_group_max_size"`
 	RotationInterval   config.Duration `toml:"rotation_interval"`
+	RotationMaxSize    config.Size     `toml:"rotation_max_size"`
+	IdleTimeout        config.Duration `toml:"idle_timeout"`
 	TimestampFieldName string          `toml:"timestamp_field_name"`
 	Log                telegraf.Logger `toml:"-"`
 
+	pathTemplate *template.Template
+	properties   *parquet.WriterProperties
 	metricGroups map[string]*metricGroup
 }
 
@@ -58,6 +105,32 @@ func (p *Parquet) Init() error {
 		return fmt.Errorf("provided directory %q is not a directory", p.Directory)
 	}
 
+	if p.PathTemplate != "" {
+		funcs := template.FuncMap{"now": time.Now}
+		tmpl, err := template.New("path").Funcs(funcs).Parse(p.PathTemplate)
+		if err != nil {
+			return fmt.Errorf("parsing path template failed: %w", err)
+		}
+		p.pathTemplate = tmpl
+	}
+
+	if p.Compression == "" {
+		p.Compression = "none"
+	}
+	codec, found := codecs[p.Compression]
+	if !found {
+		return fmt.Errorf("unknown compression %q", p.Compression)
+	}
+	if p.RowGroupMaxRows < 0 {
+		return errors.New("'row_group_max_rows' must not be negative")
+	}
+
+	options := []parquet.WriterProperty{parquet.WithCompression(codec)}
+	if p.RowGroupMaxRows > 0 {
+		options = append(options, parquet.WithMaxRowGroupLength(p.RowGroupMaxRows))
+	}
+	p.properties = parquet.NewWriterProperties(options...)
+
 	p.metricGroups = make(map[string]*metricGroup)
 
 	return nil
@@ -85,74 +158,206 @@ func (p *Parquet) Close() error {
 }
 
 func (p *Parquet) Write(metrics []telegraf.Metric) error {
+	p.closeStale()
+
+	// Group the metrics by directory and measurement
+	var buf bytes.Buffer
+	directories := make(map[string]string)
 	groupedMetrics := make(map[string][]telegraf.Metric)
-	for _, metric := range metrics {
-		groupedMetrics[metric.Name()] = append(groupedMetrics[metric.Name()], metric)
-	}
+	for _, raw := range metrics {
+		m := raw
+		if wm, ok := raw.(telegraf.UnwrappableMetric); ok {
+			m = wm.Unwrap()
+		}
 
-	now := time.Now()
-	for name, metrics := range groupedMetrics {
-		if _, ok := p.metricGroups[name]; !ok {
-			filename := fmt.Sprintf("%s/%s-%s-%s.parquet", p.Directory, name, now.Format("2006-01-02"), strconv.FormatInt(now.Unix(), 10))
-			schema, err := p.createSchema(metrics)
-			if err != nil {
-				return fmt.Errorf("failed to create schema for file %q: %w", name, err)
-			}
-			writer, err := p.createWriter(name, filename, schema)
-			if err != nil {
-				return fmt.Errorf("failed to create writer for file %q: %w", name, err)
-			}
-			p.metricGroups[name] = &metricGroup{
-				builder:  array.NewRecordBuilder(memory.DefaultAllocator, schema),
-				filename: filename,
-				schema:   schema,
-				writer:   writer,
-			}
+		dir, err := p.directory(m, &buf)
+		if err != nil {
+			p.Log.Errorf("Cannot create path for metric %v: %v", m, err)
+			continue
 		}
+		key := filepath.Join(dir, m.Name())
+		directories[key] = dir
+		groupedMetrics[key] = append(groupedMetrics[key], m)
+	}
 
-		if p.RotationInterval != 0 {
-			if err := p.rotateIfNeeded(name); err != nil {
-				return fmt.Errorf("failed to rotate file %q: %w", p.metricGroups[name].filename, err)
-			}
+	for key, metrics := range groupedMetrics {
+		if err := p.writeGroup(key, directories[key], metrics); err != nil {
+			return err
 		}
+	}
 
-		record, err := p.createRecord(metrics, p.metricGroups[name].builder, p.metricGroups[name].schema)
-		if err != nil {
-			return fmt.Errorf("failed to create record for file %q: %w", p.metricGroups[name].filename, err)
+	return nil
+}
+
+// Close the files exceeding the rotation interval or not written for the idle
+// timeout, e.g. of partitions not receiving metrics anymore, so the files are
+// complete without waiting for the next metric of the partition.
+func (p *Parquet) closeStale() {
+	if p.RotationInterval == 0 && p.IdleTimeout == 0 {
+		return
+	}
+
+	for key, group := range p.metricGroups {
+		expired := p.RotationInterval != 0 && time.Since(group.created) >= time.Duration(p.RotationInterval)
+		idle := p.IdleTimeout != 0 && time.Since(group.written) >= time.Duration(p.IdleTimeout)
+		if !expired && !idle {
+			continue
 		}
-		if err = p.metricGroups[name].writer.WriteBuffered(record); err != nil {
-			return fmt.Errorf("failed to write to file %q: %w", p.metricGroups[name].filename, err)
+		if err := p.closeGroup(key); err != nil {
+			p.Log.Errorf("Closing stale file failed: %v", err)
 		}
-		record.Release()
+	}
+}
+
+// Determine the directory of the metric's file making sure the file is
+// located within the configured directory
+func (p *Parquet) directory(m telegraf.Metric, buf *bytes.Buffer) (string, error) {
+	if name := m.Name(); filepath.Base(name) != name || !filepath.IsLocal(name) {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+	if p.pathTemplate == nil {
+		return p.Directory, nil
 	}
 
-	return nil
+	buf.Reset()
+	if err := p.pathTemplate.Execute(buf, m); err != nil {
+		return "", err
+	}
+	sub := filepath.FromSlash(buf.String())
+	if !filepath.IsLocal(sub) {
+		return "", fmt.Errorf("path %q is not a relative path within the directory", buf.String())
+	}
+	return filepath.Join(p.Directory, sub), nil
 }
 
-func (p *Parquet) rotateIfNeeded(name string) error {
-	fileInfo, err := os.Stat(p.metricGroups[name].filename)
+func (p *Parquet) writeGroup(key, dir string, metrics []telegraf.Metric) error {
+	name := metrics[0].Name()
+	schema, err := p.createSchema(metrics)
 	if err != nil {
-		return fmt.Errorf("failed to stat file %q: %w", p.metricGroups[name].filename, err)
+		return fmt.Errorf("failed to create schema for file %q: %w", name, err)
 	}
 
-	expireTime := fileInfo.ModTime().Add(time.Duration(p.RotationInterval))
-	if time.Now().Before(expireTime) {
-		return nil
+	group, found := p.metricGroups[key]
+	if found {
+		// Start a new file if the schema changed as the schema of a file
+		// cannot be modified
+		merged := mergeSchemas(group.schema, schema, p.TimestampFieldName)
+		if !merged.Equal(group.schema) {
+			p.Log.Debugf("Schema changed, rotating file %q", group.filename)
+			if err := p.closeGroup(key); err != nil {
+				return err
+			}
+			found = false
+		}
+		schema = merged
 	}
 
-	if err := p.metricGroups[name].writer.Close(); err != nil {
-		return fmt.Errorf("failed to close file for rotation %q: %w", p.metricGroups[name].filename, err)
+	if found && p.RotationInterval != 0 && time.Since(group.created) >= time.Duration(p.RotationInterval) {
+		if err := p.closeGroup(key); err != nil {
+			return err
+		}
+		found = false
+	}
+
+	if !found {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			return fmt.Errorf("failed to create directory %q: %w", dir, err)
+		}
+		group, err = p.createGroup(dir, name, schema)
+		if err != nil {
+			return fmt.Errorf("failed to create writer for file %q: %w", name, err)
+		}
+		p.metricGroups[key] = group
 	}
 
-	writer, err := p.createWriter(name, p.metricGroups[name].filename, p.metricGroups[name].schema)
+	record, err := p.createRecord(metrics, group.builder, group.schema)
 	if err != nil {
-		return fmt.Errorf("failed to create new writer for file %q: %w", p.metricGroups[name].filename, err)
+		return fmt.Errorf("failed to create record for file %q: %w", group.filename, err)
+	}
+	defer record.Release()
+	if err = group.writer.WriteBuffered(record); err != nil {
+		return fmt.Errorf("failed to write to file %q: %w", group.filename, err)
+	}
+	group.written = time.Now()
+
+	// The writer does not report the size of data buffered in unfinished
+	// pages, so keep track of the uncompressed size of the records instead.
+	// If the writer started a new row group due to the row limit, the
+	// previous row groups are flushed to the file and only the rows of the
+	// record in the new row group remain buffered.
+	size := util.TotalRecordSize(record)
+	rows, err := group.writer.RowGroupNumRows()
+	if err != nil {
+		return fmt.Errorf("failed to determine rows of file %q: %w", group.filename, err)
+	}
+	if int64(rows) == group.rows+record.NumRows() {
+		group.buffered += size
+	} else {
+		group.buffered = size * int64(rows) / record.NumRows()
+	}
+	group.rows = int64(rows)
+
+	// Close the file if it exceeds the size limit, the next write will start
+	// a new file
+	if p.RotationMaxSize > 0 && group.file.written+group.buffered >= int64(p.RotationMaxSize) {
+		return p.closeGroup(key)
+	}
+
+	// Start a new row group if the buffered one exceeds the size limit
+	if p.RowGroupMaxSize > 0 && group.buffered >= int64(p.RowGroupMaxSize) {
+		group.writer.NewBufferedRowGroup()
+		group.rows = 0
+		group.buffered = 0
 	}
-	p.metricGroups[name].writer = writer
 
 	return nil
 }
 
+func (p *Parquet) createGroup(dir, name string, schema *arrow.Schema) (*metricGroup, error) {
+	// Use a new file name if the file already exists to avoid overwriting
+	// existing data
+	now := time.Now()
+	base := fmt.Sprintf("%s-%s-%s", name, now.Format("2006-01-02"), strconv.FormatInt(now.Unix(), 10))
+	filename := filepath.Join(dir, base+".parquet")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			break
+		}
+		filename = filepath.Join(dir, fmt.Sprintf("%s-%d.parquet", base, i))
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %q: %w", filename, err)
+	}
+	file := &countingWriter{file: f}
+
+	writer, err := pqarrow.NewFileWriter(schema, file, p.properties, pqarrow.DefaultWriterProps())
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create parquet writer for file %q: %w", filename, err)
+	}
+
+	return &metricGroup{
+		filename: filename,
+		created:  now,
+		builder:  array.NewRecordBuilder(memory.DefaultAllocator, schema),
+		schema:   schema,
+		file:     file,
+		writer:   writer,
+	}, nil
+}
+
+func (p *Parquet) closeGroup(key string) error {
+	group := p.metricGroups[key]
+	delete(p.metricGroups, key)
+	group.builder.Release()
+	if err := group.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close file %q: %w", group.filename, err)
+	}
+	return nil
+}
+
 func (p *Parquet) createRecord(metrics []telegraf.Metric, builder *array.RecordBuilder, schema *arrow.Schema) (arrow.Record, error) {
 	for index, col := range schema.Fields() {
 		for _, m := range metrics {
@@ -171,65 +376,15 @@ func (p *Parquet) createRecord(metrics []telegraf.Metric, builder *array.RecordB
 
 			// if neither field nor tag exists, append a null value
 			if !ok {
-				switch col.Type {
-				case arrow.PrimitiveTypes.Int8:
-					builder.Field(index).(*array.Int8Builder).AppendNull()
-				case arrow.PrimitiveTypes.Int16:
-					builder.Field(index).(*array.Int16Builder).AppendNull()
-				case arrow.PrimitiveTypes.Int32:
-					builder.Field(index).(*array.Int32Builder).AppendNull()
-				case arrow.PrimitiveTypes.Int64:
-					builder.Field(index).(*array.Int64Builder).AppendNull()
-				case arrow.PrimitiveTypes.Uint8:
-					builder.Field(index).(*array.Uint8Builder).AppendNull()
-				case arrow.PrimitiveTypes.Uint16:
-					builder.Field(index).(*array.Uint16Builder).AppendNull()
-				case arrow.PrimitiveTypes.Uint32:
-					builder.Field(index).(*array.Uint32Builder).AppendNull()
-				case arrow.PrimitiveTypes.Uint64:
-					builder.Field(index).(*array.Uint64Builder).AppendNull()
-				case arrow.PrimitiveTypes.Float32:
-					builder.Field(index).(*array.Float32Builder).AppendNull()
-				case arrow.PrimitiveTypes.Float64:
-					builder.Field(index).(*array.Float64Builder).AppendNull()
-				case arrow.BinaryTypes.String:
-					builder.Field(index).(*array.StringBuilder).AppendNull()
-				case arrow.FixedWidthTypes.Boolean:
-					builder.Field(index).(*array.BooleanBuilder).AppendNull()
-				default:
-					return nil, fmt.Errorf("unsupported type: %T", value)
-				}
-
+				builder.Field(index).AppendNull()
 				continue
 			}
 
-			switch col.Type {
-			case arrow.PrimitiveTypes.Int8:
-				builder.Field(index).(*array.Int8Builder).Append(value.(int8))
-			case arrow.PrimitiveTypes.Int16:
-				builder.Field(index).(*array.Int16Builder).Append(value.(int16))
-			case arrow.PrimitiveTypes.Int32:
-				builder.Field(index).(*array.Int32Builder).Append(value.(int32))
-			case arrow.PrimitiveTypes.Int64:
-				builder.Field(index).(*array.Int64Builder).Append(value.(int64))
-			case arrow.PrimitiveTypes.Uint8:
-				builder.Field(index).(*array.Uint8Builder).Append(value.(uint8))
-			case arrow.PrimitiveTypes.Uint16:
-				builder.Field(index).(*array.Uint16Builder).Append(value.(uint16))
-			case arrow.PrimitiveTypes.Uint32:
-				builder.Field(index).(*array.Uint32Builder).Append(value.(uint32))
-			case arrow.PrimitiveTypes.Uint64:
-				builder.Field(index).(*array.Uint64Builder).Append(value.(uint64))
-			case arrow.PrimitiveTypes.Float32:
-				builder.Field(index).(*array.Float32Builder).Append(value.(float32))
-			case arrow.PrimitiveTypes.Float64:
-				builder.Field(index).(*array.Float64Builder).Append(value.(float64))
-			case arrow.BinaryTypes.String:
-				builder.Field(index).(*array.StringBuilder).Append(value.(string))
-			case arrow.FixedWidthTypes.Boolean:
-				builder.Field(index).(*array.BooleanBuilder).Append(value.(bool))
-			default:
-				return nil, fmt.Errorf("unsupported type: %T", value)
+			// Convert the value to the column type as the type might have
+			// been widened and append a null value if this is not possible
+			if err := appendValue(builder.Field(index), value); err != nil {
+				p.Log.Debugf("Cannot convert value of %q to %s: %v", col.Name, col.Type, err)
+				builder.Field(index).AppendNull()
 			}
 		}
 	}
@@ -238,17 +393,98 @@ func (p *Parquet) createRecord(metrics []telegraf.Metric, builder *array.RecordB
 	return record, nil
 }
 
+func appendValue(builder array.Builder, value interface{}) error {
+	switch b := builder.(type) {
+	case *array.Int8Builder:
+		v, err := internal.ToInt8(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int16Builder:
+		v, err := internal.ToInt16(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int32Builder:
+		v, err := internal.ToInt32(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Int64Builder:
+		v, err := internal.ToInt64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint8Builder:
+		v, err := internal.ToUint8(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint16Builder:
+		v, err := internal.ToUint16(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint32Builder:
+		v, err := internal.ToUint32(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Uint64Builder:
+		v, err := internal.ToUint64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float32Builder:
+		v, err := internal.ToFloat32(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float64Builder:
+		v, err := internal.ToFloat64(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.StringBuilder:
+		v, err := internal.ToString(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.BooleanBuilder:
+		v, err := internal.ToBool(value)
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	default:
+		return fmt.Errorf("unsupported builder type %T", builder)
+	}
+	return nil
+}
+
 func (p *Parquet) createSchema(metrics []telegraf.Metric) (*arrow.Schema, error) {
 	rawFields := make(map[string]arrow.DataType, 0)
 	for _, metric := range metrics {
 		for _, field := range metric.FieldList() {
-			if _, ok := rawFields[field.Key]; !ok {
-				arrowType, err := goToArrowType(field.Value)
-				if err != nil {
-					return nil, fmt.Errorf("error converting '%s=%s' field to arrow type: %w", field.Key, field.Value, err)
-				}
-				rawFields[field.Key] = arrowType
+			arrowType, err := goToArrowType(field.Value)
+			if err != nil {
+				return nil, fmt.Errorf("error converting '%s=%s' field to arrow type: %w", field.Key, field.Value, err)
+			}
+			if existing, ok := rawFields[field.Key]; ok {
+				arrowType = widenType(existing, arrowType)
 			}
+			rawFields[field.Key] = arrowType
 		}
 		for _, tag := range metric.TagList() {
 			if _, ok := rawFields[tag.Key]; !ok {
@@ -257,11 +493,18 @@ func (p *Parquet) createSchema(metrics []telegraf.Metric) (*arrow.Schema, error)
 		}
 	}
 
-	fields := make([]arrow.Field, 0)
-	for key, value := range rawFields {
+	keys := make([]string, 0, len(rawFields))
+	for key := range rawFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]arrow.Field, 0, len(keys)+1)
+	for _, key := range keys {
 		fields = append(fields, arrow.Field{
-			Name: key,
-			Type: value,
+			Name:     key,
+			Type:     rawFields[key],
+			Nullable: true,
 		})
 	}
 
@@ -275,25 +518,52 @@ func (p *Parquet) createSchema(metrics []telegraf.Metric) (*arrow.Schema, error)
 	return arrow.NewSchema(fields, nil), nil
 }
 
-func (p *Parquet) createWriter(name, filename string, schema *arrow.Schema) (*pqarrow.FileWriter, error) {
-	if _, err := os.Stat(filename); err == nil {
-		now := time.Now()
-		rotatedFilename := fmt.Sprintf("%s/%s-%s-%s.parquet", p.Directory, name, now.Format("2006-01-02"), strconv.FormatInt(now.Unix(), 10))
-		if err := os.Rename(filename, rotatedFilename); err != nil {
-			return nil, fmt.Errorf("failed to rename file %q: %w", filename, err)
+// Merge the schema of new metrics into an existing schema by widening the
+// types of existing columns and appending new columns before the timestamp
+func mergeSchemas(existing, added *arrow.Schema, timestamp string) *arrow.Schema {
+	fields := make([]arrow.Field, 0, existing.NumFields()+added.NumFields())
+	seen := make(map[string]bool, existing.NumFields())
+	var ts *arrow.Field
+	for _, f := range existing.Fields() {
+		seen[f.Name] = true
+		if timestamp != "" && f.Name == timestamp {
+			ts = &f
+			continue
 		}
+		if indices := added.FieldIndices(f.Name); len(indices) > 0 {
+			f.Type = widenType(f.Type, added.Field(indices[0]).Type)
+		}
+		fields = append(fields, f)
 	}
-	file, err := os.Create(filename)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create file %q: %w", filename, err)
+	for _, f := range added.Fields() {
+		if !seen[f.Name] {
+			fields = append(fields, f)
+		}
 	}
+	if ts != nil {
+		fields = append(fields, *ts)
+	}
+	return arrow.NewSchema(fields, nil)
+}
 
-	writer, err := pqarrow.NewFileWriter(schema, file, parquet.NewWriterProperties(), pqarrow.DefaultWriterProps())
-	if err != nil {
-		return nil, fmt.Errorf("failed to create parquet writer for file %q: %w", filename, err)
+// Determine a type able to represent values of both types
+func widenType(a, b arrow.DataType) arrow.DataType {
+	switch {
+	case arrow.TypeEqual(a, b):
+		return a
+	case arrow.IsInteger(a.ID()) && arrow.IsInteger(b.ID()):
+		if arrow.IsUnsignedInteger(a.ID()) && arrow.IsUnsignedInteger(b.ID()) {
+			return arrow.PrimitiveTypes.Uint64
+		}
+		return arrow.PrimitiveTypes.Int64
+	case isNumeric(a) && isNumeric(b):
+		return arrow.PrimitiveTypes.Float64
 	}
+	return arrow.BinaryTypes.String
+}
 
-	return writer, nil
+func isNumeric(t arrow.DataType) bool {
+	return arrow.IsInteger(t.ID()) || arrow.IsFloating(t.ID())
 }
 
 func goToArrowType(value interface{}) (arrow.DataType, error) {
@@ -327,6 +597,8 @@ func goToArrowType(value interface{}) (arrow.DataType, error) {
 	}
 }
 
+var _ io.WriteCloser = &countingWriter{}
+
 func init() {
 	outputs.Add("parquet", func() telegraf.Output {
 		return &Parquet{
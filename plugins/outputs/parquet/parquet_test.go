@@ -1,12 +1,18 @@
 package parquet
 
 import (
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/memory"
 	"github.com/apache/arrow/go/v18/parquet/file"
+	"github.com/apache/arrow/go/v18/parquet/pqarrow"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/testutil"
@@ -244,3 +250,329 @@ func TestTimestampDifferentName(t *testing.T) {
 	require.Equal(t, 1, int(metadata.NumRows))
 	require.Equal(t, 2, metadata.Schema.NumColumns())
 }
+
+func TestInitFail(t *testing.T) {
+	plugin := &Parquet{Directory: t.TempDir(), Compression: "lzo"}
+	require.ErrorContains(t, plugin.Init(), `unknown compression "lzo"`)
+
+	plugin = &Parquet{Directory: t.TempDir(), PathTemplate: "{{.Name"}
+	require.ErrorContains(t, plugin.Init(), "parsing path template failed")
+}
+
+func TestPathTemplate(t *testing.T) {
+	ts := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, ts),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, ts),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 3.0}, ts),
+		testutil.MustMetric("mem", map[string]string{"host": "a"}, map[string]interface{}{"used": 4.0}, ts),
+	}
+
+	testDir := t.TempDir()
+	plugin := &Parquet{
+		Directory:          testDir,
+		PathTemplate:       `measurement={{.Name}}/date={{.Time.Format "2006-01-02"}}/host={{.Tag "host"}}`,
+		TimestampFieldName: defaultTimestampFieldName,
+		Log:                &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+
+	expected := map[string]int64{
+		"measurement=cpu/date=2026-10-16/host=a": 2,
+		"measurement=cpu/date=2026-10-16/host=b": 1,
+		"measurement=mem/date=2026-10-16/host=a": 1,
+	}
+	for dir, rows := range expected {
+		files, err := filepath.Glob(filepath.Join(testDir, dir, "*.parquet"))
+		require.NoError(t, err)
+		require.Len(t, files, 1, dir)
+
+		reader, err := file.OpenParquetFile(files[0], false)
+		require.NoError(t, err)
+		require.Equal(t, rows, reader.MetaData().NumRows, dir)
+		reader.Close()
+	}
+}
+
+func TestPathTemplateOutsideDirectory(t *testing.T) {
+	ts := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"dir": "../escape"}, map[string]interface{}{"value": 1.0}, ts),
+		testutil.MustMetric("cpu", map[string]string{"dir": "/absolute"}, map[string]interface{}{"value": 2.0}, ts),
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 3.0}, ts),
+		testutil.MustMetric("../cpu", map[string]string{"dir": "valid"}, map[string]interface{}{"value": 4.0}, ts),
+		testutil.MustMetric("cpu", map[string]string{"dir": "a/../valid"}, map[string]interface{}{"value": 5.0}, ts),
+	}
+
+	baseDir := t.TempDir()
+	testDir := filepath.Join(baseDir, "output")
+	logger := &testutil.CaptureLogger{}
+	plugin := &Parquet{
+		Directory:          testDir,
+		PathTemplate:       `{{.Tag "dir"}}`,
+		TimestampFieldName: defaultTimestampFieldName,
+		Log:                logger,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+	require.Len(t, logger.Errors(), 4)
+
+	// Only the metric with a valid path must be written
+	var files []string
+	require.NoError(t, filepath.WalkDir(baseDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	}))
+	require.Len(t, files, 1)
+	require.Equal(t, filepath.Join(testDir, "valid"), filepath.Dir(files[0]))
+}
+
+func TestCompression(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("test", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Now()),
+	}
+
+	for _, codec := range []string{"none", "snappy", "gzip", "brotli", "zstd"} {
+		t.Run(codec, func(t *testing.T) {
+			testDir := t.TempDir()
+			plugin := &Parquet{
+				Directory:          testDir,
+				Compression:        codec,
+				TimestampFieldName: defaultTimestampFieldName,
+			}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			require.NoError(t, plugin.Write(metrics))
+			require.NoError(t, plugin.Close())
+
+			files, err := filepath.Glob(filepath.Join(testDir, "*.parquet"))
+			require.NoError(t, err)
+			require.Len(t, files, 1)
+			reader, err := file.OpenParquetFile(files[0], false)
+			require.NoError(t, err)
+			defer reader.Close()
+
+			column, err := reader.MetaData().RowGroup(0).ColumnChunk(0)
+			require.NoError(t, err)
+			require.Equal(t, codecs[codec], column.Compression())
+		})
+	}
+}
+
+func TestRowGroupMaxRows(t *testing.T) {
+	metrics := make([]telegraf.Metric, 0, 10)
+	for i := range 10 {
+		metrics = append(metrics, testutil.MustMetric("test", map[string]string{}, map[string]interface{}{"value": float64(i)}, time.Now()))
+	}
+
+	testDir := t.TempDir()
+	plugin := &Parquet{
+		Directory:          testDir,
+		RowGroupMaxRows:    4,
+		TimestampFieldName: defaultTimestampFieldName,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Close())
+
+	files, err := filepath.Glob(filepath.Join(testDir, "*.parquet"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	reader, err := file.OpenParquetFile(files[0], false)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	require.Equal(t, 3, reader.NumRowGroups())
+	require.Equal(t, int64(10), reader.NumRows())
+}
+
+func TestRowGroupBufferedSize(t *testing.T) {
+	metrics := make([]telegraf.Metric, 0, 10)
+	for i := range 10 {
+		metrics = append(metrics, testutil.MustMetric("test", map[string]string{}, map[string]interface{}{"value": float64(i)}, time.Now()))
+	}
+
+	testDir := t.TempDir()
+	plugin := &Parquet{
+		Directory:          testDir,
+		RowGroupMaxRows:    4,
+		TimestampFieldName: defaultTimestampFieldName,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.NoError(t, plugin.Write(metrics[:3]))
+
+	group := plugin.metricGroups[filepath.Join(testDir, "test")]
+	require.NotNil(t, group)
+	require.Equal(t, int64(3), group.rows)
+	size := group.buffered
+
+	// Only the rows of the last row group must be accounted as buffered as
+	// the full row groups are flushed to the file
+	require.NoError(t, plugin.Write(metrics[3:]))
+	require.Equal(t, int64(2), group.rows)
+	require.Positive(t, group.buffered)
+	require.Less(t, group.buffered, size)
+}
+
+func TestSchemaEvolution(t *testing.T) {
+	testDir := t.TempDir()
+	plugin := &Parquet{
+		Directory:          testDir,
+		TimestampFieldName: defaultTimestampFieldName,
+		Log:                &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	// The same schema must be written to the same file
+	m := testutil.MustMetric("test", map[string]string{"host": "a"}, map[string]interface{}{"value": int64(1)}, time.Now())
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+
+	// A changed type and a new field must start a new file with the merged
+	// schema
+	m = testutil.MustMetric("test", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.5, "count": uint64(3)}, time.Now())
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	require.NoError(t, plugin.Close())
+
+	files, err := filepath.Glob(filepath.Join(testDir, "*.parquet"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	var schemas []string
+	for _, fn := range files {
+		reader, err := file.OpenParquetFile(fn, false)
+		require.NoError(t, err)
+		rdr, err := pqarrow.NewFileReader(reader, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+		require.NoError(t, err)
+		schema, err := rdr.Schema()
+		require.NoError(t, err)
+
+		names := make([]string, 0, schema.NumFields())
+		for _, f := range schema.Fields() {
+			names = append(names, f.Name+":"+f.Type.String())
+		}
+		schemas = append(schemas, strings.Join(names, ","))
+		reader.Close()
+	}
+	sort.Strings(schemas)
+	expected := []string{
+		"host:utf8,value:float64,count:uint64,timestamp:int64",
+		"host:utf8,value:int64,timestamp:int64",
+	}
+	require.Equal(t, expected, schemas)
+}
+
+func TestWidenType(t *testing.T) {
+	tests := []struct {
+		a, b     arrow.DataType
+		expected arrow.DataType
+	}{
+		{arrow.PrimitiveTypes.Int8, arrow.PrimitiveTypes.Int8, arrow.PrimitiveTypes.Int8},
+		{arrow.PrimitiveTypes.Int8, arrow.PrimitiveTypes.Int32, arrow.PrimitiveTypes.Int64},
+		{arrow.PrimitiveTypes.Uint8, arrow.PrimitiveTypes.Uint32, arrow.PrimitiveTypes.Uint64},
+		{arrow.PrimitiveTypes.Uint64, arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Int64},
+		{arrow.PrimitiveTypes.Int64, arrow.PrimitiveTypes.Float32, arrow.PrimitiveTypes.Float64},
+		{arrow.FixedWidthTypes.Boolean, arrow.PrimitiveTypes.Int64, arrow.BinaryTypes.String},
+		{arrow.BinaryTypes.String, arrow.PrimitiveTypes.Float64, arrow.BinaryTypes.String},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a.String()+"+"+tt.b.String(), func(t *testing.T) {
+			require.Equal(t, tt.expected, widenType(tt.a, tt.b))
+			require.Equal(t, tt.expected, widenType(tt.b, tt.a))
+		})
+	}
+}
+
+func TestSizeRotation(t *testing.T) {
+	testDir := t.TempDir()
+	plugin := &Parquet{
+		Directory:          testDir,
+		RotationMaxSize:    config.Size(16),
+		TimestampFieldName: defaultTimestampFieldName,
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	for i := range 3 {
+		m := testutil.MustMetric("test", map[string]string{}, map[string]interface{}{"value": float64(i)}, time.Now())
+		require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	}
+	require.NoError(t, plugin.Close())
+
+	// Every write exceeds the size limit so each must end up in its own
+	// readable file
+	files, err := filepath.Glob(filepath.Join(testDir, "*.parquet"))
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	for _, fn := range files {
+		reader, err := file.OpenParquetFile(fn, false)
+		require.NoError(t, err)
+		require.Equal(t, int64(1), reader.NumRows())
+		reader.Close()
+	}
+}
+
+func TestCloseStalePartitions(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Parquet
+	}{
+		{
+			name:   "rotation interval",
+			plugin: &Parquet{RotationInterval: config.Duration(time.Hour)},
+		},
+		{
+			name:   "idle timeout",
+			plugin: &Parquet{IdleTimeout: config.Duration(time.Hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testDir := t.TempDir()
+			plugin := tt.plugin
+			plugin.Directory = testDir
+			plugin.PathTemplate = `date={{.Time.Format "2006-01-02"}}`
+			plugin.TimestampFieldName = defaultTimestampFieldName
+			plugin.Log = &testutil.Logger{}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Connect())
+			defer plugin.Close()
+
+			day := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+			m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, day)
+			require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+
+			// Pretend the file was created and written two hours ago
+			for _, group := range plugin.metricGroups {
+				group.created = group.created.Add(-2 * time.Hour)
+				group.written = group.written.Add(-2 * time.Hour)
+			}
+
+			// The partition of the previous day must be closed when writing
+			// metrics of the next day
+			m = testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 2.0}, day.Add(24*time.Hour))
+			require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+			require.Len(t, plugin.metricGroups, 1)
+
+			files, err := filepath.Glob(filepath.Join(testDir, "date=2026-10-16", "*.parquet"))
+			require.NoError(t, err)
+			require.Len(t, files, 1)
+			reader, err := file.OpenParquetFile(files[0], false)
+			require.NoError(t, err)
+			defer reader.Close()
+			require.Equal(t, int64(1), reader.MetaData().NumRows)
+		})
+	}
+}
@@ -0,0 +1,278 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package pulsar
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/gofrs/uuid/v5"
+	"github.com/hashicorp/golang-lru/v2/simplelru"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Pulsar struct {
+	Topic              string          `toml:"topic"`
+	TopicTag           string          `toml:"topic_tag"`
+	ExcludeTopicTag    bool            `toml:"exclude_topic_tag"`
+	RoutingTag         string          `toml:"routing_tag"`
+	RoutingKey         string          `toml:"routing_key"`
+	ProducerTimestamp  string          `toml:"producer_timestamp"`
+	MetricNameProperty string          `toml:"metric_name_property"`
+	MaxProducers       int             `toml:"max_producers"`
+	Log                telegraf.Logger `toml:"-"`
+	common.WriteConfig
+
+	clientOptions pulsar.ClientOptions
+	clientFunc    func(pulsar.ClientOptions) (pulsar.Client, error)
+	client        pulsar.Client
+	producers     *simplelru.LRU[string, pulsar.Producer]
+	topicTmpl     *template.Template
+	serializer    telegraf.Serializer
+}
+
+func (*Pulsar) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Pulsar) SetSerializer(serializer telegraf.Serializer) {
+	p.serializer = serializer
+}
+
+func (p *Pulsar) Init() error {
+	if p.Topic == "" {
+		return errors.New("'topic' must not be empty")
+	}
+	tmpl, err := template.New("topic").Parse(p.Topic)
+	if err != nil {
+		return fmt.Errorf("parsing topic template failed: %w", err)
+	}
+	p.topicTmpl = tmpl
+
+	switch p.ProducerTimestamp {
+	case "":
+		p.ProducerTimestamp = "metric"
+	case "metric", "now":
+	default:
+		return fmt.Errorf("unknown producer_timestamp option: %s", p.ProducerTimestamp)
+	}
+
+	if p.MaxProducers <= 0 {
+		return errors.New("'max_producers' must be positive")
+	}
+
+	// Check the producer settings before the first producer is created
+	if _, err := p.ProducerOptions(""); err != nil {
+		return err
+	}
+
+	options, err := p.ClientOptions(p.Log)
+	if err != nil {
+		return err
+	}
+	p.clientOptions = options
+
+	return nil
+}
+
+func (p *Pulsar) Connect() error {
+	options := p.clientOptions
+	if err := p.Authenticate(&options); err != nil {
+		return err
+	}
+	client, err := p.clientFunc(options)
+	if err != nil {
+		return &internal.StartupError{Err: err, Retry: true}
+	}
+
+	// Closing a producer flushes the pending messages and waits for their
+	// acknowledgement, so evicted producers do not lose messages
+	producers, err := simplelru.NewLRU(p.MaxProducers, func(_ string, producer pulsar.Producer) {
+		producer.Close()
+	})
+	if err != nil {
+		client.Close()
+		return err
+	}
+	p.client = client
+	p.producers = producers
+	return nil
+}
+
+func (p *Pulsar) Close() error {
+	if p.producers != nil {
+		p.producers.Purge()
+		p.producers = nil
+	}
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+	return nil
+}
+
+// topic determines the topic of the metric. The value of the topic tag
+// takes precedence over the topic template.
+func (p *Pulsar) topic(metric telegraf.Metric) (telegraf.Metric, string, error) {
+	if p.TopicTag != "" {
+		if t, ok := metric.GetTag(p.TopicTag); ok {
+			// If excluding the topic tag, a copy is required to avoid modifying
+			// the metric buffer.
+			if p.ExcludeTopicTag {
+				metric = metric.Copy()
+				metric.Accept()
+				metric.RemoveTag(p.TopicTag)
+			}
+			return metric, t, nil
+		}
+	}
+
+	m := metric
+	if wm, ok := metric.(telegraf.UnwrappableMetric); ok {
+		m = wm.Unwrap()
+	}
+	var buf bytes.Buffer
+	if err := p.topicTmpl.Execute(&buf, m); err != nil {
+		return nil, "", err
+	}
+	return metric, buf.String(), nil
+}
+
+func (p *Pulsar) routingKey(metric telegraf.Metric) (string, error) {
+	if p.RoutingTag != "" {
+		key, ok := metric.GetTag(p.RoutingTag)
+		if ok {
+			return key, nil
+		}
+	}
+
+	if p.RoutingKey == "random" {
+		u, err := uuid.NewV4()
+		if err != nil {
+			return "", err
+		}
+		return u.String(), nil
+	}
+
+	return p.RoutingKey, nil
+}
+
+// producer returns the producer for the topic creating it on first use
+func (p *Pulsar) producer(topic string) (pulsar.Producer, error) {
+	if producer, found := p.producers.Get(topic); found {
+		return producer, nil
+	}
+
+	options, err := p.ProducerOptions(topic)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := p.client.CreateProducer(options)
+	if err != nil {
+		return nil, err
+	}
+	p.producers.Add(topic, producer)
+	return producer, nil
+}
+
+func (p *Pulsar) Write(metrics []telegraf.Metric) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	for _, metric := range metrics {
+		metric, topic, err := p.topic(metric)
+		if err != nil {
+			p.Log.Errorf("Could not determine topic for metric %v: %v", metric, err)
+			continue
+		}
+
+		buf, err := p.serializer.Serialize(metric)
+		if err != nil {
+			p.Log.Debugf("Could not serialize metric: %v", err)
+			continue
+		}
+
+		msg := &pulsar.ProducerMessage{Payload: buf}
+		if p.MetricNameProperty != "" {
+			msg.Properties = map[string]string{p.MetricNameProperty: metric.Name()}
+		}
+		if p.ProducerTimestamp == "metric" {
+			msg.EventTime = metric.Time()
+		} else {
+			msg.EventTime = time.Now()
+		}
+
+		key, err := p.routingKey(metric)
+		if err != nil {
+			p.Log.Errorf("Could not generate routing key for metric %v: %v", metric, err)
+			continue
+		}
+		msg.Key = key
+
+		producer, err := p.producer(topic)
+		if err != nil {
+			wg.Wait()
+			return fmt.Errorf("creating producer for topic %q failed: %w", topic, err)
+		}
+
+		wg.Add(1)
+		producer.SendAsync(context.Background(), msg, func(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err error) {
+			defer wg.Done()
+			if err == nil {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("sending message to topic %q failed: %w", topic, err)
+			}
+		})
+	}
+
+	// Send all batched messages and wait for the acknowledgements
+	for _, topic := range p.producers.Keys() {
+		producer, _ := p.producers.Peek(topic)
+		if err := producer.Flush(); err != nil {
+			p.Log.Debugf("Flushing producer for topic %q failed: %v", topic, err)
+		}
+	}
+	wg.Wait()
+
+	if errors.Is(firstErr, pulsar.ErrMessageTooLarge) {
+		p.Log.Errorf("Message too large, consider increasing the broker's 'maxMessageSize'; dropping batch: %v", firstErr)
+		return nil
+	}
+	return firstErr
+}
+
+func init() {
+	outputs.Add("pulsar", func() telegraf.Output {
+		return &Pulsar{
+			MaxProducers: 100,
+			WriteConfig: common.WriteConfig{
+				Config: common.Config{
+					ServiceURL:        "pulsar://localhost:6650",
+					ConnectionTimeout: config.Duration(10 * time.Second),
+					OperationTimeout:  config.Duration(30 * time.Second),
+				},
+				BatchingMaxPublishDelay: config.Duration(10 * time.Millisecond),
+				SendTimeout:             config.Duration(30 * time.Second),
+			},
+			clientFunc: pulsar.NewClient,
+		}
+	})
+}
@@ -0,0 +1,290 @@
+package pulsar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/pulsar-client-go/pulsar"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	common "github.com/influxdata/telegraf/plugins/common/pulsar"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// mockClient records the messages sent by the producers
+type mockClient struct {
+	pulsar.Client
+	messages map[string][]*pulsar.ProducerMessage
+	err      error
+	sync.Mutex
+}
+
+func (c *mockClient) CreateProducer(options pulsar.ProducerOptions) (pulsar.Producer, error) {
+	return &mockProducer{client: c, topic: options.Topic}, nil
+}
+
+func (*mockClient) Close() {}
+
+type mockProducer struct {
+	pulsar.Producer
+	client *mockClient
+	topic  string
+	closed bool
+}
+
+func (p *mockProducer) SendAsync(_ context.Context, msg *pulsar.ProducerMessage, callback func(pulsar.MessageID, *pulsar.ProducerMessage, error)) {
+	p.client.Lock()
+	defer p.client.Unlock()
+	if p.client.err != nil {
+		go callback(nil, msg, p.client.err)
+		return
+	}
+	p.client.messages[p.topic] = append(p.client.messages[p.topic], msg)
+	go callback(nil, msg, nil)
+}
+
+func (*mockProducer) Flush() error {
+	return nil
+}
+
+func (p *mockProducer) Close() {
+	p.closed = true
+}
+
+func newPlugin(client *mockClient) *Pulsar {
+	return &Pulsar{
+		MaxProducers: 100,
+		WriteConfig: common.WriteConfig{
+			Config: common.Config{ServiceURL: "pulsar://localhost:6650"},
+		},
+		Log: &testutil.Logger{},
+		clientFunc: func(pulsar.ClientOptions) (pulsar.Client, error) {
+			return client, nil
+		},
+	}
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Pulsar
+		expected string
+	}{
+		{
+			name:     "no topic",
+			plugin:   &Pulsar{},
+			expected: "'topic' must not be empty",
+		},
+		{
+			name:     "invalid template",
+			plugin:   &Pulsar{Topic: "{{.Name"},
+			expected: "parsing topic template failed",
+		},
+		{
+			name:     "invalid compression",
+			plugin:   &Pulsar{Topic: "telegraf", MaxProducers: 1, WriteConfig: common.WriteConfig{Compression: "gzip"}},
+			expected: `invalid compression "gzip"`,
+		},
+		{
+			name:     "no service url",
+			plugin:   &Pulsar{Topic: "telegraf", MaxProducers: 1},
+			expected: "'service_url' must not be empty",
+		},
+		{
+			name:     "no producers",
+			plugin:   &Pulsar{Topic: "telegraf"},
+			expected: "'max_producers' must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestTopicRouting(t *testing.T) {
+	client := &mockClient{messages: make(map[string][]*pulsar.ProducerMessage)}
+	plugin := newPlugin(client)
+	plugin.Topic = `persistent://public/default/{{.Name}}`
+	plugin.TopicTag = "topic"
+	plugin.ExcludeTopicTag = true
+	plugin.RoutingTag = "host"
+	plugin.RoutingKey = "telegraf"
+	plugin.MetricNameProperty = "metric"
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		metric.New("mem", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+		metric.New("cpu", map[string]string{"host": "b", "topic": "special"}, map[string]interface{}{"value": 3.0}, time.Unix(3, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Len(t, client.messages, 3)
+	cpu := client.messages["persistent://public/default/cpu"]
+	require.Len(t, cpu, 1)
+	require.Equal(t, "cpu,host=a value=1 1000000000\n", string(cpu[0].Payload))
+	require.Equal(t, "a", cpu[0].Key)
+	require.Equal(t, map[string]string{"metric": "cpu"}, cpu[0].Properties)
+	require.Equal(t, time.Unix(1, 0), cpu[0].EventTime)
+
+	// The routing key is used as fallback
+	mem := client.messages["persistent://public/default/mem"]
+	require.Len(t, mem, 1)
+	require.Equal(t, "telegraf", mem[0].Key)
+
+	// The topic tag takes precedence and is removed from the metric
+	special := client.messages["special"]
+	require.Len(t, special, 1)
+	require.Equal(t, "cpu,host=b value=3 3000000000\n", string(special[0].Payload))
+
+	// The original metric must not be modified
+	require.True(t, metrics[2].HasTag("topic"))
+}
+
+func TestWriteError(t *testing.T) {
+	client := &mockClient{
+		messages: make(map[string][]*pulsar.ProducerMessage),
+		err:      errors.New("connection closed"),
+	}
+	plugin := newPlugin(client)
+	plugin.Topic = "telegraf"
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	require.ErrorContains(t, plugin.Write(testutil.MockMetrics()), `sending message to topic "telegraf" failed: connection closed`)
+
+	// Messages too large can never be sent, so the batch is dropped
+	client.err = pulsar.ErrMessageTooLarge
+	require.NoError(t, plugin.Write(testutil.MockMetrics()))
+}
+
+func TestProducerEviction(t *testing.T) {
+	client := &mockClient{messages: make(map[string][]*pulsar.ProducerMessage)}
+	plugin := newPlugin(client)
+	plugin.Topic = "{{.Name}}"
+	plugin.MaxProducers = 1
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		metric.New("mem", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics[:1]))
+	cpu, found := plugin.producers.Peek("cpu")
+	require.True(t, found)
+
+	// Writing to another topic closes the least recently used producer
+	require.NoError(t, plugin.Write(metrics[1:]))
+	require.True(t, cpu.(*mockProducer).closed)
+	require.Equal(t, []string{"mem"}, plugin.producers.Keys())
+	require.Len(t, client.messages["cpu"], 1)
+	require.Len(t, client.messages["mem"], 1)
+}
+
+func TestTokenResolvedOnConnect(t *testing.T) {
+	client := &mockClient{messages: make(map[string][]*pulsar.ProducerMessage)}
+	plugin := newPlugin(client)
+	plugin.Topic = "telegraf"
+	plugin.Token = config.NewSecret([]byte("secret"))
+	defer plugin.Token.Destroy()
+
+	var options pulsar.ClientOptions
+	plugin.clientFunc = func(o pulsar.ClientOptions) (pulsar.Client, error) {
+		options = o
+		return client, nil
+	}
+	require.NoError(t, plugin.Init())
+	require.Nil(t, plugin.clientOptions.Authentication)
+
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+	require.NotNil(t, options.Authentication)
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	container := testutil.Container{
+		Image:        "apachepulsar/pulsar:3.3.2",
+		Cmd:          []string{"bin/pulsar", "standalone"},
+		ExposedPorts: []string{"6650", "8080"},
+		WaitingFor: wait.ForAll(
+			wait.ForHTTP("/admin/v2/clusters").WithPort(nat.Port("8080")),
+			wait.ForListeningPort(nat.Port("6650")),
+		),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	serviceURL := fmt.Sprintf("pulsar://%s:%s", container.Address, container.Ports["6650"])
+	plugin := &Pulsar{
+		Topic:        "persistent://public/default/{{.Name}}",
+		MaxProducers: 100,
+		WriteConfig: common.WriteConfig{
+			Config: common.Config{
+				ServiceURL:        serviceURL,
+				ConnectionTimeout: config.Duration(10 * time.Second),
+				OperationTimeout:  config.Duration(30 * time.Second),
+			},
+		},
+		Log:        &testutil.Logger{},
+		clientFunc: pulsar.NewClient,
+	}
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Subscribe before writing to receive the messages
+	client, err := pulsar.NewClient(pulsar.ClientOptions{URL: serviceURL, Logger: common.NewLogger(&testutil.Logger{})})
+	require.NoError(t, err)
+	defer client.Close()
+	consumer, err := client.Subscribe(pulsar.ConsumerOptions{
+		Topic:            "persistent://public/default/test",
+		SubscriptionName: "test",
+	})
+	require.NoError(t, err)
+	defer consumer.Close()
+
+	require.NoError(t, plugin.Write(testutil.MockMetrics()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	msg, err := consumer.Receive(ctx)
+	require.NoError(t, err)
+	require.Contains(t, string(msg.Payload()), "test1,tag1=value1 value=1")
+}
@@ -0,0 +1,194 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package redis_streams
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	common "github.com/influxdata/telegraf/plugins/common/redis"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type RedisStreams struct {
+	Stream              string          `toml:"stream"`
+	MaxLen              int64           `toml:"max_len"`
+	ApproximateTrimming bool            `toml:"approximate_trimming"`
+	PayloadField        string          `toml:"payload_field"`
+	MetricNameField     string          `toml:"metric_name_field"`
+	Log                 telegraf.Logger `toml:"-"`
+	common.Config
+
+	client     *redis.Client
+	streamTmpl *template.Template
+	serializer telegraf.Serializer
+}
+
+func (*RedisStreams) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *RedisStreams) SetSerializer(serializer telegraf.Serializer) {
+	r.serializer = serializer
+}
+
+func (r *RedisStreams) Init() error {
+	if r.Stream == "" {
+		return errors.New("'stream' must not be empty")
+	}
+	tmpl, err := template.New("stream").Parse(r.Stream)
+	if err != nil {
+		return fmt.Errorf("parsing stream template failed: %w", err)
+	}
+	r.streamTmpl = tmpl
+
+	if r.MaxLen < 0 {
+		return errors.New("'max_len' must not be negative")
+	}
+	if r.PayloadField == "" {
+		return errors.New("'payload_field' must not be empty")
+	}
+	if r.PayloadField == r.MetricNameField {
+		return errors.New("'payload_field' and 'metric_name_field' must differ")
+	}
+
+	return nil
+}
+
+func (r *RedisStreams) Connect() error {
+	client, err := r.NewClient()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Timeout))
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return fmt.Errorf("connecting to %q failed: %w", r.Address, err)
+	}
+	r.client = client
+
+	return nil
+}
+
+func (r *RedisStreams) Close() error {
+	if r.client == nil {
+		return nil
+	}
+	err := r.client.Close()
+	r.client = nil
+	return err
+}
+
+func (r *RedisStreams) Write(metrics []telegraf.Metric) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(r.Timeout))
+	defer cancel()
+
+	// Send all entries in a single round-trip and remember the metric index
+	// of each command to report the result per metric
+	var wErr internal.PartialWriteError
+	pipe := r.client.Pipeline()
+	indices := make([]int, 0, len(metrics))
+	for i, metric := range metrics {
+		args, err := r.entry(metric)
+		if err != nil {
+			r.Log.Errorf("Could not create entry for metric %v: %v", metric, err)
+			wErr.MetricsReject = append(wErr.MetricsReject, i)
+			continue
+		}
+		pipe.XAdd(ctx, args)
+		indices = append(indices, i)
+	}
+	if pipe.Len() == 0 {
+		return rejected(&wErr)
+	}
+
+	// Accept the metrics of all successful commands and keep the others for
+	// the next write
+	cmds, err := pipe.Exec(ctx)
+	if err == nil {
+		wErr.MetricsAccept = indices
+		return rejected(&wErr)
+	}
+	for i, cmd := range cmds {
+		if cerr := cmd.Err(); cerr != nil {
+			if wErr.Err == nil {
+				wErr.Err = fmt.Errorf("adding entry to stream %q failed: %w", cmd.Args()[1], cerr)
+			}
+			continue
+		}
+		wErr.MetricsAccept = append(wErr.MetricsAccept, indices[i])
+	}
+	if wErr.Err == nil {
+		wErr.Err = err
+	}
+	return &wErr
+}
+
+// Report the rejected metrics, if any, of an otherwise successful write
+func rejected(wErr *internal.PartialWriteError) error {
+	if len(wErr.MetricsReject) == 0 {
+		return nil
+	}
+	wErr.Err = fmt.Errorf("%d metrics were rejected", len(wErr.MetricsReject))
+	return wErr
+}
+
+// entry creates the arguments for adding the serialized metric to its stream
+func (r *RedisStreams) entry(metric telegraf.Metric) (*redis.XAddArgs, error) {
+	m := metric
+	if wm, ok := metric.(telegraf.UnwrappableMetric); ok {
+		m = wm.Unwrap()
+	}
+	var stream bytes.Buffer
+	if err := r.streamTmpl.Execute(&stream, m); err != nil {
+		return nil, fmt.Errorf("determining stream failed: %w", err)
+	}
+	if stream.Len() == 0 {
+		return nil, errors.New("stream is empty")
+	}
+
+	payload, err := r.serializer.Serialize(metric)
+	if err != nil {
+		return nil, fmt.Errorf("serialization failed: %w", err)
+	}
+
+	values := []interface{}{r.PayloadField, payload}
+	if r.MetricNameField != "" {
+		values = append(values, r.MetricNameField, metric.Name())
+	}
+
+	return &redis.XAddArgs{
+		Stream: stream.String(),
+		MaxLen: r.MaxLen,
+		Approx: r.ApproximateTrimming,
+		Values: values,
+	}, nil
+}
+
+func init() {
+	outputs.Add("redis_streams", func() telegraf.Output {
+		return &RedisStreams{
+			Stream:              "telegraf",
+			ApproximateTrimming: true,
+			PayloadField:        "data",
+			Config: common.Config{
+				Address: "127.0.0.1:6379",
+				Timeout: config.Duration(10 * time.Second),
+			},
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package redis_streams
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	common "github.com/influxdata/telegraf/plugins/common/redis"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *RedisStreams
+		expected string
+	}{
+		{
+			name:     "no stream",
+			plugin:   &RedisStreams{PayloadField: "data"},
+			expected: "'stream' must not be empty",
+		},
+		{
+			name:     "invalid stream template",
+			plugin:   &RedisStreams{Stream: "{{.Name", PayloadField: "data"},
+			expected: "parsing stream template failed",
+		},
+		{
+			name:     "negative max length",
+			plugin:   &RedisStreams{Stream: "telegraf", PayloadField: "data", MaxLen: -1},
+			expected: "'max_len' must not be negative",
+		},
+		{
+			name:     "no payload field",
+			plugin:   &RedisStreams{Stream: "telegraf"},
+			expected: "'payload_field' must not be empty",
+		},
+		{
+			name:     "conflicting fields",
+			plugin:   &RedisStreams{Stream: "telegraf", PayloadField: "data", MetricNameField: "data"},
+			expected: "'payload_field' and 'metric_name_field' must differ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestEntry(t *testing.T) {
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+
+	plugin := &RedisStreams{
+		Stream:              `telegraf-{{.Name}}-{{.Tag "host"}}`,
+		MaxLen:              100,
+		ApproximateTrimming: true,
+		PayloadField:        "data",
+		MetricNameField:     "name",
+		Log:                 &testutil.Logger{},
+	}
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+
+	m := metric.New("cpu",
+		map[string]string{"host": "server01"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(1, 0),
+	)
+	args, err := plugin.entry(m)
+	require.NoError(t, err)
+	require.Equal(t, &redis.XAddArgs{
+		Stream: "telegraf-cpu-server01",
+		MaxLen: 100,
+		Approx: true,
+		Values: []interface{}{"data", []byte("cpu,host=server01 value=42 1000000000\n"), "name", "cpu"},
+	}, args)
+
+	// Tracking metrics must be unwrapped to evaluate the template
+	tm, _ := metric.WithTracking(m, func(telegraf.DeliveryInfo) {})
+	args, err = plugin.entry(tm)
+	require.NoError(t, err)
+	require.Equal(t, "telegraf-cpu-server01", args.Stream)
+}
+
+func TestEmptyStream(t *testing.T) {
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+
+	plugin := &RedisStreams{
+		Stream:       `{{.Tag "stream"}}`,
+		PayloadField: "data",
+		Log:          &testutil.Logger{},
+	}
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+
+	_, err := plugin.entry(testutil.TestMetric(1.0))
+	require.ErrorContains(t, err, "stream is empty")
+
+	// Rejected metrics must be reported even if nothing is sent to the server
+	plugin.client = redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	defer plugin.client.Close()
+
+	err = plugin.Write([]telegraf.Metric{testutil.TestMetric(1.0), testutil.TestMetric(2.0)})
+	var wErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &wErr)
+	require.ErrorContains(t, err, "2 metrics were rejected")
+	require.Empty(t, wErr.MetricsAccept)
+	require.Equal(t, []int{0, 1}, wErr.MetricsReject)
+}
+
+func TestIntegration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	servicePort := "6379"
+	container := testutil.Container{
+		Image:        "redis:7-alpine",
+		ExposedPorts: []string{servicePort},
+		WaitingFor:   wait.ForListeningPort(nat.Port(servicePort)),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	address := fmt.Sprintf("%s:%s", container.Address, container.Ports[servicePort])
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+
+	plugin := &RedisStreams{
+		Stream:              "telegraf",
+		MaxLen:              2,
+		ApproximateTrimming: false,
+		PayloadField:        "data",
+		Config: common.Config{
+			Address: address,
+			Timeout: config.Duration(10 * time.Second),
+		},
+		Log: &testutil.Logger{},
+	}
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 3.0}, time.Unix(3, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	// The stream is trimmed to the two most recent entries
+	client := redis.NewClient(&redis.Options{Addr: address})
+	defer client.Close()
+	entries, err := client.XRange(context.Background(), "telegraf", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, "cpu value=2 2000000000\n", entries[0].Values["data"])
+	require.Equal(t, "cpu value=3 3000000000\n", entries[1].Values["data"])
+}
+
+func TestIntegrationPartialWrite(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	servicePort := "6379"
+	container := testutil.Container{
+		Image:        "redis:7-alpine",
+		ExposedPorts: []string{servicePort},
+		WaitingFor:   wait.ForListeningPort(nat.Port(servicePort)),
+	}
+	require.NoError(t, container.Start(), "failed to start container")
+	defer container.Terminate()
+
+	address := fmt.Sprintf("%s:%s", container.Address, container.Ports[servicePort])
+
+	// Adding entries to a key of another type fails
+	client := redis.NewClient(&redis.Options{Addr: address})
+	defer client.Close()
+	require.NoError(t, client.Set(context.Background(), "mem", "no stream", 0).Err())
+
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+
+	plugin := &RedisStreams{
+		Stream:       "{{.Name}}",
+		PayloadField: "data",
+		Config: common.Config{
+			Address: address,
+			Timeout: config.Duration(10 * time.Second),
+		},
+		Log: &testutil.Logger{},
+	}
+	plugin.SetSerializer(serializer)
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		metric.New("mem", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 3.0}, time.Unix(3, 0)),
+	}
+	err := plugin.Write(metrics)
+	var wErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &wErr)
+	require.ErrorContains(t, err, `adding entry to stream "mem" failed`)
+	require.Equal(t, []int{0, 2}, wErr.MetricsAccept)
+	require.Empty(t, wErr.MetricsReject)
+
+	entries, err := client.XRange(context.Background(), "cpu", "-", "+").Result()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+}
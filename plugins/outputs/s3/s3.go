@@ -0,0 +1,514 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package s3
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/apache/arrow/go/v18/parquet"
+	"github.com/apache/arrow/go/v18/parquet/compress"
+	"github.com/apache/arrow/go/v18/parquet/pqarrow"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/gofrs/uuid/v5"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	common_aws "github.com/influxdata/telegraf/plugins/common/aws"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers/arrow"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+// failedDir is the subdirectory of the staging directory receiving objects
+// which can never be uploaded
+const failedDir = "failed"
+
+// keySuffix is the extension of the files holding the key of a staged object
+const keySuffix = ".key"
+
+var codecs = map[string]compress.Compression{
+	"none":   compress.Codecs.Uncompressed,
+	"snappy": compress.Codecs.Snappy,
+	"gzip":   compress.Codecs.Gzip,
+	"brotli": compress.Codecs.Brotli,
+	"zstd":   compress.Codecs.Zstd,
+}
+
+type S3 struct {
+	Bucket               string          `toml:"bucket"`
+	Key                  string          `toml:"key"`
+	ObjectFormat         string          `toml:"object_format"`
+	ParquetCompression   string          `toml:"parquet_compression"`
+	ContentType          string          `toml:"content_type"`
+	StorageClass         string          `toml:"storage_class"`
+	ServerSideEncryption string          `toml:"server_side_encryption"`
+	SSEKMSKeyID          string          `toml:"sse_kms_key_id"`
+	ForcePathStyle       bool            `toml:"force_path_style"`
+	PartSize             config.Size     `toml:"part_size"`
+	UploadConcurrency    int             `toml:"upload_concurrency"`
+	StagingDir           string          `toml:"staging_dir"`
+	StagingMaxSize       config.Size     `toml:"staging_max_size"`
+	Timeout              config.Duration `toml:"timeout"`
+	Log                  telegraf.Logger `toml:"-"`
+	common_aws.CredentialConfig
+
+	keyTmpl    *template.Template
+	serializer telegraf.Serializer
+	records    *arrow.Serializer
+	properties *parquet.WriterProperties
+	uploader   *manager.Uploader
+	sequence   uint64
+}
+
+// object is a serialized batch of metrics to upload
+type object struct {
+	key  string
+	data []byte
+}
+
+func (*S3) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *S3) SetSerializer(serializer telegraf.Serializer) {
+	s.serializer = serializer
+}
+
+func (s *S3) Init() error {
+	if s.Bucket == "" {
+		return errors.New("'bucket' must not be empty")
+	}
+	if s.Key == "" {
+		return errors.New("'key' must not be empty")
+	}
+
+	// The functions are replaced for each batch
+	funcs := template.FuncMap{
+		"now":      time.Now,
+		"batch_id": func() string { return "" },
+	}
+	tmpl, err := template.New("key").Funcs(funcs).Parse(s.Key)
+	if err != nil {
+		return fmt.Errorf("parsing key template failed: %w", err)
+	}
+	s.keyTmpl = tmpl
+
+	switch s.ObjectFormat {
+	case "", "serializer":
+		s.ObjectFormat = "serializer"
+		if s.ContentType == "" {
+			s.ContentType = "application/octet-stream"
+		}
+	case "parquet":
+		codec, found := codecs[s.ParquetCompression]
+		if !found {
+			return fmt.Errorf("unknown parquet compression %q", s.ParquetCompression)
+		}
+		s.properties = parquet.NewWriterProperties(parquet.WithCompression(codec))
+		s.records = &arrow.Serializer{Log: s.Log}
+		if err := s.records.Init(); err != nil {
+			return err
+		}
+		if s.ContentType == "" {
+			s.ContentType = "application/vnd.apache.parquet"
+		}
+	default:
+		return fmt.Errorf("invalid object format %q", s.ObjectFormat)
+	}
+
+	switch s.ServerSideEncryption {
+	case "":
+		if s.SSEKMSKeyID != "" {
+			return errors.New("'sse_kms_key_id' requires 'server_side_encryption' to use KMS")
+		}
+	case string(types.ServerSideEncryptionAes256):
+		if s.SSEKMSKeyID != "" {
+			return errors.New("'sse_kms_key_id' requires 'server_side_encryption' to use KMS")
+		}
+	case string(types.ServerSideEncryptionAwsKms), string(types.ServerSideEncryptionAwsKmsDsse):
+	default:
+		return fmt.Errorf("invalid server side encryption %q", s.ServerSideEncryption)
+	}
+
+	if int64(s.PartSize) < manager.MinUploadPartSize {
+		return fmt.Errorf("'part_size' must be at least %d bytes", manager.MinUploadPartSize)
+	}
+	if s.UploadConcurrency < 1 {
+		return errors.New("'upload_concurrency' must be positive")
+	}
+
+	if s.StagingDir != "" {
+		if err := os.MkdirAll(filepath.Join(s.StagingDir, failedDir), 0750); err != nil {
+			return fmt.Errorf("creating staging directory failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *S3) Connect() error {
+	cfg, err := s.CredentialConfig.Credentials()
+	if err != nil {
+		return err
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if s.EndpointURL != "" {
+			o.BaseEndpoint = aws.String(s.EndpointURL)
+		}
+		o.UsePathStyle = s.ForcePathStyle
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Timeout))
+	defer cancel()
+	if _, err := client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.Bucket)}); err != nil {
+		return fmt.Errorf("accessing bucket %q failed: %w", s.Bucket, err)
+	}
+
+	s.uploader = manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = int64(s.PartSize)
+		u.Concurrency = s.UploadConcurrency
+	})
+
+	// Upload the objects staged before the last shutdown without waiting for
+	// the first write
+	if s.StagingDir != "" {
+		if err := s.uploadStaged(); err != nil {
+			s.Log.Errorf("Uploading staged objects failed, retrying with next write: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *S3) Close() error {
+	// Try to upload the remaining staged objects a last time, they are kept
+	// for the next start otherwise
+	if s.StagingDir != "" && s.uploader != nil {
+		if err := s.uploadStaged(); err != nil {
+			s.Log.Errorf("Uploading staged objects failed, keeping them for the next start: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *S3) Write(metrics []telegraf.Metric) error {
+	objects, err := s.objects(metrics)
+	if err != nil {
+		return err
+	}
+
+	if s.StagingDir == "" {
+		for _, obj := range objects {
+			if err := s.upload(obj.key, bytes.NewReader(obj.data)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Persist the objects locally first so the metrics are not lost if the
+	// upload fails. Pending objects are uploaded in the order of creation.
+	// If the staging directory is full, try to make room by uploading the
+	// pending objects and keep the metrics in the output buffer otherwise.
+	if s.StagingMaxSize > 0 {
+		var size int64
+		for _, obj := range objects {
+			size += int64(len(obj.data))
+		}
+		_, staged, err := s.staged()
+		if err != nil {
+			return err
+		}
+		if staged+size > int64(s.StagingMaxSize) {
+			if err := s.uploadStaged(); err != nil {
+				return fmt.Errorf("staging directory is full: %w", err)
+			}
+		}
+	}
+	for _, obj := range objects {
+		if err := s.stage(obj); err != nil {
+			return err
+		}
+	}
+	if err := s.uploadStaged(); err != nil {
+		s.Log.Errorf("Uploading staged objects failed, retrying with next write: %v", err)
+	}
+	return nil
+}
+
+// objects groups the metrics by their object key and serializes the groups
+func (s *S3) objects(metrics []telegraf.Metric) ([]object, error) {
+	batchID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("generating batch ID failed: %w", err)
+	}
+	now := time.Now()
+	s.keyTmpl.Funcs(template.FuncMap{
+		"now":      func() time.Time { return now },
+		"batch_id": batchID.String,
+	})
+
+	var keys []string
+	groups := make(map[string][]telegraf.Metric)
+	var buf bytes.Buffer
+	for _, raw := range metrics {
+		m := raw
+		if wm, ok := raw.(telegraf.UnwrappableMetric); ok {
+			m = wm.Unwrap()
+		}
+
+		buf.Reset()
+		if err := s.keyTmpl.Execute(&buf, m); err != nil {
+			s.Log.Errorf("Cannot create key for metric %v: %v", m, err)
+			continue
+		}
+		key := strings.TrimLeft(buf.String(), "/")
+		if key == "" {
+			s.Log.Errorf("Key for metric %v is empty", m)
+			continue
+		}
+		if _, found := groups[key]; !found {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], raw)
+	}
+
+	objects := make([]object, 0, len(keys))
+	for _, key := range keys {
+		if s.ObjectFormat == "parquet" {
+			objs, err := s.parquetObjects(key, groups[key])
+			if err != nil {
+				return nil, err
+			}
+			objects = append(objects, objs...)
+			continue
+		}
+
+		data, err := s.serializer.SerializeBatch(groups[key])
+		if err != nil {
+			return nil, fmt.Errorf("serializing metrics for %q failed: %w", key, err)
+		}
+		objects = append(objects, object{key: key, data: data})
+	}
+	return objects, nil
+}
+
+// parquetObjects creates one Parquet file for every schema of the metrics.
+// All files except the first get a numbered suffix in their key.
+func (s *S3) parquetObjects(key string, metrics []telegraf.Metric) ([]object, error) {
+	records := s.records.Records(metrics)
+	defer func() {
+		for _, record := range records {
+			record.Release()
+		}
+	}()
+
+	ext := path.Ext(key)
+	objects := make([]object, 0, len(records))
+	for i, record := range records {
+		var buf bytes.Buffer
+		writer, err := pqarrow.NewFileWriter(record.Schema(), &buf, s.properties, pqarrow.DefaultWriterProps())
+		if err != nil {
+			return nil, fmt.Errorf("creating parquet writer for %q failed: %w", key, err)
+		}
+		if err := writer.Write(record); err != nil {
+			return nil, fmt.Errorf("writing parquet file for %q failed: %w", key, err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("closing parquet file for %q failed: %w", key, err)
+		}
+
+		k := key
+		if i > 0 {
+			k = strings.TrimSuffix(key, ext) + "-" + strconv.Itoa(i) + ext
+		}
+		objects = append(objects, object{key: k, data: buf.Bytes()})
+	}
+	return objects, nil
+}
+
+func (s *S3) upload(key string, body io.Reader) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.Bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(s.ContentType),
+	}
+	if s.StorageClass != "" {
+		input.StorageClass = types.StorageClass(s.StorageClass)
+	}
+	if s.ServerSideEncryption != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(s.ServerSideEncryption)
+	}
+	if s.SSEKMSKeyID != "" {
+		input.SSEKMSKeyId = aws.String(s.SSEKMSKeyID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Timeout))
+	defer cancel()
+	if _, err := s.uploader.Upload(ctx, input); err != nil {
+		return fmt.Errorf("uploading %q failed: %w", key, err)
+	}
+	return nil
+}
+
+// stage writes the object to the staging directory. The filename consists of
+// the creation time and a sequence number to retain the order of the objects.
+// The key is stored in a separate file as keys may be up to 1024 bytes long
+// and exceed the filename limits of the filesystem.
+func (s *S3) stage(obj object) error {
+	s.sequence++
+	name := fmt.Sprintf("%020d-%020d", time.Now().UnixNano(), s.sequence)
+	filename := filepath.Join(s.StagingDir, name)
+
+	// Write to temporary files first to not upload partial objects. The key
+	// is written first as the object is only considered once it exists.
+	if err := writeFile(filename+keySuffix, []byte(obj.key)); err != nil {
+		return fmt.Errorf("staging %q failed: %w", obj.key, err)
+	}
+	if err := writeFile(filename, obj.data); err != nil {
+		//nolint:errcheck // Ignore errors as key files without object are skipped
+		os.Remove(filename + keySuffix)
+		return fmt.Errorf("staging %q failed: %w", obj.key, err)
+	}
+	return nil
+}
+
+// writeFile writes the data to a temporary file and renames it afterwards
+func writeFile(filename string, data []byte) error {
+	if err := os.WriteFile(filename+".tmp", data, 0640); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+// staged returns the names of the staged objects in order of their creation
+// and their total size including the key files
+func (s *S3) staged() ([]string, int64, error) {
+	entries, err := os.ReadDir(s.StagingDir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading staging directory failed: %w", err)
+	}
+
+	var size int64
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// The file was removed in the meantime
+			continue
+		}
+		size += info.Size()
+		if !strings.HasSuffix(entry.Name(), keySuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, size, nil
+}
+
+// uploadStaged uploads the staged objects in order of their creation and
+// removes them after a successful upload, stopping at the first failure.
+// Objects failing with a permanent error are moved to the "failed"
+// subdirectory to not block the following objects.
+func (s *S3) uploadStaged() error {
+	names, _, err := s.staged()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		filename := filepath.Join(s.StagingDir, name)
+		buf, err := os.ReadFile(filename + keySuffix)
+		if err != nil {
+			s.Log.Warnf("Ignoring file %q without key in staging directory: %v", name, err)
+			continue
+		}
+		key := string(buf)
+
+		if err := s.uploadFile(key, filename); err != nil {
+			if !permanent(err) {
+				return err
+			}
+			s.Log.Errorf("Moving staged object to %q as it cannot be uploaded: %v", failedDir, err)
+			for _, fn := range []string{filename, filename + keySuffix} {
+				if err := os.Rename(fn, filepath.Join(s.StagingDir, failedDir, filepath.Base(fn))); err != nil {
+					return fmt.Errorf("moving staged object %q failed: %w", key, err)
+				}
+			}
+			continue
+		}
+		for _, fn := range []string{filename, filename + keySuffix} {
+			if err := os.Remove(fn); err != nil {
+				return fmt.Errorf("removing staged object %q failed: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *S3) uploadFile(key, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("opening staged object %q failed: %w", key, err)
+	}
+	defer f.Close()
+	return s.upload(key, f)
+}
+
+// permanent returns true if retrying the request will not succeed, e.g. due
+// to denied access or an invalid key. Client errors indicating timeouts,
+// throttling or expired credentials are considered transient.
+func permanent(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "RequestTimeout", "RequestTimeTooSkewed", "SlowDown", "ExpiredToken", "TokenRefreshRequired":
+			return false
+		}
+	}
+
+	var respErr *awshttp.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	code := respErr.HTTPStatusCode()
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+func init() {
+	outputs.Add("s3", func() telegraf.Output {
+		return &S3{
+			Key:                `{{.Name}}/{{now.Format "2006/01/02/15"}}/{{batch_id}}`,
+			ParquetCompression: "snappy",
+			PartSize:           config.Size(manager.DefaultUploadPartSize),
+			UploadConcurrency:  manager.DefaultUploadConcurrency,
+			StagingMaxSize:     config.Size(1024 * 1024 * 1024),
+			Timeout:            config.Duration(5 * time.Minute),
+		}
+	})
+}
@@ -0,0 +1,554 @@
+package s3
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v18/parquet/file"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	common_aws "github.com/influxdata/telegraf/plugins/common/aws"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+// stub implements the subset of the S3 API used by the plugin
+type stub struct {
+	objects  map[string][]byte
+	headers  map[string]http.Header
+	parts    map[string]map[int][]byte
+	uploads  int
+	failing  int
+	requests []string
+	sync.Mutex
+}
+
+func newStub() *stub {
+	return &stub{
+		objects: make(map[string][]byte),
+		headers: make(map[string]http.Header),
+		parts:   make(map[string]map[int][]byte),
+	}
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	switch s.failing {
+	case 0:
+	case http.StatusForbidden:
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+		return
+	default:
+		w.WriteHeader(s.failing)
+		_, _ = w.Write([]byte(`<Error><Code>NotImplemented</Code><Message>Not Implemented</Message></Error>`))
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != "telegraf" {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`<Error><Code>NoSuchBucket</Code></Error>`))
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query()
+
+	switch {
+	case r.Method == http.MethodHead && key == "":
+		s.requests = append(s.requests, "HeadBucket")
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		s.requests = append(s.requests, "UploadPart")
+		n, err := strconv.Atoi(query.Get("partNumber"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.parts[query.Get("uploadId")][n] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, n))
+	case r.Method == http.MethodPut:
+		s.requests = append(s.requests, "PutObject")
+		s.objects[key] = body
+		s.headers[key] = r.Header.Clone()
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.requests = append(s.requests, "CreateMultipartUpload")
+		s.uploads++
+		id := strconv.Itoa(s.uploads)
+		s.parts[id] = make(map[int][]byte)
+		s.headers[key] = r.Header.Clone()
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, bucket, key, id)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		s.requests = append(s.requests, "CompleteMultipartUpload")
+		parts := s.parts[query.Get("uploadId")]
+		numbers := make([]int, 0, len(parts))
+		for n := range parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		var buf bytes.Buffer
+		for _, n := range numbers {
+			buf.Write(parts[n])
+		}
+		s.objects[key] = buf.Bytes()
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>"etag"</ETag></CompleteMultipartUploadResult>`, bucket, key)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// setFailing lets all requests fail with the given status code, use zero to
+// succeed again. Use status codes not retried by the SDK to avoid delays.
+func (s *stub) setFailing(failing int) {
+	s.Lock()
+	defer s.Unlock()
+	s.failing = failing
+}
+
+func newPlugin(url string) *S3 {
+	return &S3{
+		Bucket:             "telegraf",
+		Key:                `{{.Name}}/{{.Tag "host"}}.influx`,
+		ParquetCompression: "snappy",
+		ForcePathStyle:     true,
+		PartSize:           config.Size(5 * 1024 * 1024),
+		UploadConcurrency:  2,
+		Timeout:            config.Duration(5 * time.Second),
+		Log:                &testutil.Logger{},
+		CredentialConfig: common_aws.CredentialConfig{
+			Region:      "us-east-1",
+			AccessKey:   "access",
+			SecretKey:   "secret",
+			EndpointURL: url,
+		},
+	}
+}
+
+func newSerializer(t *testing.T) telegraf.Serializer {
+	serializer := &influx.Serializer{}
+	require.NoError(t, serializer.Init())
+	return serializer
+}
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *S3
+		expected string
+	}{
+		{
+			name:     "no bucket",
+			plugin:   &S3{Key: "test"},
+			expected: "'bucket' must not be empty",
+		},
+		{
+			name:     "no key",
+			plugin:   &S3{Bucket: "telegraf"},
+			expected: "'key' must not be empty",
+		},
+		{
+			name:     "invalid key template",
+			plugin:   &S3{Bucket: "telegraf", Key: "{{.Name"},
+			expected: "parsing key template failed",
+		},
+		{
+			name:     "invalid format",
+			plugin:   &S3{Bucket: "telegraf", Key: "test", ObjectFormat: "orc"},
+			expected: `invalid object format "orc"`,
+		},
+		{
+			name:     "invalid parquet compression",
+			plugin:   &S3{Bucket: "telegraf", Key: "test", ObjectFormat: "parquet", ParquetCompression: "lzma"},
+			expected: `unknown parquet compression "lzma"`,
+		},
+		{
+			name:     "invalid encryption",
+			plugin:   &S3{Bucket: "telegraf", Key: "test", ServerSideEncryption: "rot13"},
+			expected: `invalid server side encryption "rot13"`,
+		},
+		{
+			name:     "kms key without kms",
+			plugin:   &S3{Bucket: "telegraf", Key: "test", ServerSideEncryption: "AES256", SSEKMSKeyID: "key"},
+			expected: "'sse_kms_key_id' requires 'server_side_encryption' to use KMS",
+		},
+		{
+			name:     "part size too small",
+			plugin:   &S3{Bucket: "telegraf", Key: "test", PartSize: config.Size(1024)},
+			expected: "'part_size' must be at least 5242880 bytes",
+		},
+		{
+			name:     "invalid concurrency",
+			plugin:   &S3{Bucket: "telegraf", Key: "test", PartSize: config.Size(5 * 1024 * 1024)},
+			expected: "'upload_concurrency' must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestConnectUnknownBucket(t *testing.T) {
+	server := httptest.NewServer(newStub())
+	defer server.Close()
+
+	plugin := newPlugin(server.URL)
+	plugin.Bucket = "unknown"
+	require.NoError(t, plugin.Init())
+	require.ErrorContains(t, plugin.Connect(), `accessing bucket "unknown" failed`)
+}
+
+func TestWrite(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	plugin := newPlugin(server.URL)
+	plugin.ServerSideEncryption = "aws:kms"
+	plugin.SSEKMSKeyID = "my-key"
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 3.0}, time.Unix(3, 0)),
+		testutil.MustMetric("mem", map[string]string{"host": "a"}, map[string]interface{}{"value": 4.0}, time.Unix(4, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	s.Lock()
+	defer s.Unlock()
+	expected := map[string][]byte{
+		"cpu/a.influx": []byte("cpu,host=a value=1 1000000000\ncpu,host=a value=3 3000000000\n"),
+		"cpu/b.influx": []byte("cpu,host=b value=2 2000000000\n"),
+		"mem/a.influx": []byte("mem,host=a value=4 4000000000\n"),
+	}
+	require.Equal(t, expected, s.objects)
+
+	header := s.headers["cpu/a.influx"]
+	require.Equal(t, "application/octet-stream", header.Get("Content-Type"))
+	require.Equal(t, "aws:kms", header.Get("X-Amz-Server-Side-Encryption"))
+	require.Equal(t, "my-key", header.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+}
+
+func TestBatchKey(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	plugin := newPlugin(server.URL)
+	plugin.Key = `/telegraf/{{now.Format "2006"}}/{{batch_id}}`
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+	require.NoError(t, plugin.Write(metrics))
+
+	// All metrics of a batch end up in the same object, each batch in a new
+	// object
+	s.Lock()
+	defer s.Unlock()
+	require.Len(t, s.objects, 2)
+	prefix := "telegraf/" + time.Now().Format("2006") + "/"
+	for key, data := range s.objects {
+		require.True(t, strings.HasPrefix(key, prefix), key)
+		require.Equal(t, "cpu value=1 1000000000\nmem value=2 2000000000\n", string(data))
+	}
+}
+
+func TestMultipart(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	plugin := newPlugin(server.URL)
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	value := strings.Repeat("x", 3*1024*1024)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("log", map[string]string{"host": "a"}, map[string]interface{}{"message": value}, time.Unix(1, 0)),
+		testutil.MustMetric("log", map[string]string{"host": "a"}, map[string]interface{}{"message": value}, time.Unix(2, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	s.Lock()
+	defer s.Unlock()
+	require.Contains(t, s.requests, "CreateMultipartUpload")
+	require.Contains(t, s.requests, "CompleteMultipartUpload")
+	require.NotContains(t, s.requests, "PutObject")
+
+	expected := fmt.Sprintf("log,host=a message=%q 1000000000\nlog,host=a message=%q 2000000000\n", value, value)
+	require.Equal(t, expected, string(s.objects["log/a.influx"]))
+}
+
+func TestParquet(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	plugin := newPlugin(server.URL)
+	plugin.Key = "{{.Name}}.parquet"
+	plugin.ObjectFormat = "parquet"
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// The last metric has an incompatible field type and is written to a
+	// separate file
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "c"}, map[string]interface{}{"value": "high"}, time.Unix(3, 0)),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	s.Lock()
+	defer s.Unlock()
+	require.Len(t, s.objects, 2)
+	require.Equal(t, "application/vnd.apache.parquet", s.headers["cpu.parquet"].Get("Content-Type"))
+
+	for key, rows := range map[string]int64{"cpu.parquet": 2, "cpu-1.parquet": 1} {
+		reader, err := file.NewParquetReader(bytes.NewReader(s.objects[key]))
+		require.NoError(t, err, key)
+		require.Equal(t, rows, reader.NumRows(), key)
+		require.Equal(t, 3, reader.MetaData().Schema.NumColumns(), key)
+		reader.Close()
+	}
+}
+
+func TestStaging(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	plugin := newPlugin(server.URL)
+	plugin.StagingDir = t.TempDir()
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Failing uploads keep the objects in the staging directory without
+	// returning an error
+	s.setFailing(http.StatusNotImplemented)
+	m1 := testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m1}))
+	names, _, err := plugin.staged()
+	require.NoError(t, err)
+	require.Len(t, names, 1)
+
+	// The staged object is uploaded with the next write
+	s.setFailing(0)
+	m2 := testutil.MustMetric("mem", map[string]string{"host": "a"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m2}))
+	names, _, err = plugin.staged()
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	s.Lock()
+	defer s.Unlock()
+	expected := map[string][]byte{
+		"cpu/a.influx": []byte("cpu,host=a value=1 1000000000\n"),
+		"mem/a.influx": []byte("mem,host=a value=2 2000000000\n"),
+	}
+	require.Equal(t, expected, s.objects)
+	require.Equal(t, []string{"HeadBucket", "PutObject", "PutObject"}, s.requests)
+}
+
+func TestStagingLongKey(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	// Keys may be up to 1024 bytes and exceed the filename limits
+	key := "cpu/" + strings.Repeat("a", 1013) + ".influx"
+	plugin := newPlugin(server.URL)
+	plugin.Key = key
+	plugin.StagingDir = t.TempDir()
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	s.setFailing(http.StatusNotImplemented)
+	m := testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m}))
+	names, _, err := plugin.staged()
+	require.NoError(t, err)
+	require.Len(t, names, 1)
+	buf, err := os.ReadFile(filepath.Join(plugin.StagingDir, names[0]+".key"))
+	require.NoError(t, err)
+	require.Equal(t, key, string(buf))
+
+	s.setFailing(0)
+	require.NoError(t, plugin.uploadStaged())
+	names, size, err := plugin.staged()
+	require.NoError(t, err)
+	require.Empty(t, names)
+	require.Zero(t, size)
+
+	s.Lock()
+	defer s.Unlock()
+	require.Equal(t, map[string][]byte{key: []byte("cpu,host=a value=1 1000000000\n")}, s.objects)
+}
+
+func TestWriteFailWithoutStaging(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	plugin := newPlugin(server.URL)
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	s.setFailing(http.StatusForbidden)
+	m := testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m}), `uploading "cpu/a.influx" failed`)
+}
+
+func TestStagingPermanentFailure(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	plugin := newPlugin(server.URL)
+	plugin.StagingDir = t.TempDir()
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	// Objects denied by the storage are moved out of the way
+	s.setFailing(http.StatusForbidden)
+	m1 := testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m1}))
+	entries, err := os.ReadDir(filepath.Join(plugin.StagingDir, "failed"))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, entries[0].Name()+".key", entries[1].Name())
+
+	// The failed object does not block the following ones
+	s.setFailing(0)
+	m2 := testutil.MustMetric("mem", map[string]string{"host": "a"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m2}))
+	names, _, err := plugin.staged()
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	s.Lock()
+	defer s.Unlock()
+	expected := map[string][]byte{
+		"mem/a.influx": []byte("mem,host=a value=2 2000000000\n"),
+	}
+	require.Equal(t, expected, s.objects)
+}
+
+func TestStagingMaxSize(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	plugin := newPlugin(server.URL)
+	plugin.StagingDir = t.TempDir()
+	plugin.StagingMaxSize = 40
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	s.setFailing(http.StatusNotImplemented)
+	m1 := testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m1}))
+
+	// Exceeding the size fails the write to keep the metrics in the buffer
+	m2 := testutil.MustMetric("mem", map[string]string{"host": "a"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0))
+	require.ErrorContains(t, plugin.Write([]telegraf.Metric{m2}), "staging directory is full")
+	names, _, err := plugin.staged()
+	require.NoError(t, err)
+	require.Len(t, names, 1)
+
+	// Uploading the staged objects makes room for the new ones
+	s.setFailing(0)
+	require.NoError(t, plugin.Write([]telegraf.Metric{m2}))
+	names, _, err = plugin.staged()
+	require.NoError(t, err)
+	require.Empty(t, names)
+}
+
+func TestStagingUploadOnConnectAndClose(t *testing.T) {
+	s := newStub()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	dir := t.TempDir()
+	plugin := newPlugin(server.URL)
+	plugin.StagingDir = dir
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+
+	s.setFailing(http.StatusNotImplemented)
+	m1 := testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(1, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m1}))
+	require.NoError(t, plugin.Close())
+
+	// The object staged before is uploaded when connecting again
+	s.setFailing(0)
+	plugin = newPlugin(server.URL)
+	plugin.StagingDir = dir
+	plugin.SetSerializer(newSerializer(t))
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	names, _, err := plugin.staged()
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	// Objects staged during a write are uploaded when closing
+	s.setFailing(http.StatusNotImplemented)
+	m2 := testutil.MustMetric("mem", map[string]string{"host": "a"}, map[string]interface{}{"value": 2.0}, time.Unix(2, 0))
+	require.NoError(t, plugin.Write([]telegraf.Metric{m2}))
+	s.setFailing(0)
+	require.NoError(t, plugin.Close())
+	names, _, err = plugin.staged()
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	s.Lock()
+	defer s.Unlock()
+	require.Len(t, s.objects, 2)
+}
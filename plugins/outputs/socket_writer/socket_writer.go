@@ -2,6 +2,7 @@
 package socket_writer
 
 import (
+	"bytes"
 	"crypto/tls"
 	_ "embed"
 	"errors"
@@ -10,8 +11,10 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/hashicorp/golang-lru/v2/simplelru"
 	"github.com/mdlayher/vsock"
 
 	"github.com/influxdata/telegraf"
@@ -25,9 +28,12 @@ import (
 var sampleConfig string
 
 type SocketWriter struct {
-	ContentEncoding string `toml:"content_encoding"`
-	Address         string
-	KeepAlivePeriod *config.Duration
+	ContentEncoding   string `toml:"content_encoding"`
+	Address           string
+	KeepAlivePeriod   *config.Duration
+	ConnectionTimeout config.Duration `toml:"connection_timeout"`
+	MaxConnections    int             `toml:"max_connections"`
+	CloseIdleAfter    config.Duration `toml:"close_idle_after"`
 	common_tls.ClientConfig
 	Log telegraf.Logger `toml:"-"`
 
@@ -36,6 +42,16 @@ type SocketWriter struct {
 	encoder internal.ContentEncoder
 
 	net.Conn
+
+	// Connections for addresses generated from the metrics
+	addressTemplate *template.Template
+	conns           *simplelru.LRU[string, *connection]
+}
+
+// connection is an open connection to an address generated from metrics
+type connection struct {
+	conn     net.Conn
+	lastUsed time.Time
 }
 
 func (*SocketWriter) SampleConfig() string {
@@ -46,15 +62,64 @@ func (sw *SocketWriter) SetSerializer(s telegraf.Serializer) {
 	sw.serializer = s
 }
 
+func (sw *SocketWriter) Init() error {
+	if sw.MaxConnections <= 0 {
+		sw.MaxConnections = 100
+	}
+
+	if strings.Contains(sw.Address, "{{") {
+		funcs := template.FuncMap{"now": time.Now}
+		tmpl, err := template.New("address").Funcs(funcs).Parse(sw.Address)
+		if err != nil {
+			return fmt.Errorf("parsing address template failed: %w", err)
+		}
+		sw.addressTemplate = tmpl
+	}
+
+	return nil
+}
+
 func (sw *SocketWriter) Connect() error {
-	spl := strings.SplitN(sw.Address, "://", 2)
+	var err error
+	if sw.addressTemplate != nil {
+		// Connections are established on demand when writing
+		sw.encoder, err = internal.NewContentEncoder(sw.ContentEncoding)
+		if err != nil {
+			return err
+		}
+		sw.conns, err = simplelru.NewLRU(sw.MaxConnections, func(address string, c *connection) {
+			if err := c.conn.Close(); err != nil {
+				sw.Log.Debugf("Closing connection to %q failed: %v", address, err)
+			}
+		})
+		return err
+	}
+
+	c, err := sw.dial(sw.Address)
+	if err != nil {
+		return err
+	}
+
+	// set encoder
+	sw.encoder, err = internal.NewContentEncoder(sw.ContentEncoding)
+	if err != nil {
+		return err
+	}
+
+	sw.Conn = c
+	return nil
+}
+
+// dial connects to the given address
+func (sw *SocketWriter) dial(address string) (net.Conn, error) {
+	spl := strings.SplitN(address, "://", 2)
 	if len(spl) != 2 {
-		return fmt.Errorf("invalid address: %s", sw.Address)
+		return nil, fmt.Errorf("invalid address: %s", address)
 	}
 
 	tlsCfg, err := sw.ClientConfig.TLSConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var c net.Conn
@@ -64,51 +129,46 @@ func (sw *SocketWriter) Connect() error {
 
 		// Check address string for containing two
 		if len(addrTuple) < 2 {
-			return errors.New("port and/or CID number missing")
+			return nil, errors.New("port and/or CID number missing")
 		}
 
 		// Parse CID and port number from address string both being 32-bit
 		// source: https://man7.org/linux/man-pages/man7/vsock.7.html
 		cid, err := strconv.ParseUint(addrTuple[0], 10, 32)
 		if err != nil {
-			return fmt.Errorf("failed to parse CID %s: %w", addrTuple[0], err)
+			return nil, fmt.Errorf("failed to parse CID %s: %w", addrTuple[0], err)
 		}
 		if (cid >= uint64(math.Pow(2, 32))-1) && (cid <= 0) {
-			return fmt.Errorf("value of CID %d is out of range", cid)
+			return nil, fmt.Errorf("value of CID %d is out of range", cid)
 		}
 		port, err := strconv.ParseUint(addrTuple[1], 10, 32)
 		if err != nil {
-			return fmt.Errorf("failed to parse port number %s: %w", addrTuple[1], err)
+			return nil, fmt.Errorf("failed to parse port number %s: %w", addrTuple[1], err)
 		}
 		if (port >= uint64(math.Pow(2, 32))-1) && (port <= 0) {
-			return fmt.Errorf("port number %d is out of range", port)
+			return nil, fmt.Errorf("port number %d is out of range", port)
 		}
 		c, err = vsock.Dial(uint32(cid), uint32(port), nil)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	} else {
+		dialer := &net.Dialer{Timeout: time.Duration(sw.ConnectionTimeout)}
 		if tlsCfg == nil {
-			c, err = net.Dial(spl[0], spl[1])
+			c, err = dialer.Dial(spl[0], spl[1])
 		} else {
-			c, err = tls.Dial(spl[0], spl[1], tlsCfg)
+			c, err = tls.DialWithDialer(dialer, spl[0], spl[1], tlsCfg)
 		}
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	if err := sw.setKeepAlive(c); err != nil {
-		sw.Log.Debugf("Unable to configure keep alive (%s): %s", sw.Address, err)
-	}
-	// set encoder
-	sw.encoder, err = internal.NewContentEncoder(sw.ContentEncoding)
-	if err != nil {
-		return err
+		sw.Log.Debugf("Unable to configure keep alive (%s): %s", address, err)
 	}
 
-	sw.Conn = c
-	return nil
+	return c, nil
 }
 
 func (sw *SocketWriter) setKeepAlive(c net.Conn) error {
@@ -117,7 +177,7 @@ func (sw *SocketWriter) setKeepAlive(c net.Conn) error {
 	}
 	tcpc, ok := c.(*net.TCPConn)
 	if !ok {
-		return fmt.Errorf("cannot set keep alive on a %s socket", strings.SplitN(sw.Address, "://", 2)[0])
+		return fmt.Errorf("cannot set keep alive on a %s socket", c.LocalAddr().Network())
 	}
 	if *sw.KeepAlivePeriod == 0 {
 		return tcpc.SetKeepAlive(false)
@@ -132,6 +192,10 @@ func (sw *SocketWriter) setKeepAlive(c net.Conn) error {
 // If an error is encountered, it is up to the caller to retry the same write again later.
 // Not parallel safe.
 func (sw *SocketWriter) Write(metrics []telegraf.Metric) error {
+	if sw.addressTemplate != nil {
+		return sw.writeRouted(metrics)
+	}
+
 	if sw.Conn == nil {
 		// previous write failed with permanent error and socket was closed.
 		if err := sw.Connect(); err != nil {
@@ -140,15 +204,8 @@ func (sw *SocketWriter) Write(metrics []telegraf.Metric) error {
 	}
 
 	for _, m := range metrics {
-		bs, err := sw.serializer.Serialize(m)
-		if err != nil {
-			sw.Log.Debugf("Could not serialize metric: %v", err)
-			continue
-		}
-
-		bs, err = sw.encoder.Encode(bs)
-		if err != nil {
-			sw.Log.Debugf("Could not encode metric: %v", err)
+		bs, ok := sw.payload(m)
+		if !ok {
 			continue
 		}
 
@@ -168,8 +225,102 @@ func (sw *SocketWriter) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// writeRouted writes the metrics to the addresses generated from the metrics
+// using the address template. Metrics for failing addresses are kept for the
+// next write while the metrics of all other addresses are accepted.
+func (sw *SocketWriter) writeRouted(metrics []telegraf.Metric) error {
+	var wErr internal.PartialWriteError
+	var errs []error
+	failed := make(map[string]bool)
+
+	var buf bytes.Buffer
+	now := time.Now()
+	for i, raw := range metrics {
+		m := raw
+		if wm, ok := raw.(telegraf.UnwrappableMetric); ok {
+			m = wm.Unwrap()
+		}
+
+		buf.Reset()
+		if err := sw.addressTemplate.Execute(&buf, m); err != nil {
+			sw.Log.Errorf("Cannot create address for metric %v: %v", m, err)
+			wErr.MetricsReject = append(wErr.MetricsReject, i)
+			continue
+		}
+		address := buf.String()
+
+		// Do not try again for addresses already failing in this write
+		if failed[address] {
+			continue
+		}
+
+		bs, ok := sw.payload(raw)
+		if !ok {
+			wErr.MetricsReject = append(wErr.MetricsReject, i)
+			continue
+		}
+
+		c, found := sw.conns.Get(address)
+		if !found {
+			conn, err := sw.dial(address)
+			if err != nil {
+				failed[address] = true
+				errs = append(errs, fmt.Errorf("connecting to %q failed: %w", address, err))
+				continue
+			}
+			c = &connection{conn: conn}
+			sw.conns.Add(address, c)
+		}
+		c.lastUsed = now
+
+		if _, err := c.conn.Write(bs); err != nil {
+			// Drop the connection to reconnect with the next write
+			sw.conns.Remove(address)
+			failed[address] = true
+			errs = append(errs, fmt.Errorf("writing to %q failed: %w", address, err))
+			continue
+		}
+		wErr.MetricsAccept = append(wErr.MetricsAccept, i)
+	}
+
+	// Close connections not used for a while
+	if sw.CloseIdleAfter > 0 {
+		for _, address := range sw.conns.Keys() {
+			if c, found := sw.conns.Peek(address); found && now.Sub(c.lastUsed) > time.Duration(sw.CloseIdleAfter) {
+				sw.conns.Remove(address)
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	wErr.Err = errors.Join(errs...)
+	return &wErr
+}
+
+// payload serializes and encodes the metric, returning false if the metric
+// should be skipped
+func (sw *SocketWriter) payload(m telegraf.Metric) ([]byte, bool) {
+	bs, err := sw.serializer.Serialize(m)
+	if err != nil {
+		sw.Log.Debugf("Could not serialize metric: %v", err)
+		return nil, false
+	}
+
+	bs, err = sw.encoder.Encode(bs)
+	if err != nil {
+		sw.Log.Debugf("Could not encode metric: %v", err)
+		return nil, false
+	}
+	return bs, true
+}
+
 // Close closes the connection. Noop if already closed.
 func (sw *SocketWriter) Close() error {
+	if sw.conns != nil {
+		sw.conns.Purge()
+	}
 	if sw.Conn == nil {
 		return nil
 	}
@@ -180,6 +331,9 @@ func (sw *SocketWriter) Close() error {
 
 func init() {
 	outputs.Add("socket_writer", func() telegraf.Output {
-		return &SocketWriter{}
+		return &SocketWriter{
+			MaxConnections:    100,
+			ConnectionTimeout: config.Duration(10 * time.Second),
+		}
 	})
 }
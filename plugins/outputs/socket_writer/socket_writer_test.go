@@ -6,10 +6,14 @@ import (
 	"runtime"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/testutil"
 )
@@ -209,3 +213,118 @@ func TestSocketWriter_udp_gzip(t *testing.T) {
 
 	testSocketWriterPacket(t, sw, listener)
 }
+
+func TestSocketWriterTemplate(t *testing.T) {
+	listeners := make(map[string]net.PacketConn, 2)
+	for _, name := range []string{"a", "b"} {
+		listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		listeners[name] = listener
+	}
+
+	sw := newSocketWriter(t, `udp://{{.Tag "destination"}}`)
+	sw.Log = &testutil.Logger{}
+	require.NoError(t, sw.Init())
+	require.NoError(t, sw.Connect())
+	defer sw.Close()
+
+	metrics := []telegraf.Metric{
+		metric.New(
+			"test",
+			map[string]string{"destination": listeners["a"].LocalAddr().String()},
+			map[string]interface{}{"value": 1},
+			time.Unix(0, 0),
+		),
+		metric.New(
+			"test",
+			map[string]string{"destination": listeners["b"].LocalAddr().String()},
+			map[string]interface{}{"value": 2},
+			time.Unix(0, 0),
+		),
+	}
+	require.NoError(t, sw.Write(metrics))
+	require.Len(t, sw.conns.Keys(), 2)
+
+	buf := make([]byte, 256)
+	for name, expected := range map[string]string{"a": "value=1i", "b": "value=2i"} {
+		require.NoError(t, listeners[name].SetReadDeadline(time.Now().Add(time.Second)))
+		n, _, err := listeners[name].ReadFrom(buf)
+		require.NoError(t, err)
+		require.Contains(t, string(buf[:n]), expected)
+	}
+
+	// Idle connections are closed
+	sw.CloseIdleAfter = config.Duration(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	require.NoError(t, sw.Write(metrics[:1]))
+	require.Equal(t, []string{"udp://" + listeners["a"].LocalAddr().String()}, sw.conns.Keys())
+}
+
+func TestSocketWriterTemplateMaxConnections(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	_, port, err := net.SplitHostPort(listener.LocalAddr().String())
+	require.NoError(t, err)
+
+	sw := newSocketWriter(t, `udp://127.0.0.{{.Tag "host"}}:`+port)
+	sw.MaxConnections = 1
+	sw.Log = &testutil.Logger{}
+	require.NoError(t, sw.Init())
+	require.NoError(t, sw.Connect())
+	defer sw.Close()
+
+	for _, host := range []string{"1", "2"} {
+		m := metric.New("test", map[string]string{"host": host}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+		require.NoError(t, sw.Write([]telegraf.Metric{m}))
+	}
+	require.Equal(t, []string{"udp://127.0.0.2:" + port}, sw.conns.Keys())
+}
+
+func TestSocketWriterTemplatePartialWrite(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// Get an address without listener to refuse connections
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, closed.Close())
+
+	sw := newSocketWriter(t, `tcp://{{.Tag "destination"}}`)
+	sw.ConnectionTimeout = config.Duration(time.Second)
+	sw.Log = &testutil.Logger{}
+	require.NoError(t, sw.Init())
+	require.NoError(t, sw.Connect())
+	defer sw.Close()
+
+	metrics := make([]telegraf.Metric, 0, 4)
+	for i, addr := range []string{listener.Addr().String(), closed.Addr().String(), listener.Addr().String(), closed.Addr().String()} {
+		m := metric.New("test", map[string]string{"destination": addr}, map[string]interface{}{"value": i}, time.Unix(0, 0))
+		metrics = append(metrics, m)
+	}
+
+	// The metrics for the working address are accepted and the others kept
+	err = sw.Write(metrics)
+	require.ErrorContains(t, err, "connecting to \"tcp://"+closed.Addr().String()+"\" failed")
+	var wErr *internal.PartialWriteError
+	require.ErrorAs(t, err, &wErr)
+	require.Equal(t, []int{0, 2}, wErr.MetricsAccept)
+	require.Empty(t, wErr.MetricsReject)
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	scanner := bufio.NewScanner(conn)
+	require.True(t, scanner.Scan())
+	require.Equal(t, "test,destination="+listener.Addr().String()+" value=0i 0", scanner.Text())
+	require.True(t, scanner.Scan())
+	require.Equal(t, "test,destination="+listener.Addr().String()+" value=2i 0", scanner.Text())
+}
+
+func TestSocketWriterTemplateInvalid(t *testing.T) {
+	sw := newSocketWriter(t, `udp://{{.Tag "destination"`)
+	require.ErrorContains(t, sw.Init(), "parsing address template failed")
+}
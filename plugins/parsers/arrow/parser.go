@@ -0,0 +1,225 @@
+package arrow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/arrow"
+)
+
+type Parser struct {
+	MeasurementColumn string   `toml:"arrow_measurement_column"`
+	TagColumns        []string `toml:"arrow_tag_columns"`
+	TimestampColumn   string   `toml:"arrow_timestamp_column"`
+
+	defaultTags map[string]string
+	metricName  string
+}
+
+func (p *Parser) Init() error {
+	if p.TimestampColumn == "" {
+		p.TimestampColumn = "time"
+	}
+	return nil
+}
+
+// Parse reads one or more concatenated Arrow IPC streams
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	now := time.Now()
+
+	var metrics []telegraf.Metric
+	r := bytes.NewReader(buf)
+	for r.Len() > 0 {
+		reader, err := ipc.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create arrow reader: %w", err)
+		}
+
+		for reader.Next() {
+			m, err := p.parseRecord(reader.Record(), now)
+			if err != nil {
+				reader.Release()
+				return nil, err
+			}
+			metrics = append(metrics, m...)
+		}
+		err = reader.Err()
+		reader.Release()
+		if err != nil {
+			return nil, fmt.Errorf("reading record failed: %w", err)
+		}
+	}
+
+	return metrics, nil
+}
+
+func (p *Parser) parseRecord(record arrow.Record, now time.Time) ([]telegraf.Metric, error) {
+	schema := record.Schema()
+
+	name := p.metricName
+	if md := schema.Metadata(); md.FindKey(serializer.MeasurementKey) >= 0 {
+		name = md.Values()[md.FindKey(serializer.MeasurementKey)]
+	}
+
+	metrics := make([]telegraf.Metric, 0, record.NumRows())
+	for range record.NumRows() {
+		metrics = append(metrics, metric.New(name, p.defaultTags, nil, now))
+	}
+
+	for i, col := range record.Columns() {
+		field := schema.Field(i)
+		kind := p.columnKind(field)
+		for row, m := range metrics {
+			if col.IsNull(row) {
+				continue
+			}
+
+			if kind == serializer.ColumnTimestamp {
+				ts, err := timestamp(col, row)
+				if err != nil {
+					return nil, fmt.Errorf("invalid timestamp column %q: %w", field.Name, err)
+				}
+				m.SetTime(ts)
+				continue
+			}
+
+			value, err := value(col, row)
+			if err != nil {
+				return nil, fmt.Errorf("invalid column %q: %w", field.Name, err)
+			}
+			switch kind {
+			case "measurement":
+				v, err := internal.ToString(value)
+				if err != nil {
+					return nil, fmt.Errorf("could not convert value to string: %w", err)
+				}
+				m.SetName(v)
+			case serializer.ColumnTag:
+				v, err := internal.ToString(value)
+				if err != nil {
+					return nil, fmt.Errorf("could not convert value to string: %w", err)
+				}
+				m.AddTag(field.Name, v)
+			default:
+				m.AddField(field.Name, value)
+			}
+		}
+	}
+
+	return metrics, nil
+}
+
+// Determine the kind of the column either from the metadata written by the
+// arrow serializer or from the configured column names
+func (p *Parser) columnKind(field arrow.Field) string {
+	switch {
+	case p.MeasurementColumn != "" && field.Name == p.MeasurementColumn:
+		return "measurement"
+	case field.Metadata.FindKey(serializer.ColumnKey) >= 0:
+		return field.Metadata.Values()[field.Metadata.FindKey(serializer.ColumnKey)]
+	case field.Name == p.TimestampColumn:
+		return serializer.ColumnTimestamp
+	case slices.Contains(p.TagColumns, field.Name):
+		return serializer.ColumnTag
+	}
+	return serializer.ColumnField
+}
+
+func timestamp(col arrow.Array, row int) (time.Time, error) {
+	switch c := col.(type) {
+	case *array.Timestamp:
+		toTime, err := c.DataType().(*arrow.TimestampType).GetToTimeFunc()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return toTime(c.Value(row)), nil
+	case *array.Date64:
+		return c.Value(row).ToTime(), nil
+	case *array.Int64:
+		return time.Unix(0, c.Value(row)), nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported type %s", col.DataType())
+}
+
+func value(col arrow.Array, row int) (interface{}, error) {
+	switch c := col.(type) {
+	case *array.Int8:
+		return int64(c.Value(row)), nil
+	case *array.Int16:
+		return int64(c.Value(row)), nil
+	case *array.Int32:
+		return int64(c.Value(row)), nil
+	case *array.Int64:
+		return c.Value(row), nil
+	case *array.Uint8:
+		return uint64(c.Value(row)), nil
+	case *array.Uint16:
+		return uint64(c.Value(row)), nil
+	case *array.Uint32:
+		return uint64(c.Value(row)), nil
+	case *array.Uint64:
+		return c.Value(row), nil
+	case *array.Float16:
+		return float64(c.Value(row).Float32()), nil
+	case *array.Float32:
+		return float64(c.Value(row)), nil
+	case *array.Float64:
+		return c.Value(row), nil
+	case *array.String:
+		return c.Value(row), nil
+	case *array.LargeString:
+		return c.Value(row), nil
+	case *array.Binary:
+		return string(c.Value(row)), nil
+	case *array.Boolean:
+		return c.Value(row), nil
+	case *array.Timestamp:
+		ts, err := timestamp(c, row)
+		if err != nil {
+			return nil, err
+		}
+		return ts.UnixNano(), nil
+	case *array.Dictionary:
+		return value(c.Dictionary(), c.GetValueIndex(row))
+	}
+	return nil, fmt.Errorf("unsupported type %s", col.DataType())
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, nil
+	}
+	if len(metrics) > 1 {
+		return nil, errors.New("line contains multiple metrics")
+	}
+
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func init() {
+	parsers.Add("arrow",
+		func(defaultMetricName string) telegraf.Parser {
+			return &Parser{metricName: defaultMetricName}
+		},
+	)
+}
@@ -0,0 +1,128 @@
+package arrow
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/arrow"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestRoundTrip(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5, "count": int64(3)},
+			time.Unix(1700000000, 1),
+		),
+		metric.New("mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"used": uint64(10), "ok": true, "state": "fine"},
+			time.Unix(1700000000, 2),
+		),
+		metric.New("cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 10.0},
+			time.Unix(1700000000, 3),
+		),
+	}
+
+	s := &serializer.Serializer{Compression: "zstd"}
+	require.NoError(t, s.Init())
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	parser := &Parser{metricName: "arrow"}
+	require.NoError(t, parser.Init())
+	actual, err := parser.Parse(buf)
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t, metrics, actual, testutil.SortMetrics())
+}
+
+func TestForeignSchema(t *testing.T) {
+	// Build a record without the metadata written by the serializer
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Millisecond}},
+		{Name: "host", Type: &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}},
+		{Name: "temp", Type: arrow.PrimitiveTypes.Float32, Nullable: true},
+		{Name: "level", Type: arrow.PrimitiveTypes.Int16, Nullable: true},
+	}, nil)
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+	builder.Field(0).(*array.StringBuilder).AppendValues([]string{"sensor", "sensor"}, nil)
+	builder.Field(1).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{1700000000000, 1700000001000}, nil)
+	require.NoError(t, builder.Field(2).(*array.BinaryDictionaryBuilder).AppendString("a"))
+	require.NoError(t, builder.Field(2).(*array.BinaryDictionaryBuilder).AppendString("b"))
+	builder.Field(3).(*array.Float32Builder).AppendValues([]float32{21.5, 0}, []bool{true, false})
+	builder.Field(4).(*array.Int16Builder).AppendValues([]int16{1, 2}, nil)
+	record := builder.NewRecord()
+	defer record.Release()
+
+	var buf bytes.Buffer
+	writer := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Close())
+
+	parser := &Parser{
+		MeasurementColumn: "name",
+		TagColumns:        []string{"host"},
+		TimestampColumn:   "ts",
+		metricName:        "arrow",
+	}
+	require.NoError(t, parser.Init())
+	parser.SetDefaultTags(map[string]string{"source": "test"})
+	actual, err := parser.Parse(buf.Bytes())
+	require.NoError(t, err)
+
+	// Null values must be skipped
+	expected := []telegraf.Metric{
+		metric.New("sensor",
+			map[string]string{"host": "a", "source": "test"},
+			map[string]interface{}{"temp": 21.5, "level": int64(1)},
+			time.Unix(1700000000, 0),
+		),
+		metric.New("sensor",
+			map[string]string{"host": "b", "source": "test"},
+			map[string]interface{}{"level": int64(2)},
+			time.Unix(1700000001, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParseLine(t *testing.T) {
+	s := &serializer.Serializer{}
+	require.NoError(t, s.Init())
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	parser := &Parser{}
+	require.NoError(t, parser.Init())
+	actual, err := parser.ParseLine(string(buf))
+	require.NoError(t, err)
+	testutil.RequireMetricEqual(t, m, actual)
+
+	buf, err = s.SerializeBatch([]telegraf.Metric{m, m})
+	require.NoError(t, err)
+	_, err = parser.ParseLine(string(buf))
+	require.ErrorContains(t, err, "line contains multiple metrics")
+}
+
+func TestInvalidData(t *testing.T) {
+	parser := &Parser{}
+	require.NoError(t, parser.Init())
+	_, err := parser.Parse([]byte("cpu value=1"))
+	require.ErrorContains(t, err, "unable to create arrow reader")
+}
@@ -0,0 +1,5 @@
+//go:build !custom || processors || processors.container_metadata
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/processors/container_metadata" // register plugin
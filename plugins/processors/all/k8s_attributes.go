@@ -0,0 +1,5 @@
+//go:build !custom || processors || processors.k8s_attributes
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/processors/k8s_attributes" // register plugin
@@ -0,0 +1,5 @@
+//go:build !custom || processors || processors.sql_lookup
+
+package all
+
+import _ "github.com/influxdata/telegraf/plugins/processors/sql_lookup" // register plugin
@@ -0,0 +1,290 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package container_metadata
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/docker"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+// Container IDs are 64 hex characters and appear in cgroup paths in
+// different forms depending on the runtime and the cgroup driver, e.g.
+// "/docker/<id>", "/system.slice/docker-<id>.scope" or
+// "/kubepods/.../cri-containerd-<id>.scope"
+var containerIDRe = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// dockerClient is the subset of the Docker API used to resolve container
+// metadata
+type dockerClient interface {
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	Close() error
+}
+
+type ContainerMetadata struct {
+	Endpoint       string          `toml:"endpoint"`
+	Timeout        config.Duration `toml:"timeout"`
+	PIDTag         string          `toml:"pid_tag"`
+	PIDField       string          `toml:"pid_field"`
+	CgroupTags     []string        `toml:"cgroup_tags"`
+	ContainerIDTag string          `toml:"container_id_tag"`
+	LabelInclude   []string        `toml:"label_include"`
+	LabelExclude   []string        `toml:"label_exclude"`
+	CacheSize      int             `toml:"cache_size"`
+	CacheTTL       config.Duration `toml:"cache_ttl"`
+	NegativeTTL    config.Duration `toml:"negative_cache_ttl"`
+	Log            telegraf.Logger `toml:"-"`
+	tls.ClientConfig
+
+	client         dockerClient
+	labelFilter    filter.Filter
+	pidCache       *expirable.LRU[int64, string]
+	containerCache *expirable.LRU[string, map[string]string]
+	negativeCache  *expirable.LRU[string, bool]
+}
+
+func (*ContainerMetadata) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *ContainerMetadata) Init() error {
+	if c.ContainerIDTag == "" {
+		return errors.New("'container_id_tag' must be set")
+	}
+	if c.CacheSize <= 0 {
+		return errors.New("'cache_size' must be greater than zero")
+	}
+
+	labelFilter, err := filter.NewIncludeExcludeFilter(c.LabelInclude, c.LabelExclude)
+	if err != nil {
+		return fmt.Errorf("creating label filter failed: %w", err)
+	}
+	c.labelFilter = labelFilter
+
+	c.pidCache = expirable.NewLRU[int64, string](c.CacheSize, nil, time.Duration(c.CacheTTL))
+	c.containerCache = expirable.NewLRU[string, map[string]string](c.CacheSize, nil, time.Duration(c.CacheTTL))
+	c.negativeCache = expirable.NewLRU[string, bool](c.CacheSize, nil, time.Duration(c.NegativeTTL))
+
+	if c.client != nil {
+		return nil
+	}
+
+	var cl *client.Client
+	if c.Endpoint == "ENV" {
+		cl, err = docker.NewEnvClient()
+	} else {
+		tlsConfig, terr := c.ClientConfig.TLSConfig()
+		if terr != nil {
+			return terr
+		}
+		cl, err = docker.NewClient(c.Endpoint, tlsConfig)
+	}
+	if err != nil {
+		return fmt.Errorf("creating docker client failed: %w", err)
+	}
+	c.client = cl
+
+	return nil
+}
+
+func (*ContainerMetadata) Start(telegraf.Accumulator) error {
+	return nil
+}
+
+func (c *ContainerMetadata) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	c.enrich(m)
+	acc.AddMetric(m)
+	return nil
+}
+
+func (c *ContainerMetadata) Stop() {
+	if c.client == nil {
+		return
+	}
+	if err := c.client.Close(); err != nil {
+		c.Log.Errorf("Closing docker client failed: %v", err)
+	}
+	c.client = nil
+}
+
+// Add the container ID and metadata tags to the metric
+func (c *ContainerMetadata) enrich(m telegraf.Metric) {
+	id := c.containerID(m)
+	if id == "" {
+		return
+	}
+	m.AddTag(c.ContainerIDTag, id)
+
+	tags, err := c.metadata(id)
+	if err != nil {
+		c.Log.Errorf("Resolving container %q failed: %v", id, err)
+		return
+	}
+	for k, v := range tags {
+		m.AddTag(k, v)
+	}
+}
+
+// Determine the container ID using an existing container-ID tag, the cgroup
+// tags or the process ID in this order
+func (c *ContainerMetadata) containerID(m telegraf.Metric) string {
+	if id, found := m.GetTag(c.ContainerIDTag); found && id != "" {
+		return id
+	}
+
+	for _, tag := range c.CgroupTags {
+		if path, found := m.GetTag(tag); found {
+			if id := containerIDFromCgroup(path); id != "" {
+				return id
+			}
+		}
+	}
+
+	pid, found := c.pid(m)
+	if !found {
+		return ""
+	}
+	if id, found := c.pidCache.Get(pid); found {
+		return id
+	}
+
+	// Cache the result even if the process does not belong to a container to
+	// avoid reading the cgroup file over and over again
+	id, err := containerIDFromPID(pid)
+	if err != nil {
+		c.Log.Debugf("Reading cgroup of process %d failed: %v", pid, err)
+	}
+	c.pidCache.Add(pid, id)
+
+	return id
+}
+
+func (c *ContainerMetadata) pid(m telegraf.Metric) (int64, bool) {
+	if c.PIDTag != "" {
+		if v, found := m.GetTag(c.PIDTag); found {
+			pid, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				c.Log.Debugf("Invalid PID tag %q: %v", v, err)
+				return 0, false
+			}
+			return pid, true
+		}
+	}
+	if c.PIDField != "" {
+		if v, found := m.GetField(c.PIDField); found {
+			pid, err := internal.ToInt64(v)
+			if err != nil {
+				c.Log.Debugf("Invalid PID field %v: %v", v, err)
+				return 0, false
+			}
+			return pid, true
+		}
+	}
+	return 0, false
+}
+
+// Get the tags of the container with the given ID either from cache or from
+// the container runtime
+func (c *ContainerMetadata) metadata(id string) (map[string]string, error) {
+	if tags, found := c.containerCache.Get(id); found {
+		return tags, nil
+	}
+	if _, found := c.negativeCache.Get(id); found {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(c.Timeout))
+	defer cancel()
+	info, err := c.client.ContainerInspect(ctx, id)
+	if err != nil {
+		if docker.IsErrNotFound(err) {
+			c.negativeCache.Add(id, true)
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	tags["container_name"] = strings.TrimPrefix(info.Name, "/")
+	if info.Config != nil {
+		imageName, imageVersion := docker.ParseImage(info.Config.Image)
+		tags["container_image"] = imageName
+		tags["container_version"] = imageVersion
+		for k, v := range info.Config.Labels {
+			if c.labelFilter.Match(k) {
+				tags[k] = v
+			}
+		}
+	}
+	c.containerCache.Add(id, tags)
+
+	return tags, nil
+}
+
+func containerIDFromCgroup(path string) string {
+	matches := containerIDRe.FindAllString(path, -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[len(matches)-1]
+}
+
+func containerIDFromPID(pid int64) (string, error) {
+	fn := filepath.Join(internal.GetProcPath(), strconv.FormatInt(pid, 10), "cgroup")
+	file, err := os.Open(fn)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// Each line has the form "hierarchy-ID:controller-list:cgroup-path"
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if id := containerIDFromCgroup(parts[2]); id != "" {
+			return id, nil
+		}
+	}
+	return "", scanner.Err()
+}
+
+func init() {
+	processors.AddStreaming("container_metadata", func() telegraf.StreamingProcessor {
+		return &ContainerMetadata{
+			Endpoint:       "unix:///var/run/docker.sock",
+			Timeout:        config.Duration(5 * time.Second),
+			PIDTag:         "pid",
+			PIDField:       "pid",
+			CgroupTags:     []string{"cgroup", "cgroup_full", "path"},
+			ContainerIDTag: "container_id",
+			CacheSize:      1000,
+			CacheTTL:       config.Duration(10 * time.Minute),
+			NegativeTTL:    config.Duration(time.Minute),
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package container_metadata
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+const webID = "4b8e6d1c0f3a9b2e7d5c1a3f5e7d9b1c3a5f7e9d1b3c5a7f9e1d3b5c7a9f1e3d"
+
+type mockClient struct {
+	containers map[string]types.ContainerJSON
+	calls      int
+	closed     bool
+}
+
+func (c *mockClient) ContainerInspect(_ context.Context, id string) (types.ContainerJSON, error) {
+	c.calls++
+	if info, found := c.containers[id]; found {
+		return info, nil
+	}
+	return types.ContainerJSON{}, errdefs.NotFound(errors.New("no such container"))
+}
+
+func (c *mockClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func newPlugin(client *mockClient) *ContainerMetadata {
+	return &ContainerMetadata{
+		Timeout:        config.Duration(time.Second),
+		PIDTag:         "pid",
+		PIDField:       "pid",
+		CgroupTags:     []string{"cgroup", "path"},
+		ContainerIDTag: "container_id",
+		LabelExclude:   []string{"internal.*"},
+		CacheSize:      10,
+		CacheTTL:       config.Duration(time.Minute),
+		NegativeTTL:    config.Duration(time.Minute),
+		Log:            &testutil.Logger{},
+		client:         client,
+	}
+}
+
+func newMockClient() *mockClient {
+	return &mockClient{
+		containers: map[string]types.ContainerJSON{
+			webID: {
+				ContainerJSONBase: &types.ContainerJSONBase{Name: "/web"},
+				Config: &container.Config{
+					Image:  "nginx:1.27",
+					Labels: map[string]string{"app": "shop", "internal.hash": "abc"},
+				},
+			},
+		},
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Setenv("HOST_PROC", "testdata/proc")
+
+	client := newMockClient()
+	plugin := newPlugin(client)
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	input := []telegraf.Metric{
+		metric.New("procstat",
+			map[string]string{"pid": "100"},
+			map[string]interface{}{"cpu_usage": 1.5}, now),
+		metric.New("procstat",
+			map[string]string{},
+			map[string]interface{}{"pid": int32(100), "cpu_usage": 2.5}, now),
+		metric.New("cgroup",
+			map[string]string{"path": "/sys/fs/cgroup/system.slice/docker-" + webID + ".scope"},
+			map[string]interface{}{"memory.current": int64(42)}, now),
+		metric.New("procstat",
+			map[string]string{"pid": "200"},
+			map[string]interface{}{"cpu_usage": 3.5}, now),
+		metric.New("procstat",
+			map[string]string{"pid": "300"},
+			map[string]interface{}{"cpu_usage": 4.5}, now),
+	}
+
+	tags := map[string]string{
+		"container_id":      webID,
+		"container_name":    "web",
+		"container_image":   "nginx",
+		"container_version": "1.27",
+		"app":               "shop",
+	}
+	withTags := func(extra map[string]string) map[string]string {
+		result := make(map[string]string, len(tags)+len(extra))
+		for k, v := range tags {
+			result[k] = v
+		}
+		for k, v := range extra {
+			result[k] = v
+		}
+		return result
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("procstat",
+			withTags(map[string]string{"pid": "100"}),
+			map[string]interface{}{"cpu_usage": 1.5}, now),
+		metric.New("procstat",
+			withTags(nil),
+			map[string]interface{}{"pid": int32(100), "cpu_usage": 2.5}, now),
+		metric.New("cgroup",
+			withTags(map[string]string{"path": "/sys/fs/cgroup/system.slice/docker-" + webID + ".scope"}),
+			map[string]interface{}{"memory.current": int64(42)}, now),
+		metric.New("procstat",
+			map[string]string{"pid": "200"},
+			map[string]interface{}{"cpu_usage": 3.5}, now),
+		metric.New("procstat",
+			map[string]string{"pid": "300"},
+			map[string]interface{}{"cpu_usage": 4.5}, now),
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	plugin.Stop()
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.True(t, client.closed)
+
+	// The container should only be inspected once
+	require.Equal(t, 1, client.calls)
+}
+
+func TestUnknownContainer(t *testing.T) {
+	client := newMockClient()
+	plugin := newPlugin(client)
+	require.NoError(t, plugin.Init())
+
+	id := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	input := []telegraf.Metric{
+		metric.New("cgroup",
+			map[string]string{"cgroup": "/kubepods/besteffort/pod1/cri-containerd-" + id + ".scope"},
+			map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		metric.New("cgroup",
+			map[string]string{"cgroup": "/kubepods/besteffort/pod1/cri-containerd-" + id + ".scope"},
+			map[string]interface{}{"value": 2}, time.Unix(1, 0)),
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("cgroup",
+			map[string]string{
+				"cgroup":       "/kubepods/besteffort/pod1/cri-containerd-" + id + ".scope",
+				"container_id": id,
+			},
+			map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+		metric.New("cgroup",
+			map[string]string{
+				"cgroup":       "/kubepods/besteffort/pod1/cri-containerd-" + id + ".scope",
+				"container_id": id,
+			},
+			map[string]interface{}{"value": 2}, time.Unix(1, 0)),
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	plugin.Stop()
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+	require.True(t, client.closed)
+
+	// Unknown containers must be cached as well
+	require.Equal(t, 1, client.calls)
+}
@@ -0,0 +1,306 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package k8s_attributes
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	common_kubernetes "github.com/influxdata/telegraf/plugins/common/kubernetes"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+const (
+	defaultServiceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	indexPodUID      = "uid"
+	indexContainerID = "container_id"
+	indexPodIP       = "ip"
+
+	// Length of the short container ID as used e.g. by docker
+	shortContainerIDLength = 12
+)
+
+type K8sAttributes struct {
+	URL              string          `toml:"url"`
+	BearerToken      string          `toml:"bearer_token"`
+	Namespace        string          `toml:"namespace"`
+	NodeName         string          `toml:"node_name"`
+	ResyncInterval   config.Duration `toml:"resync_interval"`
+	StartupTimeout   config.Duration `toml:"startup_timeout"`
+	PodUIDTag        string          `toml:"pod_uid_tag"`
+	ContainerIDTag   string          `toml:"container_id_tag"`
+	PodIPTag         string          `toml:"pod_ip_tag"`
+	Labels           []string        `toml:"labels"`
+	LabelPrefix      string          `toml:"label_prefix"`
+	Annotations      []string        `toml:"annotations"`
+	AnnotationPrefix string          `toml:"annotation_prefix"`
+	Log              telegraf.Logger `toml:"-"`
+	tls.ClientConfig
+
+	client           kubernetes.Interface
+	labelFilter      filter.Filter
+	annotationFilter filter.Filter
+	indexer          cache.Indexer
+	cancel           context.CancelFunc
+}
+
+func (*K8sAttributes) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *K8sAttributes) Init() error {
+	if k.PodUIDTag == "" && k.ContainerIDTag == "" && k.PodIPTag == "" {
+		return errors.New("at least one of 'pod_uid_tag', 'container_id_tag' or 'pod_ip_tag' must be set")
+	}
+
+	var err error
+	if k.labelFilter, err = filter.Compile(k.Labels); err != nil {
+		return fmt.Errorf("creating label filter failed: %w", err)
+	}
+	if k.annotationFilter, err = filter.Compile(k.Annotations); err != nil {
+		return fmt.Errorf("creating annotation filter failed: %w", err)
+	}
+
+	if k.client == nil {
+		if k.BearerToken == "" {
+			k.BearerToken = defaultServiceAccountPath
+		}
+		if k.client, err = common_kubernetes.NewClientset(k.URL, k.BearerToken, "", k.ClientConfig); err != nil {
+			return fmt.Errorf("creating kubernetes client failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (k *K8sAttributes) Start(telegraf.Accumulator) error {
+	options := []informers.SharedInformerOption{
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			if k.NodeName != "" {
+				options.FieldSelector = fields.OneTermEqualSelector("spec.nodeName", k.NodeName).String()
+			}
+		}),
+	}
+	if k.Namespace != "" {
+		options = append(options, informers.WithNamespace(k.Namespace))
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(k.client, time.Duration(k.ResyncInterval), options...)
+
+	informer := factory.Core().V1().Pods().Informer()
+	err := informer.AddIndexers(cache.Indexers{
+		indexPodUID:      indexByPodUID,
+		indexContainerID: indexByContainerID,
+		indexPodIP:       indexByPodIP,
+	})
+	if err != nil {
+		return fmt.Errorf("adding indexers failed: %w", err)
+	}
+	k.indexer = informer.GetIndexer()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	k.cancel = cancel
+	factory.Start(ctx.Done())
+
+	// Wait for the initial listing to complete so the first metrics can be
+	// enriched already
+	syncCtx, syncCancel := context.WithTimeout(ctx, time.Duration(k.StartupTimeout))
+	defer syncCancel()
+	if !cache.WaitForCacheSync(syncCtx.Done(), informer.HasSynced) {
+		cancel()
+		return errors.New("timeout waiting for pod cache to sync")
+	}
+
+	return nil
+}
+
+func (k *K8sAttributes) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	if pod := k.lookup(m); pod != nil {
+		k.enrich(m, pod)
+	}
+	acc.AddMetric(m)
+	return nil
+}
+
+func (k *K8sAttributes) Stop() {
+	if k.cancel != nil {
+		k.cancel()
+	}
+}
+
+// Find the pod referenced by the metric using the configured tags in the
+// order pod UID, container ID and pod IP
+func (k *K8sAttributes) lookup(m telegraf.Metric) *corev1.Pod {
+	candidates := []struct {
+		tag   string
+		index string
+		key   func(string) string
+	}{
+		{k.PodUIDTag, indexPodUID, func(v string) string { return v }},
+		{k.ContainerIDTag, indexContainerID, normalizeContainerID},
+		{k.PodIPTag, indexPodIP, func(v string) string { return v }},
+	}
+
+	for _, c := range candidates {
+		if c.tag == "" {
+			continue
+		}
+		value, found := m.GetTag(c.tag)
+		if !found || value == "" {
+			continue
+		}
+		objs, err := k.indexer.ByIndex(c.index, c.key(value))
+		if err != nil {
+			k.Log.Errorf("Looking up %q in index %q failed: %v", value, c.index, err)
+			continue
+		}
+		if len(objs) != 1 {
+			if len(objs) > 1 {
+				k.Log.Debugf("Ambiguous pod for %s %q", c.tag, value)
+			}
+			continue
+		}
+		if pod, ok := objs[0].(*corev1.Pod); ok {
+			return pod
+		}
+	}
+
+	return nil
+}
+
+func (k *K8sAttributes) enrich(m telegraf.Metric, pod *corev1.Pod) {
+	m.AddTag("pod_name", pod.Name)
+	m.AddTag("namespace", pod.Namespace)
+	if pod.Spec.NodeName != "" {
+		m.AddTag("node_name", pod.Spec.NodeName)
+	}
+
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+		switch owner.Kind {
+		case "ReplicaSet":
+			// Derive the deployment from the replica-set name to avoid watching
+			// replica sets. The name is suffixed by the pod-template hash.
+			hash := pod.Labels["pod-template-hash"]
+			if hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+				m.AddTag("deployment", strings.TrimSuffix(owner.Name, "-"+hash))
+			}
+		case "StatefulSet":
+			m.AddTag("statefulset", owner.Name)
+		case "DaemonSet":
+			m.AddTag("daemonset", owner.Name)
+		}
+	}
+
+	if len(k.Labels) > 0 {
+		for key, value := range pod.Labels {
+			if k.labelFilter.Match(key) {
+				m.AddTag(k.LabelPrefix+key, value)
+			}
+		}
+	}
+	if len(k.Annotations) > 0 {
+		for key, value := range pod.Annotations {
+			if k.annotationFilter.Match(key) {
+				m.AddTag(k.AnnotationPrefix+key, value)
+			}
+		}
+	}
+}
+
+func indexByPodUID(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, nil
+	}
+	return []string{string(pod.UID)}, nil
+}
+
+func indexByContainerID(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, nil
+	}
+
+	statuses := make([]corev1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+
+	keys := make([]string, 0, 2*len(statuses))
+	for _, status := range statuses {
+		id := normalizeContainerID(status.ContainerID)
+		if id == "" {
+			continue
+		}
+		keys = append(keys, id)
+		if len(id) > shortContainerIDLength {
+			keys = append(keys, id[:shortContainerIDLength])
+		}
+	}
+	return keys, nil
+}
+
+func indexByPodIP(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, nil
+	}
+
+	// Pods in the host network share the node's IP and cannot be identified.
+	// Terminated pods might have released their IP to a new pod.
+	if pod.Spec.HostNetwork || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, len(pod.Status.PodIPs)+1)
+	if pod.Status.PodIP != "" {
+		keys = append(keys, pod.Status.PodIP)
+	}
+	for _, ip := range pod.Status.PodIPs {
+		if ip.IP != "" && ip.IP != pod.Status.PodIP {
+			keys = append(keys, ip.IP)
+		}
+	}
+	return keys, nil
+}
+
+// Strip the runtime prefix such as "containerd://" from the container ID
+func normalizeContainerID(id string) string {
+	if _, after, found := strings.Cut(id, "://"); found {
+		return after
+	}
+	return id
+}
+
+func init() {
+	processors.AddStreaming("k8s_attributes", func() telegraf.StreamingProcessor {
+		return &K8sAttributes{
+			ResyncInterval:   config.Duration(60 * time.Minute),
+			StartupTimeout:   config.Duration(30 * time.Second),
+			PodUIDTag:        "pod_uid",
+			ContainerIDTag:   "container_id",
+			PodIPTag:         "pod_ip",
+			LabelPrefix:      "label_",
+			AnnotationPrefix: "annotation_",
+		}
+	})
+}
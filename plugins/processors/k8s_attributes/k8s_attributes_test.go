@@ -0,0 +1,210 @@
+package k8s_attributes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func newPlugin() *K8sAttributes {
+	return &K8sAttributes{
+		ResyncInterval:   config.Duration(time.Minute),
+		StartupTimeout:   config.Duration(5 * time.Second),
+		PodUIDTag:        "pod_uid",
+		ContainerIDTag:   "container_id",
+		PodIPTag:         "pod_ip",
+		Labels:           []string{"app"},
+		LabelPrefix:      "label_",
+		Annotations:      []string{"team.example.com/*"},
+		AnnotationPrefix: "annotation_",
+		Log:              &testutil.Logger{},
+	}
+}
+
+func TestInitFail(t *testing.T) {
+	plugin := &K8sAttributes{Log: &testutil.Logger{}}
+	require.ErrorContains(t, plugin.Init(), "at least one of")
+}
+
+func TestEnrich(t *testing.T) {
+	controller := true
+	pods := []*corev1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "web-7d9c5b-x2k4q",
+				Namespace:   "shop",
+				UID:         types.UID("6f1f0c5e-2a43-4d3c-9d6c-5d1e9b1c2a01"),
+				Labels:      map[string]string{"app": "web", "pod-template-hash": "7d9c5b", "tier": "frontend"},
+				Annotations: map[string]string{"team.example.com/owner": "shop-team", "other": "x"},
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "ReplicaSet", Name: "web-7d9c5b", Controller: &controller},
+				},
+			},
+			Spec: corev1.PodSpec{NodeName: "node-1"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				PodIP: "10.0.0.5",
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "web", ContainerID: "containerd://4b8e6d1c0f3a9b2e7d5c1a3f"},
+				},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "db-0",
+				Namespace: "shop",
+				UID:       types.UID("0a9b8c7d-1e2f-4a5b-8c9d-0e1f2a3b4c5d"),
+				OwnerReferences: []metav1.OwnerReference{
+					{Kind: "StatefulSet", Name: "db", Controller: &controller},
+				},
+			},
+			Spec: corev1.PodSpec{NodeName: "node-2"},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				PodIP: "10.0.0.6",
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "exporter",
+				Namespace: "kube-system",
+				UID:       types.UID("11111111-2222-3333-4444-555555555555"),
+			},
+			Spec: corev1.PodSpec{NodeName: "node-1", HostNetwork: true},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				PodIP: "192.168.1.10",
+			},
+		},
+	}
+
+	client := fake.NewClientset()
+	for _, pod := range pods {
+		_, err := client.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	plugin := newPlugin()
+	plugin.client = client
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	now := time.Now()
+	input := []telegraf.Metric{
+		metric.New("procstat",
+			map[string]string{"container_id": "containerd://4b8e6d1c0f3a9b2e7d5c1a3f"},
+			map[string]interface{}{"value": 1}, now),
+		metric.New("docker",
+			map[string]string{"container_id": "4b8e6d1c0f3a"},
+			map[string]interface{}{"value": 2}, now),
+		metric.New("cadvisor",
+			map[string]string{"pod_uid": "0a9b8c7d-1e2f-4a5b-8c9d-0e1f2a3b4c5d"},
+			map[string]interface{}{"value": 3}, now),
+		metric.New("socketstat",
+			map[string]string{"pod_ip": "10.0.0.6"},
+			map[string]interface{}{"value": 4}, now),
+		metric.New("socketstat",
+			map[string]string{"pod_ip": "192.168.1.10"},
+			map[string]interface{}{"value": 5}, now),
+		metric.New("socketstat",
+			map[string]string{"pod_ip": "10.9.9.9"},
+			map[string]interface{}{"value": 6}, now),
+	}
+
+	webTags := map[string]string{
+		"pod_name":                          "web-7d9c5b-x2k4q",
+		"namespace":                         "shop",
+		"node_name":                         "node-1",
+		"deployment":                        "web",
+		"label_app":                         "web",
+		"annotation_team.example.com/owner": "shop-team",
+	}
+	withTags := func(tags map[string]string, extra map[string]string) map[string]string {
+		result := make(map[string]string, len(tags)+len(extra))
+		for k, v := range tags {
+			result[k] = v
+		}
+		for k, v := range extra {
+			result[k] = v
+		}
+		return result
+	}
+	dbTags := map[string]string{
+		"pod_name":    "db-0",
+		"namespace":   "shop",
+		"node_name":   "node-2",
+		"statefulset": "db",
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("procstat",
+			withTags(webTags, map[string]string{"container_id": "containerd://4b8e6d1c0f3a9b2e7d5c1a3f"}),
+			map[string]interface{}{"value": 1}, now),
+		metric.New("docker",
+			withTags(webTags, map[string]string{"container_id": "4b8e6d1c0f3a"}),
+			map[string]interface{}{"value": 2}, now),
+		metric.New("cadvisor",
+			withTags(dbTags, map[string]string{"pod_uid": "0a9b8c7d-1e2f-4a5b-8c9d-0e1f2a3b4c5d"}),
+			map[string]interface{}{"value": 3}, now),
+		metric.New("socketstat",
+			withTags(dbTags, map[string]string{"pod_ip": "10.0.0.6"}),
+			map[string]interface{}{"value": 4}, now),
+		metric.New("socketstat",
+			map[string]string{"pod_ip": "192.168.1.10"},
+			map[string]interface{}{"value": 5}, now),
+		metric.New("socketstat",
+			map[string]string{"pod_ip": "10.9.9.9"},
+			map[string]interface{}{"value": 6}, now),
+	}
+
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestPodUpdates(t *testing.T) {
+	client := fake.NewClientset()
+
+	plugin := newPlugin()
+	plugin.client = client
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "late",
+			Namespace: "default",
+			UID:       types.UID("late-uid"),
+		},
+	}
+	_, err := client.CoreV1().Pods("default").Create(context.Background(), pod, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	m := metric.New("test", map[string]string{"pod_uid": "late-uid"}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	require.Eventually(t, func() bool {
+		return plugin.lookup(m) != nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.CoreV1().Pods("default").Delete(context.Background(), "late", metav1.DeleteOptions{}))
+	require.Eventually(t, func() bool {
+		return plugin.lookup(m) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+}
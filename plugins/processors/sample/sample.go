@@ -0,0 +1,283 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package sample
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Sample struct {
+	Mode          string          `toml:"mode"`
+	Rate          uint64          `toml:"rate"`
+	HashTags      []string        `toml:"hash_tags"`
+	Window        int             `toml:"window"`
+	Threshold     float64         `toml:"threshold"`
+	Fields        []string        `toml:"fields"`
+	SampleRateTag string          `toml:"sample_rate_tag"`
+	ExpireAfter   config.Duration `toml:"expire_after"`
+	Log           telegraf.Logger `toml:"-"`
+
+	fieldFilter filter.Filter
+	rate        string
+	series      map[uint64]*seriesState
+	lastCleanup time.Time
+}
+
+// seriesState keeps the sampling state of a single series
+type seriesState struct {
+	count    uint64
+	fields   map[string]*rollingStats
+	lastSeen time.Time
+}
+
+// rollingStats computes mean and standard deviation over a fixed-size window
+// of the most recent values
+type rollingStats struct {
+	values []float64
+	next   int
+}
+
+func (*Sample) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Sample) Init() error {
+	switch s.Mode {
+	case "":
+		s.Mode = "fixed"
+	case "fixed", "hash", "anomaly":
+	default:
+		return fmt.Errorf("invalid mode %q", s.Mode)
+	}
+
+	if s.Rate == 0 {
+		return errors.New("rate must be greater than zero")
+	}
+	s.rate = strconv.FormatUint(s.Rate, 10)
+
+	if s.Mode == "anomaly" {
+		if s.Window < 2 {
+			return errors.New("window must contain at least two samples")
+		}
+		if s.Threshold <= 0 {
+			return errors.New("threshold must be greater than zero")
+		}
+	}
+
+	if len(s.Fields) == 0 {
+		s.Fields = []string{"*"}
+	}
+	f, err := filter.Compile(s.Fields)
+	if err != nil {
+		return fmt.Errorf("creating field filter failed: %w", err)
+	}
+	s.fieldFilter = f
+
+	s.series = make(map[uint64]*seriesState)
+	s.lastCleanup = time.Now()
+
+	return nil
+}
+
+func (s *Sample) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	s.cleanup()
+
+	out := in[:0]
+	for _, m := range in {
+		var keep bool
+		var rate string
+		switch s.Mode {
+		case "fixed":
+			keep, rate = s.sampleFixed(m), s.rate
+		case "hash":
+			keep, rate = s.sampleHash(m), s.rate
+		case "anomaly":
+			if s.isAnomalous(m) {
+				keep, rate = true, "1"
+			} else {
+				keep, rate = s.sampleFixed(m), s.rate
+			}
+		}
+
+		if !keep {
+			m.Drop()
+			continue
+		}
+		if s.SampleRateTag != "" {
+			m.AddTag(s.SampleRateTag, rate)
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// Keep the first and then every n-th metric of each series
+func (s *Sample) sampleFixed(m telegraf.Metric) bool {
+	state := s.state(m)
+	keep := state.count%s.Rate == 0
+	state.count++
+	return keep
+}
+
+// Keep all metrics of the series whose hash falls into the sampled bucket.
+// The hash only depends on the metric name and the selected tags, so the
+// decision is the same across restarts and across agents.
+func (s *Sample) sampleHash(m telegraf.Metric) bool {
+	h := fnv.New64a()
+	h.Write([]byte(m.Name()))
+	h.Write([]byte("\n"))
+
+	if len(s.HashTags) == 0 {
+		for _, tag := range m.TagList() {
+			h.Write([]byte(tag.Key))
+			h.Write([]byte("\n"))
+			h.Write([]byte(tag.Value))
+			h.Write([]byte("\n"))
+		}
+	} else {
+		keys := make([]string, len(s.HashTags))
+		copy(keys, s.HashTags)
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, _ := m.GetTag(key)
+			h.Write([]byte(key))
+			h.Write([]byte("\n"))
+			h.Write([]byte(value))
+			h.Write([]byte("\n"))
+		}
+	}
+
+	return h.Sum64()%s.Rate == 0
+}
+
+// Check if any of the selected fields deviates from the rolling mean by
+// more than the threshold and add the values to the rolling window
+func (s *Sample) isAnomalous(m telegraf.Metric) bool {
+	state := s.state(m)
+
+	var anomalous bool
+	for _, field := range m.FieldList() {
+		if !s.fieldFilter.Match(field.Key) {
+			continue
+		}
+		if _, ok := field.Value.(string); ok {
+			continue
+		}
+		v, err := internal.ToFloat64(field.Value)
+		if err != nil {
+			s.Log.Debugf("Cannot convert field %q to float: %v", field.Key, err)
+			continue
+		}
+
+		stats, found := state.fields[field.Key]
+		if !found {
+			stats = &rollingStats{values: make([]float64, 0, s.Window)}
+			state.fields[field.Key] = stats
+		}
+		if z, ok := stats.zscore(v); ok && math.Abs(z) > s.Threshold {
+			anomalous = true
+		}
+		stats.add(v)
+	}
+
+	return anomalous
+}
+
+func (s *Sample) state(m telegraf.Metric) *seriesState {
+	id := m.HashID()
+	state, found := s.series[id]
+	if !found {
+		state = &seriesState{fields: make(map[string]*rollingStats)}
+		s.series[id] = state
+	}
+	state.lastSeen = time.Now()
+	return state
+}
+
+// Remove the state of series not seen for the configured expiry duration
+func (s *Sample) cleanup() {
+	if s.ExpireAfter <= 0 {
+		return
+	}
+
+	// No need to cleanup too often
+	expiry := time.Duration(s.ExpireAfter)
+	if time.Since(s.lastCleanup) < expiry {
+		return
+	}
+	s.lastCleanup = time.Now()
+
+	for id, state := range s.series {
+		if time.Since(state.lastSeen) >= expiry {
+			delete(s.series, id)
+		}
+	}
+}
+
+func (r *rollingStats) add(v float64) {
+	if len(r.values) < cap(r.values) {
+		r.values = append(r.values, v)
+		return
+	}
+	r.values[r.next] = v
+	r.next = (r.next + 1) % len(r.values)
+}
+
+// Compute the z-score of the given value with respect to the current window.
+// The second return value is false if the score cannot be determined.
+// Mean and variance are recomputed from the window to avoid accumulating
+// floating-point errors of running sums over long runs.
+func (r *rollingStats) zscore(v float64) (float64, bool) {
+	n := float64(len(r.values))
+	if n < 2 {
+		return 0, false
+	}
+
+	var sum float64
+	for _, x := range r.values {
+		sum += x
+	}
+	mean := sum / n
+
+	var sumsq float64
+	for _, x := range r.values {
+		sumsq += (x - mean) * (x - mean)
+	}
+	variance := sumsq / (n - 1)
+	if variance <= 0 {
+		// A constant window has no spread, so every deviation is anomalous
+		if v == mean {
+			return 0, true
+		}
+		return math.Inf(1), true
+	}
+	return (v - mean) / math.Sqrt(variance), true
+}
+
+func init() {
+	processors.Add("sample", func() telegraf.Processor {
+		return &Sample{
+			Mode:          "fixed",
+			Rate:          10,
+			Window:        100,
+			Threshold:     3.0,
+			SampleRateTag: "sample_rate",
+			ExpireAfter:   config.Duration(time.Hour),
+		}
+	})
+}
@@ -0,0 +1,217 @@
+package sample
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Sample
+		expected string
+	}{
+		{
+			name:     "invalid mode",
+			plugin:   &Sample{Mode: "foo", Rate: 1},
+			expected: `invalid mode "foo"`,
+		},
+		{
+			name:     "zero rate",
+			plugin:   &Sample{Mode: "fixed"},
+			expected: "rate must be greater than zero",
+		},
+		{
+			name:     "small window",
+			plugin:   &Sample{Mode: "anomaly", Rate: 1, Window: 1, Threshold: 3},
+			expected: "window must contain at least two samples",
+		},
+		{
+			name:     "zero threshold",
+			plugin:   &Sample{Mode: "anomaly", Rate: 1, Window: 10},
+			expected: "threshold must be greater than zero",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestFixed(t *testing.T) {
+	plugin := &Sample{
+		Mode:          "fixed",
+		Rate:          3,
+		SampleRateTag: "sample_rate",
+		Log:           &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	var input []telegraf.Metric
+	for i := 0; i < 5; i++ {
+		ts := now.Add(time.Duration(i) * time.Second)
+		input = append(input,
+			metric.New("test", map[string]string{"host": "a"}, map[string]interface{}{"value": i}, ts),
+			metric.New("test", map[string]string{"host": "b"}, map[string]interface{}{"value": i}, ts),
+		)
+	}
+
+	expected := []telegraf.Metric{
+		metric.New("test", map[string]string{"host": "a", "sample_rate": "3"}, map[string]interface{}{"value": 0}, now),
+		metric.New("test", map[string]string{"host": "b", "sample_rate": "3"}, map[string]interface{}{"value": 0}, now),
+		metric.New("test", map[string]string{"host": "a", "sample_rate": "3"}, map[string]interface{}{"value": 3}, now.Add(3*time.Second)),
+		metric.New("test", map[string]string{"host": "b", "sample_rate": "3"}, map[string]interface{}{"value": 3}, now.Add(3*time.Second)),
+	}
+
+	actual := plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestHashConsistent(t *testing.T) {
+	newPlugin := func() *Sample {
+		plugin := &Sample{
+			Mode:     "hash",
+			Rate:     4,
+			HashTags: []string{"host"},
+			Log:      &testutil.Logger{},
+		}
+		require.NoError(t, plugin.Init())
+		return plugin
+	}
+
+	newInput := func(other string) []telegraf.Metric {
+		input := make([]telegraf.Metric, 0, 100)
+		for i := 0; i < 100; i++ {
+			input = append(input, metric.New(
+				"test",
+				map[string]string{"host": fmt.Sprintf("host%d", i), "other": other},
+				map[string]interface{}{"value": i},
+				time.Unix(0, 0),
+			))
+		}
+		return input
+	}
+
+	// Different agents need to sample the same series
+	first := newPlugin().Apply(newInput("x")...)
+	second := newPlugin().Apply(newInput("x")...)
+	require.NotEmpty(t, first)
+	require.Less(t, len(first), 100)
+	testutil.RequireMetricsEqual(t, first, second)
+
+	// Tags not part of the hash must not change the decision
+	third := newPlugin().Apply(newInput("y")...)
+	require.Len(t, third, len(first))
+	for i := range first {
+		require.Equal(t, first[i].Tags()["host"], third[i].Tags()["host"])
+	}
+}
+
+func TestAnomaly(t *testing.T) {
+	plugin := &Sample{
+		Mode:          "anomaly",
+		Rate:          100,
+		Window:        10,
+		Threshold:     3.0,
+		SampleRateTag: "sample_rate",
+		Log:           &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	now := time.Now()
+	var input []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		input = append(input, metric.New(
+			"test",
+			map[string]string{},
+			map[string]interface{}{"value": 10.0 + float64(i%2)},
+			now.Add(time.Duration(i)*time.Second),
+		))
+	}
+	input = append(input, metric.New(
+		"test",
+		map[string]string{},
+		map[string]interface{}{"value": 50.0},
+		now.Add(10*time.Second),
+	))
+
+	expected := []telegraf.Metric{
+		metric.New("test", map[string]string{"sample_rate": "100"}, map[string]interface{}{"value": 10.0}, now),
+		metric.New("test", map[string]string{"sample_rate": "1"}, map[string]interface{}{"value": 50.0}, now.Add(10*time.Second)),
+	}
+
+	actual := plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestTracking(t *testing.T) {
+	var delivered int
+	notify := func(telegraf.DeliveryInfo) {
+		delivered++
+	}
+
+	var input []telegraf.Metric
+	for i := 0; i < 10; i++ {
+		m := metric.New("test", map[string]string{}, map[string]interface{}{"value": i}, time.Unix(int64(i), 0))
+		tm, _ := metric.WithTracking(m, notify)
+		input = append(input, tm)
+	}
+
+	plugin := &Sample{Mode: "fixed", Rate: 5, Log: &testutil.Logger{}}
+	require.NoError(t, plugin.Init())
+
+	actual := plugin.Apply(input...)
+	require.Len(t, actual, 2)
+	for _, m := range actual {
+		m.Accept()
+	}
+	require.Equal(t, 10, delivered)
+}
+
+func TestExpireSeries(t *testing.T) {
+	plugin := &Sample{
+		Mode:        "fixed",
+		Rate:        2,
+		ExpireAfter: config.Duration(time.Millisecond),
+	}
+	require.NoError(t, plugin.Init())
+
+	for _, host := range []string{"a", "b"} {
+		m := metric.New("test", map[string]string{"host": host}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+		plugin.Apply(m)
+	}
+	require.Len(t, plugin.series, 2)
+
+	// Series not seen for the expiry duration are removed
+	time.Sleep(2 * time.Millisecond)
+	m := metric.New("test", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	plugin.Apply(m)
+	require.Len(t, plugin.series, 1)
+}
+
+func TestRollingStatsStable(t *testing.T) {
+	stats := &rollingStats{values: make([]float64, 0, 10)}
+
+	// Large offsets cancel in running sums, the variance must stay positive
+	for i := range 100000 {
+		stats.add(1e9 + float64(i%10))
+	}
+	z, ok := stats.zscore(1e9 + 4.5)
+	require.True(t, ok)
+	require.InDelta(t, 0, z, 1e-6)
+
+	z, ok = stats.zscore(1e9 + 100)
+	require.True(t, ok)
+	require.Greater(t, z, 3.0)
+}
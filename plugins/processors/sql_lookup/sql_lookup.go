@@ -0,0 +1,314 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package sql_lookup
+
+import (
+	"bytes"
+	"context"
+	dbsql "database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/choice"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type SQLLookup struct {
+	Driver             string          `toml:"driver"`
+	Dsn                config.Secret   `toml:"dsn"`
+	Timeout            config.Duration `toml:"timeout"`
+	MaxLifetime        config.Duration `toml:"connection_max_life_time"`
+	MaxOpenConnections int             `toml:"connection_max_open"`
+	MaxIdleConnections int             `toml:"connection_max_idle"`
+	Query              string          `toml:"query"`
+	KeyTemplates       []string        `toml:"keys"`
+	FieldColumns       []string        `toml:"field_columns"`
+	CacheSize          int             `toml:"cache_size"`
+	CacheTTL           config.Duration `toml:"cache_ttl"`
+	NegativeTTL        config.Duration `toml:"negative_cache_ttl"`
+	RetryInterval      config.Duration `toml:"retry_interval"`
+	Log                telegraf.Logger `toml:"-"`
+
+	driverName    string
+	templates     []*template.Template
+	fieldFilter   filter.Filter
+	db            *dbsql.DB
+	statement     *dbsql.Stmt
+	cache         *expirable.LRU[string, *result]
+	negativeCache *expirable.LRU[string, bool]
+	retryAfter    time.Time
+}
+
+// result holds the tags and fields derived from a query result row
+type result struct {
+	tags   []telegraf.Tag
+	fields []telegraf.Field
+}
+
+func (*SQLLookup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SQLLookup) Init() error {
+	if s.Driver == "" {
+		return errors.New("missing SQL driver option")
+	}
+	if s.Dsn.Empty() {
+		return errors.New("missing data source name (DSN) option")
+	}
+	if s.Query == "" {
+		return errors.New("missing query")
+	}
+	if len(s.KeyTemplates) == 0 {
+		return errors.New("missing keys")
+	}
+	if s.CacheSize <= 0 {
+		return errors.New("'cache_size' must be greater than zero")
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = config.Duration(5 * time.Second)
+	}
+
+	// Use the same driver aliases as the SQL input plugin
+	aliases := map[string]string{
+		"cockroach": "pgx",
+		"tidb":      "mysql",
+		"mssql":     "sqlserver",
+		"maria":     "mysql",
+		"postgres":  "pgx",
+		"oracle":    "oracle",
+	}
+	s.driverName = s.Driver
+	if driver, ok := aliases[s.Driver]; ok {
+		s.driverName = driver
+	}
+	if !choice.Contains(s.driverName, dbsql.Drivers()) {
+		availDrivers := dbsql.Drivers()
+		sort.Strings(availDrivers)
+		return fmt.Errorf("driver %q not supported use one of %v", s.Driver, availDrivers)
+	}
+
+	s.templates = make([]*template.Template, 0, len(s.KeyTemplates))
+	for i, k := range s.KeyTemplates {
+		tmpl, err := template.New(fmt.Sprintf("key%d", i+1)).Parse(k)
+		if err != nil {
+			return fmt.Errorf("creating template for key %d failed: %w", i+1, err)
+		}
+		s.templates = append(s.templates, tmpl)
+	}
+
+	fieldFilter, err := filter.Compile(s.FieldColumns)
+	if err != nil {
+		return fmt.Errorf("creating field filter failed: %w", err)
+	}
+	s.fieldFilter = fieldFilter
+
+	s.cache = expirable.NewLRU[string, *result](s.CacheSize, nil, time.Duration(s.CacheTTL))
+	s.negativeCache = expirable.NewLRU[string, bool](s.CacheSize, nil, time.Duration(s.NegativeTTL))
+
+	return nil
+}
+
+func (s *SQLLookup) Start(telegraf.Accumulator) error {
+	dsnSecret, err := s.Dsn.Get()
+	if err != nil {
+		return fmt.Errorf("getting DSN failed: %w", err)
+	}
+	dsn := dsnSecret.String()
+	dsnSecret.Destroy()
+
+	s.db, err = dbsql.Open(s.driverName, dsn)
+	if err != nil {
+		return err
+	}
+	s.db.SetConnMaxLifetime(time.Duration(s.MaxLifetime))
+	s.db.SetMaxOpenConns(s.MaxOpenConnections)
+	s.db.SetMaxIdleConns(s.MaxIdleConnections)
+
+	// Preparing the statement might fail if the server is not reachable, so
+	// retry when processing the metrics
+	if err := s.prepare(); err != nil {
+		s.Log.Errorf("Preparing query failed: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SQLLookup) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	s.enrich(m)
+	acc.AddMetric(m)
+	return nil
+}
+
+func (s *SQLLookup) Stop() {
+	if s.statement != nil {
+		if err := s.statement.Close(); err != nil {
+			s.Log.Errorf("Closing statement failed: %v", err)
+		}
+	}
+	if s.db != nil {
+		if err := s.db.Close(); err != nil {
+			s.Log.Errorf("Closing database connection failed: %v", err)
+		}
+	}
+}
+
+func (s *SQLLookup) prepare() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Timeout))
+	defer cancel()
+
+	stmt, err := s.db.PrepareContext(ctx, s.Query)
+	if err != nil {
+		return err
+	}
+	s.statement = stmt
+	return nil
+}
+
+func (s *SQLLookup) enrich(raw telegraf.Metric) {
+	m := raw
+	if wm, ok := raw.(telegraf.UnwrappableMetric); ok {
+		m = wm.Unwrap()
+	}
+
+	// Generate the query parameters and a cache key from them
+	args := make([]interface{}, 0, len(s.templates))
+	var key bytes.Buffer
+	for _, tmpl := range s.templates {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, m); err != nil {
+			s.Log.Errorf("Generating key failed: %v", err)
+			s.Log.Debugf("metric was %v", m)
+			return
+		}
+		args = append(args, buf.String())
+		fmt.Fprintf(&key, "%d:%s;", buf.Len(), buf.String())
+	}
+
+	// Try the cache first
+	if _, found := s.negativeCache.Get(key.String()); found {
+		return
+	}
+	r, found := s.cache.Get(key.String())
+	if !found {
+		// Do not query the database again for some time after a failure to
+		// avoid blocking each metric for the query timeout, e.g. if the
+		// database is unreachable.
+		if time.Now().Before(s.retryAfter) {
+			return
+		}
+
+		var err error
+		r, err = s.lookup(args)
+		if err != nil {
+			s.Log.Errorf("Looking up %v failed, skipping lookups for %s: %v", args, s.RetryInterval, err)
+			s.retryAfter = time.Now().Add(time.Duration(s.RetryInterval))
+			return
+		}
+		if r == nil {
+			s.negativeCache.Add(key.String(), true)
+			return
+		}
+		s.cache.Add(key.String(), r)
+	}
+
+	for _, tag := range r.tags {
+		m.AddTag(tag.Key, tag.Value)
+	}
+	for _, field := range r.fields {
+		m.AddField(field.Key, field.Value)
+	}
+}
+
+// Query the database and convert the first result row. A nil result without
+// error is returned if there is no matching row.
+func (s *SQLLookup) lookup(args []interface{}) (*result, error) {
+	if s.statement == nil {
+		if err := s.prepare(); err != nil {
+			return nil, fmt.Errorf("preparing query failed: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Timeout))
+	defer cancel()
+
+	rows, err := s.statement.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	if rows.Next() {
+		s.Log.Debugf("Query for %v returned more than one row, using the first one", args)
+	}
+
+	r := &result{}
+	for i, name := range columns {
+		// Skip NULL values
+		if values[i] == nil {
+			continue
+		}
+
+		if s.fieldFilter != nil && s.fieldFilter.Match(name) {
+			v := values[i]
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			r.fields = append(r.fields, telegraf.Field{Key: name, Value: v})
+			continue
+		}
+
+		var v string
+		if t, ok := values[i].(time.Time); ok {
+			v = t.Format(time.RFC3339Nano)
+		} else if v, err = internal.ToString(values[i]); err != nil {
+			s.Log.Debugf("Cannot convert column %q to string: %v", name, err)
+			continue
+		}
+		r.tags = append(r.tags, telegraf.Tag{Key: name, Value: v})
+	}
+
+	return r, nil
+}
+
+func init() {
+	processors.AddStreaming("sql_lookup", func() telegraf.StreamingProcessor {
+		return &SQLLookup{
+			Timeout:            config.Duration(5 * time.Second),
+			MaxIdleConnections: 2,
+			CacheSize:          1000,
+			CacheTTL:           config.Duration(time.Hour),
+			NegativeTTL:        config.Duration(5 * time.Minute),
+			RetryInterval:      config.Duration(10 * time.Second),
+		}
+	})
+}
@@ -0,0 +1,187 @@
+//go:build !mips && !mipsle && !mips64 && !ppc64 && !riscv64 && !loong64 && !mips64le && !(windows && (386 || arm))
+
+package sql_lookup
+
+import (
+	dbsql "database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func setupDatabase(t *testing.T) (string, *dbsql.DB) {
+	dbfile := filepath.Join(t.TempDir(), "cmdb.db")
+	db, err := dbsql.Open("sqlite", dbfile)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE hosts (hostname TEXT, owner TEXT, environment TEXT, rack INTEGER, cost REAL)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO hosts VALUES
+		('web01', 'shop', 'production', 12, 1.5),
+		('db01', 'platform', NULL, 7, 2.5)`)
+	require.NoError(t, err)
+
+	return dbfile, db
+}
+
+func TestLookupSqlite(t *testing.T) {
+	dbfile, _ := setupDatabase(t)
+
+	plugin := &SQLLookup{
+		Driver:       "sqlite",
+		Dsn:          config.NewSecret([]byte(dbfile)),
+		Query:        "SELECT owner, environment, rack, cost FROM hosts WHERE hostname = ?",
+		KeyTemplates: []string{`{{.Tag "host"}}`},
+		FieldColumns: []string{"cost"},
+		CacheSize:    10,
+		CacheTTL:     config.Duration(time.Hour),
+		NegativeTTL:  config.Duration(time.Hour),
+		Log:          &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "web01"}, map[string]interface{}{"usage": 42.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "db01"}, map[string]interface{}{"usage": 23.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "unknown"}, map[string]interface{}{"usage": 5.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"usage": 5.0}, time.Unix(0, 0)),
+	}
+	expected := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"host": "web01", "owner": "shop", "environment": "production", "rack": "12"},
+			map[string]interface{}{"usage": 42.0, "cost": 1.5},
+			time.Unix(0, 0),
+		),
+		metric.New("cpu",
+			map[string]string{"host": "db01", "owner": "platform", "rack": "7"},
+			map[string]interface{}{"usage": 23.0, "cost": 2.5},
+			time.Unix(0, 0),
+		),
+		metric.New("cpu", map[string]string{"host": "unknown"}, map[string]interface{}{"usage": 5.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"usage": 5.0}, time.Unix(0, 0)),
+	}
+
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m, &acc))
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestCachingSqlite(t *testing.T) {
+	dbfile, db := setupDatabase(t)
+
+	plugin := &SQLLookup{
+		Driver:       "sqlite",
+		Dsn:          config.NewSecret([]byte(dbfile)),
+		Query:        "SELECT owner FROM hosts WHERE hostname = ?",
+		KeyTemplates: []string{`{{.Tag "host"}}`},
+		CacheSize:    10,
+		CacheTTL:     config.Duration(time.Hour),
+		NegativeTTL:  config.Duration(time.Hour),
+		Log:          &testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Fill the positive and negative cache
+	require.NoError(t, plugin.Add(metric.New("cpu", map[string]string{"host": "web01"}, map[string]interface{}{"v": 1}, time.Unix(0, 0)), &acc))
+	require.NoError(t, plugin.Add(metric.New("cpu", map[string]string{"host": "new01"}, map[string]interface{}{"v": 1}, time.Unix(0, 0)), &acc))
+
+	// Modify the database, the cached results must be used
+	_, err := db.Exec(`UPDATE hosts SET owner = 'changed' WHERE hostname = 'web01'`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO hosts (hostname, owner) VALUES ('new01', 'someone')`)
+	require.NoError(t, err)
+
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Add(metric.New("cpu", map[string]string{"host": "web01"}, map[string]interface{}{"v": 2}, time.Unix(0, 0)), &acc))
+	require.NoError(t, plugin.Add(metric.New("cpu", map[string]string{"host": "new01"}, map[string]interface{}{"v": 2}, time.Unix(0, 0)), &acc))
+
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "web01", "owner": "shop"}, map[string]interface{}{"v": 2}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "new01"}, map[string]interface{}{"v": 2}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestFailingDatabaseSqlite(t *testing.T) {
+	dbfile := filepath.Join(t.TempDir(), "cmdb.db")
+
+	// The table does not exist so all queries fail
+	logger := &testutil.CaptureLogger{}
+	plugin := &SQLLookup{
+		Driver:        "sqlite",
+		Dsn:           config.NewSecret([]byte(dbfile)),
+		Query:         "SELECT owner FROM hosts WHERE hostname = ?",
+		KeyTemplates:  []string{`{{.Tag "host"}}`},
+		CacheSize:     10,
+		CacheTTL:      config.Duration(time.Hour),
+		NegativeTTL:   config.Duration(time.Hour),
+		RetryInterval: config.Duration(time.Hour),
+		Log:           logger,
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	defer plugin.Stop()
+
+	// Only the first lookup must query the database, all others must be
+	// skipped and the metrics passed on unmodified
+	input := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "web01"}, map[string]interface{}{"v": 1}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "db01"}, map[string]interface{}{"v": 1}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "web01"}, map[string]interface{}{"v": 2}, time.Unix(0, 0)),
+	}
+	for _, m := range input {
+		require.NoError(t, plugin.Add(m.Copy(), &acc))
+	}
+	testutil.RequireMetricsEqual(t, input, acc.GetTelegrafMetrics())
+
+	var failures int
+	for _, msg := range logger.Errors() {
+		if strings.Contains(msg, "Looking up") {
+			failures++
+		}
+	}
+	require.Equal(t, 1, failures)
+
+	// Recover the database, the lookup must succeed once the retry interval
+	// passed
+	db, err := dbsql.Open("sqlite", dbfile)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE hosts (hostname TEXT, owner TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO hosts VALUES ('web01', 'shop')`)
+	require.NoError(t, err)
+
+	acc.ClearMetrics()
+	m := metric.New("cpu", map[string]string{"host": "web01"}, map[string]interface{}{"v": 3}, time.Unix(0, 0))
+	require.NoError(t, plugin.Add(m.Copy(), &acc))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, acc.GetTelegrafMetrics())
+
+	plugin.retryAfter = time.Time{}
+	acc.ClearMetrics()
+	require.NoError(t, plugin.Add(m.Copy(), &acc))
+	expected := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "web01", "owner": "shop"}, map[string]interface{}{"v": 3}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
@@ -0,0 +1,77 @@
+package sql_lookup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *SQLLookup
+		expected string
+	}{
+		{
+			name:     "missing driver",
+			plugin:   &SQLLookup{},
+			expected: "missing SQL driver option",
+		},
+		{
+			name: "missing dsn",
+			plugin: &SQLLookup{
+				Driver: "mysql",
+			},
+			expected: "missing data source name (DSN) option",
+		},
+		{
+			name: "missing query",
+			plugin: &SQLLookup{
+				Driver: "mysql",
+				Dsn:    config.NewSecret([]byte("user@tcp(localhost)/db")),
+			},
+			expected: "missing query",
+		},
+		{
+			name: "missing keys",
+			plugin: &SQLLookup{
+				Driver: "mysql",
+				Dsn:    config.NewSecret([]byte("user@tcp(localhost)/db")),
+				Query:  "SELECT owner FROM hosts WHERE name = ?",
+			},
+			expected: "missing keys",
+		},
+		{
+			name: "unknown driver",
+			plugin: &SQLLookup{
+				Driver:       "foo",
+				Dsn:          config.NewSecret([]byte("user@tcp(localhost)/db")),
+				Query:        "SELECT owner FROM hosts WHERE name = ?",
+				KeyTemplates: []string{`{{.Tag "host"}}`},
+				CacheSize:    1,
+			},
+			expected: `driver "foo" not supported`,
+		},
+		{
+			name: "invalid template",
+			plugin: &SQLLookup{
+				Driver:       "mysql",
+				Dsn:          config.NewSecret([]byte("user@tcp(localhost)/db")),
+				Query:        "SELECT owner FROM hosts WHERE name = ?",
+				KeyTemplates: []string{`{{.Tag "host"`},
+				CacheSize:    1,
+			},
+			expected: "creating template for key 1 failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = &testutil.Logger{}
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
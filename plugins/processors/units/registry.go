@@ -0,0 +1,110 @@
+package units
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unit describes a unit by the dimension it measures and the linear
+// transformation to the base unit of that dimension, i.e.
+//
+//	base = value * factor + offset
+type unit struct {
+	name      string
+	dimension string
+	factor    float64
+	offset    float64
+	suffix    string
+}
+
+// Default canonical unit per dimension
+var defaultCanonical = map[string]string{
+	"information": "B",
+	"time":        "s",
+	"fraction":    "ratio",
+	"temperature": "C",
+}
+
+var registry, registryLower = buildRegistry()
+
+// buildRegistry returns the units indexed by their names and aliases as well
+// as by the lower-cased names and aliases. Names differing only by case but
+// referring to different units, e.g. "b" (bit) and "B" (byte), are ambiguous
+// and stored as nil in the lower-case index.
+func buildRegistry() (map[string]*unit, map[string]*unit) {
+	units := []struct {
+		unit
+		aliases []string
+	}{
+		// Information, base unit is bytes
+		{unit{"bit", "information", 1.0 / 8, 0, "_bits"}, []string{"bits", "b"}},
+		{unit{"B", "information", 1, 0, "_bytes"}, []string{"byte", "bytes"}},
+		{unit{"kB", "information", 1e3, 0, "_kilobytes"}, []string{"KB", "kilobyte", "kilobytes"}},
+		{unit{"MB", "information", 1e6, 0, "_megabytes"}, []string{"megabyte", "megabytes"}},
+		{unit{"GB", "information", 1e9, 0, "_gigabytes"}, []string{"gigabyte", "gigabytes"}},
+		{unit{"TB", "information", 1e12, 0, "_terabytes"}, []string{"terabyte", "terabytes"}},
+		{unit{"KiB", "information", 1 << 10, 0, "_kibibytes"}, []string{"kibibyte", "kibibytes"}},
+		{unit{"MiB", "information", 1 << 20, 0, "_mebibytes"}, []string{"mebibyte", "mebibytes"}},
+		{unit{"GiB", "information", 1 << 30, 0, "_gibibytes"}, []string{"gibibyte", "gibibytes"}},
+		{unit{"TiB", "information", 1 << 40, 0, "_tebibytes"}, []string{"tebibyte", "tebibytes"}},
+
+		// Time, base unit is seconds
+		{unit{"ns", "time", 1e-9, 0, "_nanoseconds"}, []string{"nanosecond", "nanoseconds"}},
+		{unit{"us", "time", 1e-6, 0, "_microseconds"}, []string{"µs", "μs", "microsecond", "microseconds"}},
+		{unit{"ms", "time", 1e-3, 0, "_milliseconds"}, []string{"millisecond", "milliseconds"}},
+		{unit{"s", "time", 1, 0, "_seconds"}, []string{"sec", "second", "seconds"}},
+		{unit{"min", "time", 60, 0, "_minutes"}, []string{"minute", "minutes"}},
+		{unit{"h", "time", 3600, 0, "_hours"}, []string{"hour", "hours"}},
+		{unit{"d", "time", 86400, 0, "_days"}, []string{"day", "days"}},
+
+		// Fraction, base unit is the ratio
+		{unit{"ratio", "fraction", 1, 0, "_ratio"}, nil},
+		{unit{"percent", "fraction", 1e-2, 0, "_percent"}, []string{"%", "pct"}},
+
+		// Temperature, base unit is degree Celsius
+		{unit{"C", "temperature", 1, 0, "_celsius"}, []string{"°C", "celsius"}},
+		{unit{"F", "temperature", 5.0 / 9, -32 * 5.0 / 9, "_fahrenheit"}, []string{"°F", "fahrenheit"}},
+		{unit{"K", "temperature", 1, -273.15, "_kelvin"}, []string{"kelvin"}},
+	}
+
+	r := make(map[string]*unit, 3*len(units))
+	lower := make(map[string]*unit, 3*len(units))
+	for i := range units {
+		u := &units[i].unit
+		for _, name := range append([]string{u.name}, units[i].aliases...) {
+			if _, found := r[name]; found {
+				panic(fmt.Sprintf("duplicate unit name %q", name))
+			}
+			r[name] = u
+
+			key := strings.ToLower(name)
+			if existing, found := lower[key]; found && existing != u {
+				lower[key] = nil
+				continue
+			}
+			lower[key] = u
+		}
+	}
+	return r, lower
+}
+
+// lookupUnit returns the unit for the given name or alias. Names are
+// matched case-sensitive first to distinguish e.g. "b" (bit) from "B" (byte)
+// and case-insensitive otherwise. Case-insensitive matches of names that are
+// ambiguous, like "b", are rejected.
+func lookupUnit(name string) (*unit, bool) {
+	if u, found := registry[name]; found {
+		return u, true
+	}
+	u := registryLower[strings.ToLower(name)]
+	return u, u != nil
+}
+
+// convert the value given in the source unit to the destination unit
+func convert(value float64, from, to *unit) float64 {
+	if from == to {
+		return value
+	}
+	base := value*from.factor + from.offset
+	return (base - to.offset) / to.factor
+}
@@ -0,0 +1,260 @@
+//go:generate ../../../tools/readme_config_includer/generator
+package units
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+//go:embed sample.conf
+var sampleConfig string
+
+type Conversion struct {
+	Fields []string `toml:"fields"`
+	From   string   `toml:"from"`
+	To     string   `toml:"to"`
+
+	fieldFilter filter.Filter
+	from        *unit
+	to          *unit
+}
+
+type Units struct {
+	Canonical      map[string]string `toml:"canonical"`
+	Conversions    []Conversion      `toml:"conversion"`
+	Suffixes       map[string]string `toml:"suffixes"`
+	RenameSuffixes bool              `toml:"rename_suffixes"`
+	UnitTag        string            `toml:"unit_tag"`
+	UnitTagFields  []string          `toml:"unit_tag_fields"`
+	Log            telegraf.Logger   `toml:"-"`
+
+	canonical      map[string]*unit
+	suffixes       []suffixUnit
+	tagFieldFilter filter.Filter
+}
+
+type suffixUnit struct {
+	suffix string
+	unit   *unit
+}
+
+func (*Units) SampleConfig() string {
+	return sampleConfig
+}
+
+func (u *Units) Init() error {
+	// Setup the canonical units using the defaults for unset dimensions
+	u.canonical = make(map[string]*unit, len(defaultCanonical))
+	for dimension, name := range defaultCanonical {
+		u.canonical[dimension] = registry[name]
+	}
+	for dimension, name := range u.Canonical {
+		if _, found := defaultCanonical[dimension]; !found {
+			return fmt.Errorf("unknown dimension %q", dimension)
+		}
+		target, found := lookupUnit(name)
+		if !found {
+			return fmt.Errorf("unknown canonical unit %q", name)
+		}
+		if target.dimension != dimension {
+			return fmt.Errorf("canonical unit %q does not measure %q", name, dimension)
+		}
+		u.canonical[dimension] = target
+	}
+
+	for i := range u.Conversions {
+		c := &u.Conversions[i]
+		if len(c.Fields) == 0 {
+			return fmt.Errorf("conversion %d: no fields specified", i+1)
+		}
+		f, err := filter.Compile(c.Fields)
+		if err != nil {
+			return fmt.Errorf("conversion %d: creating field filter failed: %w", i+1, err)
+		}
+		c.fieldFilter = f
+
+		from, found := lookupUnit(c.From)
+		if !found {
+			return fmt.Errorf("conversion %d: unknown source unit %q", i+1, c.From)
+		}
+		c.from = from
+
+		if c.To == "" {
+			c.to = u.canonical[from.dimension]
+		} else if c.to, found = lookupUnit(c.To); !found {
+			return fmt.Errorf("conversion %d: unknown destination unit %q", i+1, c.To)
+		}
+		if c.from.dimension != c.to.dimension {
+			return fmt.Errorf("conversion %d: cannot convert %q to %q", i+1, c.From, c.To)
+		}
+	}
+
+	// Sort the suffixes by length to match the most specific suffix first
+	u.suffixes = make([]suffixUnit, 0, len(u.Suffixes))
+	for suffix, name := range u.Suffixes {
+		if suffix == "" {
+			return errors.New("empty suffix")
+		}
+		from, found := lookupUnit(name)
+		if !found {
+			return fmt.Errorf("unknown unit %q for suffix %q", name, suffix)
+		}
+		u.suffixes = append(u.suffixes, suffixUnit{suffix: suffix, unit: from})
+	}
+	sort.Slice(u.suffixes, func(i, j int) bool {
+		if len(u.suffixes[i].suffix) != len(u.suffixes[j].suffix) {
+			return len(u.suffixes[i].suffix) > len(u.suffixes[j].suffix)
+		}
+		return u.suffixes[i].suffix < u.suffixes[j].suffix
+	})
+
+	if u.UnitTag != "" {
+		if len(u.UnitTagFields) == 0 {
+			u.UnitTagFields = []string{"*"}
+		}
+		f, err := filter.Compile(u.UnitTagFields)
+		if err != nil {
+			return fmt.Errorf("creating unit-tag field filter failed: %w", err)
+		}
+		u.tagFieldFilter = f
+	}
+
+	return nil
+}
+
+func (u *Units) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		u.process(m)
+	}
+	return in
+}
+
+func (u *Units) process(m telegraf.Metric) {
+	// Determine the unit of the metric given by the tag if any
+	var tagUnit *unit
+	if u.UnitTag != "" {
+		if name, found := m.GetTag(u.UnitTag); found {
+			if tagUnit, found = lookupUnit(name); !found {
+				u.Log.Debugf("Unknown unit %q in tag %q of metric %q", name, u.UnitTag, m.Name())
+			}
+		}
+	}
+
+	// Collect the renames and apply them after iterating the fields to not
+	// modify the field-list while iterating
+	type rename struct{ from, to string }
+	var renames []rename
+	var tagConverted bool
+	for _, field := range m.FieldList() {
+		// Explicit conversions take precedence over suffixes and the unit tag
+		var from, to *unit
+		var suffix string
+		for i := range u.Conversions {
+			if u.Conversions[i].fieldFilter.Match(field.Key) {
+				from, to = u.Conversions[i].from, u.Conversions[i].to
+				break
+			}
+		}
+		if from == nil {
+			for _, s := range u.suffixes {
+				if strings.HasSuffix(field.Key, s.suffix) {
+					from, to, suffix = s.unit, u.canonical[s.unit.dimension], s.suffix
+					break
+				}
+			}
+		}
+		var fromTag bool
+		if from == nil && tagUnit != nil && u.tagFieldFilter.Match(field.Key) {
+			from, to, fromTag = tagUnit, u.canonical[tagUnit.dimension], true
+		}
+		if from == nil {
+			continue
+		}
+
+		value, ok := convertValue(field.Value, from, to)
+		if !ok {
+			u.Log.Debugf("Cannot convert field %q of type %T", field.Key, field.Value)
+			continue
+		}
+		field.Value = value
+		tagConverted = tagConverted || fromTag
+
+		if u.RenameSuffixes && suffix != "" && suffix != to.suffix {
+			key := strings.TrimSuffix(field.Key, suffix) + to.suffix
+			renames = append(renames, rename{field.Key, key})
+		}
+	}
+
+	for _, r := range renames {
+		if _, found := m.GetField(r.to); found {
+			u.Log.Debugf("Not renaming field %q as %q already exists in metric %q", r.from, r.to, m.Name())
+			continue
+		}
+		v, _ := m.GetField(r.from)
+		m.RemoveField(r.from)
+		m.AddField(r.to, v)
+	}
+
+	// Reflect the new unit in the tag
+	if tagConverted {
+		m.AddTag(u.UnitTag, u.canonical[tagUnit.dimension].name)
+	}
+}
+
+// convertValue converts the given numeric value between the units. Integer
+// values are kept as integers if the conversion is an exact integer
+// multiplication, all other values are converted to float.
+func convertValue(value interface{}, from, to *unit) (interface{}, bool) {
+	if from == to {
+		return value, true
+	}
+
+	// Tolerate rounding errors of the decimal factors, e.g. for ms to us
+	var integerFactor bool
+	ratio := from.factor / to.factor
+	if from.offset == to.offset && ratio >= 1 && ratio <= 1<<53 {
+		rounded := math.Round(ratio)
+		integerFactor = math.Abs(ratio-rounded) < 1e-9*rounded
+		ratio = rounded
+	}
+
+	switch v := value.(type) {
+	case int64:
+		if integerFactor {
+			factor := int64(ratio)
+			if r := v * factor; r/factor == v {
+				return r, true
+			}
+		}
+	case uint64:
+		if integerFactor {
+			factor := uint64(ratio)
+			if r := v * factor; r/factor == v {
+				return r, true
+			}
+		}
+	case string, bool:
+		return nil, false
+	}
+
+	f, err := internal.ToFloat64(value)
+	if err != nil {
+		return nil, false
+	}
+	return convert(f, from, to), true
+}
+
+func init() {
+	processors.Add("units", func() telegraf.Processor {
+		return &Units{}
+	})
+}
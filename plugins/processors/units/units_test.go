@@ -0,0 +1,200 @@
+package units
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	tests := []struct {
+		name     string
+		plugin   *Units
+		expected string
+	}{
+		{
+			name:     "unknown dimension",
+			plugin:   &Units{Canonical: map[string]string{"length": "m"}},
+			expected: `unknown dimension "length"`,
+		},
+		{
+			name:     "canonical unit of wrong dimension",
+			plugin:   &Units{Canonical: map[string]string{"time": "MiB"}},
+			expected: `canonical unit "MiB" does not measure "time"`,
+		},
+		{
+			name: "conversion between dimensions",
+			plugin: &Units{Conversions: []Conversion{
+				{Fields: []string{"x"}, From: "ms", To: "B"},
+			}},
+			expected: `conversion 1: cannot convert "ms" to "B"`,
+		},
+		{
+			name: "conversion without fields",
+			plugin: &Units{Conversions: []Conversion{
+				{From: "ms"},
+			}},
+			expected: "conversion 1: no fields specified",
+		},
+		{
+			name:     "unknown suffix unit",
+			plugin:   &Units{Suffixes: map[string]string{"_foo": "foo"}},
+			expected: `unknown unit "foo" for suffix "_foo"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorContains(t, tt.plugin.Init(), tt.expected)
+		})
+	}
+}
+
+func TestCases(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		plugin   *Units
+		input    telegraf.Metric
+		expected telegraf.Metric
+	}{
+		{
+			name: "explicit conversion",
+			plugin: &Units{Conversions: []Conversion{
+				{Fields: []string{"memory_*"}, From: "MiB"},
+				{Fields: []string{"latency"}, From: "ms", To: "us"},
+			}},
+			input: metric.New("nvidia_smi", map[string]string{},
+				map[string]interface{}{
+					"memory_total": int64(8),
+					"memory_used":  1.5,
+					"latency":      int64(3),
+					"name":         "gpu",
+				}, now),
+			expected: metric.New("nvidia_smi", map[string]string{},
+				map[string]interface{}{
+					"memory_total": int64(8 << 20),
+					"memory_used":  1.5 * (1 << 20),
+					"latency":      int64(3000),
+					"name":         "gpu",
+				}, now),
+		},
+		{
+			name: "suffix with rename",
+			plugin: &Units{
+				Suffixes:       map[string]string{"_kb": "KiB", "_ms": "ms", "_pct": "percent"},
+				RenameSuffixes: true,
+			},
+			input: metric.New("procstat", map[string]string{},
+				map[string]interface{}{
+					"memory_rss_kb": uint64(2),
+					"cpu_time_ms":   int64(1500),
+					"usage_pct":     50.0,
+				}, now),
+			expected: metric.New("procstat", map[string]string{},
+				map[string]interface{}{
+					"memory_rss_bytes": uint64(2048),
+					"cpu_time_seconds": 1.5,
+					"usage_ratio":      0.5,
+				}, now),
+		},
+		{
+			name: "suffix with custom canonical",
+			plugin: &Units{
+				Canonical:      map[string]string{"fraction": "%"},
+				Suffixes:       map[string]string{"_ratio": "ratio"},
+				RenameSuffixes: true,
+			},
+			input: metric.New("mem", map[string]string{},
+				map[string]interface{}{"used_ratio": 0.25}, now),
+			expected: metric.New("mem", map[string]string{},
+				map[string]interface{}{"used_percent": 25.0}, now),
+		},
+		{
+			name:   "unit tag",
+			plugin: &Units{UnitTag: "unit"},
+			input: metric.New("smart", map[string]string{"unit": "°F"},
+				map[string]interface{}{"temp": 212.0, "serial": "abc"}, now),
+			expected: metric.New("smart", map[string]string{"unit": "C"},
+				map[string]interface{}{"temp": 100.0, "serial": "abc"}, now),
+		},
+		{
+			name:   "unknown unit tag",
+			plugin: &Units{UnitTag: "unit"},
+			input: metric.New("smart", map[string]string{"unit": "furlong"},
+				map[string]interface{}{"value": 1.0}, now),
+			expected: metric.New("smart", map[string]string{"unit": "furlong"},
+				map[string]interface{}{"value": 1.0}, now),
+		},
+		{
+			name: "rename conflict",
+			plugin: &Units{
+				Suffixes:       map[string]string{"_kb": "KiB"},
+				RenameSuffixes: true,
+			},
+			input: metric.New("mem", map[string]string{},
+				map[string]interface{}{"used_kb": int64(1), "used_bytes": int64(5)}, now),
+			expected: metric.New("mem", map[string]string{},
+				map[string]interface{}{"used_kb": int64(1024), "used_bytes": int64(5)}, now),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plugin.Log = &testutil.Logger{}
+			require.NoError(t, tt.plugin.Init())
+			actual := tt.plugin.Apply(tt.input)
+			testutil.RequireMetricsEqual(t, []telegraf.Metric{tt.expected}, actual, testutil.SortMetrics())
+		})
+	}
+}
+
+func TestConvertTemperature(t *testing.T) {
+	c, f, k := registry["C"], registry["F"], registry["K"]
+	require.InDelta(t, 32.0, convert(0, c, f), 1e-9)
+	require.InDelta(t, -40.0, convert(-40, f, c), 1e-9)
+	require.InDelta(t, 273.15, convert(0, c, k), 1e-9)
+	require.InDelta(t, 212.0, convert(373.15, k, f), 1e-9)
+}
+
+func TestLookupUnit(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "b", expected: "bit"},
+		{name: "B", expected: "B"},
+		{name: "MB", expected: "MB"},
+		{name: "mb", expected: "MB"},
+		{name: "Kb", expected: "kB"},
+		{name: "Seconds", expected: "s"},
+		{name: "MS", expected: "ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, found := lookupUnit(tt.name)
+			require.True(t, found)
+			require.Equal(t, tt.expected, u.name)
+		})
+	}
+
+	// Names matching multiple units case-insensitively are ambiguous
+	r, lower := buildRegistry()
+	require.Nil(t, lower["b"])
+	require.Contains(t, r, "b")
+	for key, u := range lower {
+		if u != nil {
+			continue
+		}
+		_, found := lookupUnit(strings.ToUpper(key))
+		if _, exact := r[strings.ToUpper(key)]; !exact {
+			require.False(t, found, "ambiguous name %q resolved", key)
+		}
+	}
+}
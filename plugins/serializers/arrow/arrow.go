@@ -0,0 +1,287 @@
+package arrow
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/apache/arrow/go/v18/arrow/memory"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// Metadata keys used to describe the record batches
+const (
+	MeasurementKey = "telegraf.measurement"
+	ColumnKey      = "telegraf.column"
+)
+
+// Values of the column metadata
+const (
+	ColumnTimestamp = "timestamp"
+	ColumnTag       = "tag"
+	ColumnField     = "field"
+)
+
+type Serializer struct {
+	TimestampColumn string          `toml:"arrow_timestamp_column"`
+	Compression     string          `toml:"arrow_compression"`
+	Log             telegraf.Logger `toml:"-"`
+
+	options []ipc.Option
+	buffer  bytes.Buffer
+}
+
+// group collects metrics of the same measurement with compatible fields
+type group struct {
+	name    string
+	tags    map[string]bool
+	fields  map[string]arrow.DataType
+	metrics []telegraf.Metric
+}
+
+func (s *Serializer) Init() error {
+	if s.TimestampColumn == "" {
+		s.TimestampColumn = "time"
+	}
+
+	switch s.Compression {
+	case "", "none":
+	case "lz4":
+		s.options = append(s.options, ipc.WithLZ4())
+	case "zstd":
+		s.options = append(s.options, ipc.WithZstd())
+	default:
+		return fmt.Errorf("invalid compression %q", s.Compression)
+	}
+
+	return nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	if err := s.check(metric); err != nil {
+		return nil, fmt.Errorf("metric %q: %w", metric.Name(), err)
+	}
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+// SerializeBatch writes one Arrow IPC stream per record batch. The streams
+// are concatenated as every stream can only carry a single schema.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	records := s.Records(metrics)
+	defer func() {
+		for _, record := range records {
+			record.Release()
+		}
+	}()
+
+	s.buffer.Reset()
+	for _, record := range records {
+		options := append([]ipc.Option{ipc.WithSchema(record.Schema())}, s.options...)
+		writer := ipc.NewWriter(&s.buffer, options...)
+		if err := writer.Write(record); err != nil {
+			return nil, fmt.Errorf("writing record failed: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("closing stream failed: %w", err)
+		}
+	}
+
+	out := make([]byte, s.buffer.Len())
+	copy(out, s.buffer.Bytes())
+	return out, nil
+}
+
+// IPCOptions returns the options to use when writing the records returned by
+// Records using an IPC writer
+func (s *Serializer) IPCOptions() []ipc.Option {
+	return s.options
+}
+
+// Records converts the metrics to Arrow record batches, one for each
+// measurement and set of compatible field types. Tags or fields missing in a
+// metric are null. Metrics that cannot be represented, e.g. due to unsupported
+// field types or name collisions, are skipped. The caller must release the
+// returned records.
+func (s *Serializer) Records(metrics []telegraf.Metric) []arrow.Record {
+	var groups []*group
+	for _, m := range metrics {
+		if err := s.check(m); err != nil {
+			s.Log.Errorf("Skipping metric %q: %v", m.Name(), err)
+			continue
+		}
+
+		g := findGroup(groups, m)
+		if g == nil {
+			g = &group{
+				name:   m.Name(),
+				tags:   make(map[string]bool),
+				fields: make(map[string]arrow.DataType),
+			}
+			groups = append(groups, g)
+		}
+		for _, tag := range m.TagList() {
+			g.tags[tag.Key] = true
+		}
+		for _, field := range m.FieldList() {
+			// The type was checked when searching for the group
+			g.fields[field.Key], _ = arrowType(field.Value)
+		}
+		g.metrics = append(g.metrics, m)
+	}
+
+	records := make([]arrow.Record, 0, len(groups))
+	for _, g := range groups {
+		records = append(records, s.buildRecord(g))
+	}
+	return records
+}
+
+// Check if the metric can be represented in a record
+func (s *Serializer) check(m telegraf.Metric) error {
+	if m.HasTag(s.TimestampColumn) {
+		return fmt.Errorf("tag %q collides with the timestamp column", s.TimestampColumn)
+	}
+	for _, field := range m.FieldList() {
+		if _, err := arrowType(field.Value); err != nil {
+			return fmt.Errorf("field %q: %w", field.Key, err)
+		}
+		if field.Key == s.TimestampColumn {
+			return fmt.Errorf("field %q collides with the timestamp column", field.Key)
+		}
+		if m.HasTag(field.Key) {
+			return fmt.Errorf("field %q collides with a tag", field.Key)
+		}
+	}
+	return nil
+}
+
+// Find the group compatible with the tags and field types of the metric
+func findGroup(groups []*group, m telegraf.Metric) *group {
+outer:
+	for _, g := range groups {
+		if g.name != m.Name() {
+			continue
+		}
+		for _, tag := range m.TagList() {
+			if _, found := g.fields[tag.Key]; found {
+				continue outer
+			}
+		}
+		for _, field := range m.FieldList() {
+			if g.tags[field.Key] {
+				continue outer
+			}
+			t, _ := arrowType(field.Value)
+			if existing, found := g.fields[field.Key]; found && !arrow.TypeEqual(existing, t) {
+				continue outer
+			}
+		}
+		return g
+	}
+	return nil
+}
+
+func (s *Serializer) buildRecord(g *group) arrow.Record {
+	tags := make([]string, 0, len(g.tags))
+	for k := range g.tags {
+		tags = append(tags, k)
+	}
+	sort.Strings(tags)
+
+	fields := make([]string, 0, len(g.fields))
+	for k := range g.fields {
+		fields = append(fields, k)
+	}
+	sort.Strings(fields)
+
+	columns := make([]arrow.Field, 0, 1+len(tags)+len(fields))
+	columns = append(columns, arrow.Field{
+		Name:     s.TimestampColumn,
+		Type:     &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"},
+		Metadata: arrow.NewMetadata([]string{ColumnKey}, []string{ColumnTimestamp}),
+	})
+	for _, k := range tags {
+		columns = append(columns, arrow.Field{
+			Name:     k,
+			Type:     arrow.BinaryTypes.String,
+			Nullable: true,
+			Metadata: arrow.NewMetadata([]string{ColumnKey}, []string{ColumnTag}),
+		})
+	}
+	for _, k := range fields {
+		columns = append(columns, arrow.Field{
+			Name:     k,
+			Type:     g.fields[k],
+			Nullable: true,
+			Metadata: arrow.NewMetadata([]string{ColumnKey}, []string{ColumnField}),
+		})
+	}
+	metadata := arrow.NewMetadata([]string{MeasurementKey}, []string{g.name})
+	schema := arrow.NewSchema(columns, &metadata)
+
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+
+	for _, m := range g.metrics {
+		builder.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(m.Time().UnixNano()))
+		for i, k := range tags {
+			b := builder.Field(1 + i).(*array.StringBuilder)
+			if v, found := m.GetTag(k); found {
+				b.Append(v)
+			} else {
+				b.AppendNull()
+			}
+		}
+		for i, k := range fields {
+			b := builder.Field(1 + len(tags) + i)
+			v, found := m.GetField(k)
+			if !found {
+				b.AppendNull()
+				continue
+			}
+			switch b := b.(type) {
+			case *array.Int64Builder:
+				b.Append(v.(int64))
+			case *array.Uint64Builder:
+				b.Append(v.(uint64))
+			case *array.Float64Builder:
+				b.Append(v.(float64))
+			case *array.StringBuilder:
+				b.Append(v.(string))
+			case *array.BooleanBuilder:
+				b.Append(v.(bool))
+			}
+		}
+	}
+
+	return builder.NewRecord()
+}
+
+func arrowType(value interface{}) (arrow.DataType, error) {
+	switch value.(type) {
+	case int64:
+		return arrow.PrimitiveTypes.Int64, nil
+	case uint64:
+		return arrow.PrimitiveTypes.Uint64, nil
+	case float64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case string:
+		return arrow.BinaryTypes.String, nil
+	case bool:
+		return arrow.FixedWidthTypes.Boolean, nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
+
+func init() {
+	serializers.Add("arrow",
+		func() telegraf.Serializer {
+			return &Serializer{}
+		},
+	)
+}
@@ -0,0 +1,149 @@
+package arrow
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v18/arrow"
+	"github.com/apache/arrow/go/v18/arrow/array"
+	"github.com/apache/arrow/go/v18/arrow/ipc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	s := &Serializer{Compression: "gzip"}
+	require.ErrorContains(t, s.Init(), `invalid compression "gzip"`)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"usage": 42.5, "count": int64(3)},
+			time.Unix(1700000000, 1),
+		),
+		metric.New("mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"used": uint64(10), "ok": true},
+			time.Unix(1700000000, 2),
+		),
+		metric.New("cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage": 10.0, "state": "idle"},
+			time.Unix(1700000000, 3),
+		),
+		// The type conflict must result in a separate record batch
+		metric.New("cpu",
+			map[string]string{"host": "c"},
+			map[string]interface{}{"usage": "n/a"},
+			time.Unix(1700000000, 4),
+		),
+	}
+
+	for _, compression := range []string{"none", "lz4", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			s := &Serializer{Compression: compression}
+			require.NoError(t, s.Init())
+			buf, err := s.SerializeBatch(metrics)
+			require.NoError(t, err)
+
+			// Record batches are ordered by first occurrence
+			expected := []string{
+				"cpu: time=timestamp[ns, tz=UTC]/timestamp cpu=utf8/tag host=utf8/tag count=int64/field state=utf8/field usage=float64/field",
+				"mem: time=timestamp[ns, tz=UTC]/timestamp host=utf8/tag ok=bool/field used=uint64/field",
+				"cpu: time=timestamp[ns, tz=UTC]/timestamp host=utf8/tag usage=utf8/field",
+			}
+
+			// Read back the concatenated streams
+			var schemas []string
+			var rows []int64
+			r := bytes.NewReader(buf)
+			for r.Len() > 0 {
+				reader, err := ipc.NewReader(r)
+				require.NoError(t, err)
+				for reader.Next() {
+					rows = append(rows, reader.Record().NumRows())
+				}
+				require.NoError(t, reader.Err())
+				schemas = append(schemas, describe(reader.Schema()))
+				reader.Release()
+			}
+			require.Equal(t, expected, schemas)
+			require.Equal(t, []int64{2, 1, 1}, rows)
+		})
+	}
+}
+
+func describe(schema *arrow.Schema) string {
+	md := schema.Metadata()
+	parts := []string{md.Values()[md.FindKey(MeasurementKey)] + ":"}
+	for _, f := range schema.Fields() {
+		parts = append(parts, f.Name+"="+f.Type.String()+"/"+f.Metadata.Values()[f.Metadata.FindKey(ColumnKey)])
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestRecords(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage": 42.5}, time.Unix(0, 1)),
+		metric.New("cpu", map[string]string{"cpu": "cpu0"}, map[string]interface{}{"idle": 1.5}, time.Unix(0, 2)),
+	}
+
+	s := &Serializer{TimestampColumn: "ts"}
+	require.NoError(t, s.Init())
+	records := s.Records(metrics)
+	require.Len(t, records, 1)
+	defer records[0].Release()
+
+	// Missing tags and fields must be null
+	record := records[0]
+	require.Equal(t, "ts", record.ColumnName(0))
+	require.Equal(t, arrow.Timestamp(2), record.Column(0).(*array.Timestamp).Value(1))
+	for i, name := range []string{"cpu", "host", "idle", "usage"} {
+		require.Equal(t, name, record.ColumnName(i+1))
+		require.Equal(t, 1, record.Column(i+1).NullN(), name)
+	}
+}
+
+func TestInvalidMetrics(t *testing.T) {
+	s := &Serializer{}
+	require.NoError(t, s.Init())
+
+	m := metric.New("cpu", map[string]string{"value": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	_, err := s.Serialize(m)
+	require.ErrorContains(t, err, `metric "cpu": field "value" collides with a tag`)
+
+	m = metric.New("cpu", map[string]string{}, map[string]interface{}{"time": 1.0}, time.Unix(0, 0))
+	_, err = s.Serialize(m)
+	require.ErrorContains(t, err, `metric "cpu": field "time" collides with the timestamp column`)
+}
+
+func TestSkipInvalidMetrics(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("cpu", map[string]string{"value": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{}, map[string]interface{}{"time": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"time": "now"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		metric.New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	}
+
+	logger := &testutil.CaptureLogger{}
+	s := &Serializer{Log: logger}
+	require.NoError(t, s.Init())
+
+	// Only the valid metric must be serialized
+	records := s.Records(metrics)
+	require.Len(t, records, 1)
+	defer records[0].Release()
+	require.Equal(t, int64(1), records[0].NumRows())
+	require.Len(t, logger.Errors(), 3)
+
+	buf, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+	require.NotEmpty(t, buf)
+}
@@ -2,6 +2,7 @@ package prometheusremotewrite
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"hash/fnv"
 	"sort"
@@ -20,9 +21,51 @@ import (
 type MetricKey uint64
 
 type Serializer struct {
-	SortMetrics   bool            `toml:"prometheus_sort_metrics"`
-	StringAsLabel bool            `toml:"prometheus_string_as_label"`
-	Log           telegraf.Logger `toml:"-"`
+	SortMetrics      bool            `toml:"prometheus_sort_metrics"`
+	StringAsLabel    bool            `toml:"prometheus_string_as_label"`
+	Version          string          `toml:"prometheus_remote_write_version"`
+	NativeHistograms bool            `toml:"prometheus_native_histograms"`
+	Log              telegraf.Logger `toml:"-"`
+}
+
+// seriesInfo contains the information about a series required for
+// remote-write 2.0
+type seriesInfo struct {
+	valueType telegraf.ValueType
+	created   int64
+}
+
+func (s *Serializer) Init() error {
+	switch s.Version {
+	case "":
+		s.Version = "1.0"
+	case "1.0", "2.0":
+	default:
+		return fmt.Errorf("invalid remote-write version %q", s.Version)
+	}
+
+	if s.NativeHistograms && s.Version != "2.0" {
+		return errors.New("native histograms require remote-write version 2.0")
+	}
+
+	return nil
+}
+
+// Headers returns the HTTP headers required for sending the serialized data
+// to a remote-write receiver
+func (s *Serializer) Headers() map[string]string {
+	if s.Version == "2.0" {
+		return map[string]string{
+			"Content-Type":                      "application/x-protobuf;proto=io.prometheus.write.v2.Request",
+			"Content-Encoding":                  "snappy",
+			"X-Prometheus-Remote-Write-Version": "2.0.0",
+		}
+	}
+	return map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	}
 }
 
 func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
@@ -38,14 +81,35 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 		s.Log.Trace(lastErr)
 	}
 
+	v2 := s.Version == "2.0"
+
 	var buf bytes.Buffer
 	var entries = make(map[MetricKey]prompb.TimeSeries)
+	var infos = make(map[MetricKey]seriesInfo)
+	var histograms = make(map[MetricKey]*nativeHistogram)
 	var labels = make([]prompb.Label, 0)
 	for _, metric := range metrics {
 		labels = s.appendCommonLabels(labels[:0], metric)
+		if s.NativeHistograms && metric.Type() == telegraf.Histogram {
+			if err := addNativeHistogram(histograms, labels, metric); err != nil {
+				traceAndKeepErr("%w", err)
+			}
+			continue
+		}
+
 		var metrickey MetricKey
 		var promts prompb.TimeSeries
 		for _, field := range metric.FieldList() {
+			// Created timestamps are sent as part of the series in
+			// remote-write 2.0
+			var info seriesInfo
+			if v2 {
+				if isCreatedField(metric.Type(), field.Key) {
+					continue
+				}
+				info = seriesInfo{valueType: metric.Type(), created: createdTimestamp(metric, field.Key)}
+			}
+
 			metricName := prometheus.MetricName(metric.Name(), field.Key, metric.Type())
 			metricName, ok := prometheus.SanitizeMetricName(metricName)
 			if !ok {
@@ -72,10 +136,12 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 					metrickeysum, promtssum := getPromTS(metricName+"_sum", labels, float64(0), metric.Time())
 					if _, ok = entries[metrickeysum]; !ok {
 						entries[metrickeysum] = promtssum
+						infos[metrickeysum] = info
 					}
 					metrickeycount, promtscount := getPromTS(metricName+"_count", labels, float64(0), metric.Time())
 					if _, ok = entries[metrickeycount]; !ok {
 						entries[metrickeycount] = promtscount
+						infos[metrickeycount] = info
 					}
 					extraLabel := prompb.Label{
 						Name:  "le",
@@ -84,6 +150,7 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 					metrickeyinf, promtsinf := getPromTS(metricName+"_bucket", labels, float64(0), metric.Time(), extraLabel)
 					if _, ok = entries[metrickeyinf]; !ok {
 						entries[metrickeyinf] = promtsinf
+						infos[metrickeyinf] = info
 					}
 
 					le, ok := metric.GetTag("le")
@@ -130,6 +197,7 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 					metrickeyinf, promtsinf := getPromTS(metricName+"_bucket", labels, float64(count), metric.Time(), extraLabel)
 					if minf, ok := entries[metrickeyinf]; !ok || minf.Samples[0].Value == 0 {
 						entries[metrickeyinf] = promtsinf
+						infos[metrickeyinf] = info
 					}
 
 					metrickey, promts = getPromTS(metricName+"_count", labels, float64(count), metric.Time())
@@ -193,13 +261,29 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 				}
 			}
 			entries[metrickey] = promts
+			infos[metrickey] = info
+		}
+	}
+
+	for key, h := range histograms {
+		if err := h.finalize(); err != nil {
+			traceAndKeepErr("failed to convert histogram %q: %w", h.name, err)
+			delete(histograms, key)
 		}
 	}
 
 	if lastErr != nil {
 		// log only the last recorded error in the batch, as it could have many errors and logging each one
 		// could be too verbose. The following log line still provides enough info for user to act on.
-		s.Log.Errorf("some series were dropped, %d series left to send; last recorded error: %v", len(entries), lastErr)
+		s.Log.Errorf("some series were dropped, %d series left to send; last recorded error: %v", len(entries)+len(histograms), lastErr)
+	}
+
+	if v2 {
+		data, err := s.marshalV2(entries, infos, histograms)
+		if err != nil {
+			return nil, err
+		}
+		return snappy.Encode(nil, data), nil
 	}
 
 	var promTS = make([]prompb.TimeSeries, len(entries))
@@ -211,26 +295,7 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 
 	if s.SortMetrics {
 		sort.Slice(promTS, func(i, j int) bool {
-			lhs := promTS[i].Labels
-			rhs := promTS[j].Labels
-			if len(lhs) != len(rhs) {
-				return len(lhs) < len(rhs)
-			}
-
-			for index := range lhs {
-				l := lhs[index]
-				r := rhs[index]
-
-				if l.Name != r.Name {
-					return l.Name < r.Name
-				}
-
-				if l.Value != r.Value {
-					return l.Value < r.Value
-				}
-			}
-
-			return false
+			return lessLabels(promTS[i].Labels, promTS[j].Labels)
 		})
 	}
 	pb := &prompb.WriteRequest{Timeseries: promTS}
@@ -243,6 +308,27 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+func lessLabels(lhs, rhs []prompb.Label) bool {
+	if len(lhs) != len(rhs) {
+		return len(lhs) < len(rhs)
+	}
+
+	for index := range lhs {
+		l := lhs[index]
+		r := rhs[index]
+
+		if l.Name != r.Name {
+			return l.Name < r.Name
+		}
+
+		if l.Value != r.Value {
+			return l.Value < r.Value
+		}
+	}
+
+	return false
+}
+
 func hasLabel(name string, labels []prompb.Label) bool {
 	for _, label := range labels {
 		if name == label.Name {
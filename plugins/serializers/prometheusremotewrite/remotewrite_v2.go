@@ -0,0 +1,290 @@
+package prometheusremotewrite
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
+)
+
+// Schema of native histograms with custom bucket boundaries as used for
+// converting classic histograms
+const customBucketsSchema = -53
+
+// nativeHistogram collects the buckets, sum and count of a classic histogram
+// to be sent as native histogram with custom buckets
+type nativeHistogram struct {
+	name     string
+	labels   []prompb.Label
+	time     time.Time
+	created  int64
+	buckets  map[float64]uint64
+	sum      float64
+	count    uint64
+	hasCount bool
+
+	result writev2.Histogram
+}
+
+// v2Series is a series with its labels not yet converted to symbol references
+type v2Series struct {
+	labels     []prompb.Label
+	timeseries writev2.TimeSeries
+}
+
+func (s *Serializer) marshalV2(
+	entries map[MetricKey]prompb.TimeSeries,
+	infos map[MetricKey]seriesInfo,
+	histograms map[MetricKey]*nativeHistogram,
+) ([]byte, error) {
+	series := make([]v2Series, 0, len(entries)+len(histograms))
+	for key, entry := range entries {
+		info := infos[key]
+		samples := make([]writev2.Sample, 0, len(entry.Samples))
+		for _, sample := range entry.Samples {
+			samples = append(samples, writev2.Sample{Value: sample.Value, Timestamp: sample.Timestamp})
+		}
+		series = append(series, v2Series{
+			labels: entry.Labels,
+			timeseries: writev2.TimeSeries{
+				Samples:          samples,
+				Metadata:         writev2.Metadata{Type: metadataType(info.valueType)},
+				CreatedTimestamp: info.created,
+			},
+		})
+	}
+	for _, h := range histograms {
+		series = append(series, v2Series{
+			labels: h.labels,
+			timeseries: writev2.TimeSeries{
+				Histograms:       []writev2.Histogram{h.result},
+				Metadata:         writev2.Metadata{Type: writev2.Metadata_METRIC_TYPE_HISTOGRAM},
+				CreatedTimestamp: h.created,
+			},
+		})
+	}
+
+	if s.SortMetrics {
+		sort.Slice(series, func(i, j int) bool {
+			return lessLabels(series[i].labels, series[j].labels)
+		})
+	}
+
+	// Intern all label names and values
+	symbols := writev2.NewSymbolTable()
+	timeseries := make([]writev2.TimeSeries, 0, len(series))
+	for _, ts := range series {
+		refs := make([]uint32, 0, 2*len(ts.labels))
+		for _, label := range ts.labels {
+			refs = append(refs, symbols.Symbolize(label.Name), symbols.Symbolize(label.Value))
+		}
+		ts.timeseries.LabelsRefs = refs
+		timeseries = append(timeseries, ts.timeseries)
+	}
+
+	req := &writev2.Request{
+		Symbols:    symbols.Symbols(),
+		Timeseries: timeseries,
+	}
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal protobuf: %w", err)
+	}
+	return data, nil
+}
+
+// addNativeHistogram adds the buckets, sum and count fields of the metric to
+// the corresponding histograms
+func addNativeHistogram(histograms map[MetricKey]*nativeHistogram, labels []prompb.Label, metric telegraf.Metric) error {
+	var lastErr error
+	for _, field := range metric.FieldList() {
+		if isCreatedField(telegraf.Histogram, field.Key) {
+			continue
+		}
+
+		metricName := prometheus.MetricName(metric.Name(), field.Key, telegraf.Histogram)
+		metricName, ok := prometheus.SanitizeMetricName(metricName)
+		if !ok {
+			lastErr = fmt.Errorf("failed to parse metric name %q", metricName)
+			continue
+		}
+
+		series := make([]prompb.Label, len(labels), len(labels)+1)
+		copy(series, labels)
+		series = append(series, prompb.Label{Name: "__name__", Value: metricName})
+		sort.Sort(sortableLabels(series))
+		key := MakeMetricKey(series)
+
+		// Keep the most recent histogram of a series only
+		h, found := histograms[key]
+		if found && metric.Time().Before(h.time) {
+			lastErr = fmt.Errorf("metric %q has samples with timestamp %v older than already registered before", metric.Name(), metric.Time())
+			continue
+		}
+		if !found || metric.Time().After(h.time) {
+			h = &nativeHistogram{
+				name:    metricName,
+				labels:  series,
+				time:    metric.Time(),
+				buckets: make(map[float64]uint64),
+			}
+			histograms[key] = h
+		}
+		if created := createdTimestamp(metric, field.Key); created != 0 {
+			h.created = created
+		}
+
+		switch {
+		case strings.HasSuffix(field.Key, "_bucket"):
+			le, ok := metric.GetTag("le")
+			if !ok {
+				lastErr = fmt.Errorf("failed to parse %q: can't find `le` label", metricName)
+				continue
+			}
+			bound, err := strconv.ParseFloat(le, 64)
+			if err != nil {
+				lastErr = fmt.Errorf("failed to parse %q: can't parse %q value: %w", metricName, le, err)
+				continue
+			}
+			count, ok := prometheus.SampleCount(field.Value)
+			if !ok {
+				lastErr = fmt.Errorf("failed to parse %q: bad sample value %#v", metricName, field.Value)
+				continue
+			}
+			h.buckets[bound] = count
+		case strings.HasSuffix(field.Key, "_sum"):
+			sum, ok := prometheus.SampleSum(field.Value)
+			if !ok {
+				lastErr = fmt.Errorf("failed to parse %q: bad sample value %#v", metricName, field.Value)
+				continue
+			}
+			h.sum = sum
+		case strings.HasSuffix(field.Key, "_count"):
+			count, ok := prometheus.SampleCount(field.Value)
+			if !ok {
+				lastErr = fmt.Errorf("failed to parse %q: bad sample value %#v", metricName, field.Value)
+				continue
+			}
+			h.count = count
+			h.hasCount = true
+		default:
+			lastErr = fmt.Errorf("failed to parse %q: series %q should have `_count`, `_sum` or `_bucket` suffix", metricName, field.Key)
+		}
+	}
+	return lastErr
+}
+
+// finalize converts the cumulative bucket counts of the classic histogram to
+// a native histogram with custom bucket boundaries
+func (h *nativeHistogram) finalize() error {
+	bounds := make([]float64, 0, len(h.buckets))
+	for bound := range h.buckets {
+		if !math.IsInf(bound, 1) {
+			bounds = append(bounds, bound)
+		}
+	}
+	sort.Float64s(bounds)
+
+	counts := make([]uint64, 0, len(bounds)+1)
+	var previous uint64
+	for _, bound := range bounds {
+		count := h.buckets[bound]
+		if count < previous {
+			return errors.New("bucket counts are not cumulative")
+		}
+		counts = append(counts, count-previous)
+		previous = count
+	}
+
+	// The last bucket spans up to +Inf and contains the remaining observations
+	total := previous
+	if count, found := h.buckets[math.Inf(1)]; found {
+		total = count
+	}
+	if h.hasCount {
+		total = h.count
+	}
+	if total < previous {
+		return errors.New("count is smaller than the bucket counts")
+	}
+	counts = append(counts, total-previous)
+
+	deltas := make([]int64, 0, len(counts))
+	var last int64
+	for _, count := range counts {
+		deltas = append(deltas, int64(count)-last)
+		last = int64(count)
+	}
+
+	h.result = writev2.Histogram{
+		Count:          &writev2.Histogram_CountInt{CountInt: total},
+		Sum:            h.sum,
+		Schema:         customBucketsSchema,
+		PositiveSpans:  []writev2.BucketSpan{{Offset: 0, Length: uint32(len(counts))}},
+		PositiveDeltas: deltas,
+		CustomValues:   bounds,
+		Timestamp:      h.time.UnixMilli(),
+	}
+	return nil
+}
+
+func metadataType(valueType telegraf.ValueType) writev2.Metadata_MetricType {
+	switch valueType {
+	case telegraf.Counter:
+		return writev2.Metadata_METRIC_TYPE_COUNTER
+	case telegraf.Gauge:
+		return writev2.Metadata_METRIC_TYPE_GAUGE
+	case telegraf.Histogram:
+		return writev2.Metadata_METRIC_TYPE_HISTOGRAM
+	case telegraf.Summary:
+		return writev2.Metadata_METRIC_TYPE_SUMMARY
+	}
+	return writev2.Metadata_METRIC_TYPE_UNSPECIFIED
+}
+
+// isCreatedField returns true for fields containing the created timestamp of
+// counters, histograms or summaries as produced by the openmetrics parser
+func isCreatedField(valueType telegraf.ValueType, key string) bool {
+	switch valueType {
+	case telegraf.Counter, telegraf.Histogram, telegraf.Summary:
+		return strings.HasSuffix(key, "_created")
+	}
+	return false
+}
+
+// createdTimestamp returns the created timestamp in milliseconds for the
+// series of the given field or zero if the metric does not contain one. The
+// timestamp is taken from the "<name>_created" field in seconds.
+func createdTimestamp(metric telegraf.Metric, key string) int64 {
+	switch metric.Type() {
+	case telegraf.Counter:
+	case telegraf.Histogram, telegraf.Summary:
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			if strings.HasSuffix(key, suffix) {
+				key = strings.TrimSuffix(key, suffix)
+				break
+			}
+		}
+	default:
+		return 0
+	}
+
+	value, found := metric.GetField(key + "_created")
+	if !found {
+		return 0
+	}
+	seconds, ok := prometheus.SampleValue(value)
+	if !ok || seconds <= 0 {
+		return 0
+	}
+	return int64(seconds * 1000)
+}
@@ -0,0 +1,208 @@
+package prometheusremotewrite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/model/labels"
+	writev2 "github.com/prometheus/prometheus/prompb/io/prometheus/write/v2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+)
+
+func TestInitFail(t *testing.T) {
+	s := &Serializer{Version: "3.0"}
+	require.ErrorContains(t, s.Init(), `invalid remote-write version "3.0"`)
+
+	s = &Serializer{NativeHistograms: true}
+	require.ErrorContains(t, s.Init(), "native histograms require remote-write version 2.0")
+}
+
+func TestHeaders(t *testing.T) {
+	s := &Serializer{}
+	require.NoError(t, s.Init())
+	require.Equal(t, map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	}, s.Headers())
+
+	s = &Serializer{Version: "2.0"}
+	require.NoError(t, s.Init())
+	require.Equal(t, map[string]string{
+		"Content-Type":                      "application/x-protobuf;proto=io.prometheus.write.v2.Request",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "2.0.0",
+	}, s.Headers())
+}
+
+func TestRemoteWriteV2(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New(
+			"cpu",
+			map[string]string{"host": "example.org"},
+			map[string]interface{}{"time_idle": 42.0},
+			time.Unix(1, 0),
+			telegraf.Gauge,
+		),
+		metric.New(
+			"http",
+			map[string]string{"host": "example.org"},
+			map[string]interface{}{"requests": 10.0, "requests_created": 0.5},
+			time.Unix(2, 0),
+			telegraf.Counter,
+		),
+		metric.New(
+			"rpc",
+			map[string]string{"host": "example.org", "quantile": "0.5"},
+			map[string]interface{}{"duration": 0.25},
+			time.Unix(3, 0),
+			telegraf.Summary,
+		),
+		metric.New(
+			"rpc",
+			map[string]string{"host": "example.org"},
+			map[string]interface{}{"duration_sum": 10.0, "duration_count": 40.0, "duration_created": 1.0},
+			time.Unix(3, 0),
+			telegraf.Summary,
+		),
+	}
+
+	s := &Serializer{Version: "2.0", SortMetrics: true, Log: &testutil.Logger{}}
+	require.NoError(t, s.Init())
+	data, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	req := decodeV2(t, data)
+
+	// Every string is interned once with the empty string first
+	require.Equal(t, "", req.Symbols[0])
+	seen := make(map[string]bool, len(req.Symbols))
+	for _, symbol := range req.Symbols {
+		require.False(t, seen[symbol], "duplicate symbol %q", symbol)
+		seen[symbol] = true
+	}
+
+	type series struct {
+		labels  string
+		typ     writev2.Metadata_MetricType
+		created int64
+		value   float64
+		ts      int64
+	}
+	expected := []series{
+		{`{__name__="cpu_time_idle", host="example.org"}`, writev2.Metadata_METRIC_TYPE_GAUGE, 0, 42, 1000},
+		{`{__name__="http_requests", host="example.org"}`, writev2.Metadata_METRIC_TYPE_COUNTER, 500, 10, 2000},
+		{`{__name__="rpc_duration_count", host="example.org"}`, writev2.Metadata_METRIC_TYPE_SUMMARY, 1000, 40, 3000},
+		{`{__name__="rpc_duration_sum", host="example.org"}`, writev2.Metadata_METRIC_TYPE_SUMMARY, 1000, 10, 3000},
+		{`{__name__="rpc_duration", host="example.org", quantile="0.5"}`, writev2.Metadata_METRIC_TYPE_SUMMARY, 0, 0.25, 3000},
+	}
+
+	var b labels.ScratchBuilder
+	actual := make([]series, 0, len(req.Timeseries))
+	for _, ts := range req.Timeseries {
+		require.Len(t, ts.Samples, 1)
+		require.Empty(t, ts.Histograms)
+		actual = append(actual, series{
+			labels:  ts.ToLabels(&b, req.Symbols).String(),
+			typ:     ts.Metadata.Type,
+			created: ts.CreatedTimestamp,
+			value:   ts.Samples[0].Value,
+			ts:      ts.Samples[0].Timestamp,
+		})
+	}
+	require.Equal(t, expected, actual)
+}
+
+func TestNativeHistograms(t *testing.T) {
+	tags := func(le string) map[string]string {
+		if le == "" {
+			return map[string]string{"host": "example.org"}
+		}
+		return map[string]string{"host": "example.org", "le": le}
+	}
+	now := time.Unix(10, 0)
+	metrics := []telegraf.Metric{
+		metric.New("http", tags("0.1"), map[string]interface{}{"latency_bucket": 2.0}, now, telegraf.Histogram),
+		metric.New("http", tags("0.5"), map[string]interface{}{"latency_bucket": 5.0}, now, telegraf.Histogram),
+		metric.New("http", tags("1"), map[string]interface{}{"latency_bucket": 9.0}, now, telegraf.Histogram),
+		metric.New("http", tags("+Inf"), map[string]interface{}{"latency_bucket": 10.0}, now, telegraf.Histogram),
+		metric.New("http", tags(""),
+			map[string]interface{}{"latency_sum": 4.5, "latency_count": 10.0, "latency_created": 5.0},
+			now,
+			telegraf.Histogram,
+		),
+		// Bucket counts are not cumulative
+		metric.New("broken", tags("0.1"), map[string]interface{}{"latency_bucket": 5.0}, now, telegraf.Histogram),
+		metric.New("broken", tags("0.5"), map[string]interface{}{"latency_bucket": 2.0}, now, telegraf.Histogram),
+	}
+
+	logger := &testutil.CaptureLogger{}
+	s := &Serializer{Version: "2.0", NativeHistograms: true, Log: logger}
+	require.NoError(t, s.Init())
+	data, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+	require.Contains(t, logger.LastError(), `failed to convert histogram "broken_latency": bucket counts are not cumulative`)
+
+	req := decodeV2(t, data)
+	require.Len(t, req.Timeseries, 1)
+	ts := req.Timeseries[0]
+
+	var b labels.ScratchBuilder
+	require.Equal(t, `{__name__="http_latency", host="example.org"}`, ts.ToLabels(&b, req.Symbols).String())
+	require.Equal(t, writev2.Metadata_METRIC_TYPE_HISTOGRAM, ts.Metadata.Type)
+	require.Equal(t, int64(5000), ts.CreatedTimestamp)
+	require.Empty(t, ts.Samples)
+	require.Len(t, ts.Histograms, 1)
+
+	h := ts.Histograms[0]
+	require.Equal(t, now.UnixMilli(), h.Timestamp)
+
+	native := h.ToIntHistogram()
+	require.NoError(t, native.Validate())
+	require.Equal(t, uint64(10), native.Count)
+	require.InDelta(t, 4.5, native.Sum, 1e-9)
+	require.Equal(t, []float64{0.1, 0.5, 1}, native.CustomValues)
+
+	var counts []uint64
+	it := native.PositiveBucketIterator()
+	for it.Next() {
+		counts = append(counts, it.At().Count)
+	}
+	require.Equal(t, []uint64{2, 3, 4, 1}, counts)
+}
+
+func TestNativeHistogramsLatestOnly(t *testing.T) {
+	metrics := []telegraf.Metric{
+		metric.New("http", map[string]string{"le": "1"}, map[string]interface{}{"latency_bucket": 1.0}, time.Unix(1, 0), telegraf.Histogram),
+		metric.New("http", map[string]string{"le": "1"}, map[string]interface{}{"latency_bucket": 3.0}, time.Unix(2, 0), telegraf.Histogram),
+		metric.New("http", map[string]string{}, map[string]interface{}{"latency_count": 4.0}, time.Unix(2, 0), telegraf.Histogram),
+	}
+
+	s := &Serializer{Version: "2.0", NativeHistograms: true, Log: &testutil.Logger{}}
+	require.NoError(t, s.Init())
+	data, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	req := decodeV2(t, data)
+	require.Len(t, req.Timeseries, 1)
+	require.Len(t, req.Timeseries[0].Histograms, 1)
+	native := req.Timeseries[0].Histograms[0].ToIntHistogram()
+	require.Equal(t, int64(2000), req.Timeseries[0].Histograms[0].Timestamp)
+	require.Equal(t, uint64(4), native.Count)
+	require.Equal(t, []float64{1}, native.CustomValues)
+}
+
+func decodeV2(t *testing.T, data []byte) *writev2.Request {
+	t.Helper()
+
+	buf, err := snappy.Decode(nil, data)
+	require.NoError(t, err)
+	var req writev2.Request
+	require.NoError(t, req.Unmarshal(buf))
+	return &req
+}